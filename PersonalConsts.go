@@ -24,27 +24,50 @@ package Utils
 import (
 	"errors"
 	"os"
+	"sort"
 	"strings"
 )
 
 var PersonalConsts_GL PersonalConsts = PersonalConsts{}
 
+// Values of FILE_SYSTEM in PersonalConsts_EOG.json.
+const (
+	// _FILE_SYSTEM_OS is the OS file system (the default if none is given).
+	_FILE_SYSTEM_OS string = "OS"
+	// _FILE_SYSTEM_READ_ONLY is the OS file system, but refusing all changes (ReadOnlyFileSystem).
+	_FILE_SYSTEM_READ_ONLY string = "READ_ONLY"
+	// _FILE_SYSTEM_OVERLAY is the OS file system, but with all changes kept in memory (OverlayFileSystem).
+	_FILE_SYSTEM_OVERLAY string = "OVERLAY"
+	// _FILE_SYSTEM_MEMORY is an empty in-memory file system (MemFileSystem), where the VISOR directory is created.
+	_FILE_SYSTEM_MEMORY string = "MEMORY"
+)
+
 // _PersonalConstsEOG is the internal struct with the format of the PersonalConsts_EOG.json file.
 type _PersonalConstsEOG struct {
 	VISOR_DIR string
+	FILE_SYSTEM string
 
 	VISOR_EMAIL_ADDR string
 	VISOR_EMAIL_PW string
 
 	USER_EMAIL_ADDR string
 
+	EMAIL_ROUTING EmailRouting
+
+	SMTP_ACCOUNTS map[string]SmtpAccount
+	SMTP_FAILOVER []string
+
+	ENCRYPTION_KEY string
+	ENCRYPTION_KEY_FILE string
+
 	WEBSITE_URL string
 	WEBSITE_PW string
 }
 
 // PersonalConsts is a struct containing the constants that are personal to the user.
 type PersonalConsts struct {
-	// _VISOR_DIR is the full path to the main directory of VISOR.
+	// _VISOR_DIR is the full path to the main directory of VISOR, on the file system chosen with FILE_SYSTEM (passed
+	// on to all the paths inside it).
 	_VISOR_DIR GPath
 
 	// _VISOR_EMAIL_ADDR is VISOR's email address
@@ -52,9 +75,24 @@ type PersonalConsts struct {
 	// _VISOR_EMAIL_PW is VISOR's email password
 	_VISOR_EMAIL_PW string
 
-	// USER_EMAIL_ADDR is the email address of the user, used for all email communication
+	// USER_EMAIL_ADDR is the email address of the user, used for all email communication without a more specific
+	// recipient in EMAIL_ROUTING
 	USER_EMAIL_ADDR string
 
+	// EMAIL_ROUTING is the table that decides to whom each email is sent
+	EMAIL_ROUTING EmailRouting
+
+	// _SMTP_ACCOUNTS maps the names of the accounts to send the emails through to their information - if none are
+	// given, it has a "default" one with VISOR's Gmail address
+	_SMTP_ACCOUNTS map[string]SmtpAccount
+	// SMTP_FAILOVER is the order in which the _SMTP_ACCOUNTS are tried to send each email (the next one is used if the
+	// previous one is down) - if none is given, all the accounts by alphabetical order
+	SMTP_FAILOVER []string
+
+	// _ENCRYPTION_KEY is the key of the encrypted files (GPath.WriteEncrypted()), from ENCRYPTION_KEY or
+	// ENCRYPTION_KEY_FILE (both in base64), or nil if none was given
+	_ENCRYPTION_KEY []byte
+
 	// WEBSITE_URL is the URL of the VISOR website
 	WEBSITE_URL string
 	// WEBSITE_PW is the password for the VISOR website
@@ -84,21 +122,86 @@ func (personalConsts *PersonalConsts) Init() error {
 	// Set the global variables
 
 	personalConsts._VISOR_DIR = PathFILESDIRS(true, "", struct_file_format.VISOR_DIR)
+	switch struct_file_format.FILE_SYSTEM {
+		case "", _FILE_SYSTEM_OS:
+			// Keep the default one.
+		case _FILE_SYSTEM_READ_ONLY:
+			personalConsts._VISOR_DIR = personalConsts._VISOR_DIR.WithFS(ReadOnlyFileSystem{FS: OsFileSystem{}})
+		case _FILE_SYSTEM_OVERLAY:
+			personalConsts._VISOR_DIR = personalConsts._VISOR_DIR.WithFS(NewOverlayFileSystemFILESDIRS(OsFileSystem{}))
+		case _FILE_SYSTEM_MEMORY:
+			var memFS *MemFileSystem = NewMemFileSystemFILESDIRS()
+			personalConsts._VISOR_DIR = personalConsts._VISOR_DIR.WithFS(memFS)
+			if err = mkdirAllFS(memFS, personalConsts._VISOR_DIR.p, 0o777); nil != err {
+				return errors.New("The VISOR directory could not be created in memory (" + err.Error() + ")! Aborting...")
+			}
+		default:
+			return errors.New("The FILE_SYSTEM \"" + struct_file_format.FILE_SYSTEM + "\" in " + PERSONAL_CONSTS_FILE +
+				" is not one of \"" + _FILE_SYSTEM_OS + "\", \"" + _FILE_SYSTEM_READ_ONLY + "\", \"" + _FILE_SYSTEM_OVERLAY +
+				"\" or \"" + _FILE_SYSTEM_MEMORY + "\"! Aborting...")
+	}
 
 	personalConsts._VISOR_EMAIL_ADDR = struct_file_format.VISOR_EMAIL_ADDR
 	personalConsts._VISOR_EMAIL_PW = struct_file_format.VISOR_EMAIL_PW
 
 	personalConsts.USER_EMAIL_ADDR = struct_file_format.USER_EMAIL_ADDR
 
+	personalConsts.EMAIL_ROUTING = struct_file_format.EMAIL_ROUTING
+
+	personalConsts._SMTP_ACCOUNTS = struct_file_format.SMTP_ACCOUNTS
+	personalConsts.SMTP_FAILOVER = struct_file_format.SMTP_FAILOVER
+	if 0 == len(personalConsts._SMTP_ACCOUNTS) {
+		personalConsts._SMTP_ACCOUNTS = map[string]SmtpAccount{
+			_SMTP_DEF_ACCOUNT: getDefaultSmtpAccountEMAIL(personalConsts._VISOR_EMAIL_ADDR, personalConsts._VISOR_EMAIL_PW),
+		}
+	}
+	if 0 == len(personalConsts.SMTP_FAILOVER) {
+		for account_name := range personalConsts._SMTP_ACCOUNTS {
+			personalConsts.SMTP_FAILOVER = append(personalConsts.SMTP_FAILOVER, account_name)
+		}
+		sort.Strings(personalConsts.SMTP_FAILOVER)
+	}
+
+	if "" != struct_file_format.ENCRYPTION_KEY_FILE {
+		var p_key_file *string = PathFILESDIRS(false, "", struct_file_format.ENCRYPTION_KEY_FILE).ReadTextFile()
+		if nil == p_key_file {
+			return errors.New("The encryption key file \"" + struct_file_format.ENCRYPTION_KEY_FILE + "\" could not be read! Aborting...")
+		}
+		struct_file_format.ENCRYPTION_KEY = *p_key_file
+	}
+	if "" != struct_file_format.ENCRYPTION_KEY {
+		personalConsts._ENCRYPTION_KEY, err = DecodeEncryptionKeyFILESDIRS(struct_file_format.ENCRYPTION_KEY)
+		if nil != err {
+			return errors.New("The encryption key in " + PERSONAL_CONSTS_FILE + " is invalid (" + err.Error() + ")! Aborting...")
+		}
+	}
+
 	personalConsts.WEBSITE_PW = struct_file_format.WEBSITE_PW
 	personalConsts.WEBSITE_URL = struct_file_format.WEBSITE_URL + "/"
 
-	if !strings.Contains(personalConsts._VISOR_EMAIL_ADDR, "@") || personalConsts._VISOR_EMAIL_PW == "" ||
+	if (0 == len(struct_file_format.SMTP_ACCOUNTS) &&
+				(!strings.Contains(personalConsts._VISOR_EMAIL_ADDR, "@") || personalConsts._VISOR_EMAIL_PW == "")) ||
 				!strings.Contains(personalConsts.USER_EMAIL_ADDR, "@") || personalConsts.WEBSITE_PW == "" ||
 				!strings.Contains(personalConsts.USER_EMAIL_ADDR, "http") {
 		return errors.New("Some fields in " + PERSONAL_CONSTS_FILE + " are empty or incorrect! Aborting...")
 	}
 
+	if !personalConsts.EMAIL_ROUTING.isValid() {
+		return errors.New("The EMAIL_ROUTING table in " + PERSONAL_CONSTS_FILE + " has invalid email addresses! Aborting...")
+	}
+
+	for _, account_name := range personalConsts.SMTP_FAILOVER {
+		smtpAccount, ok := personalConsts._SMTP_ACCOUNTS[account_name]
+		if !ok {
+			return errors.New("The SMTP account \"" + account_name + "\" in SMTP_FAILOVER of " + PERSONAL_CONSTS_FILE +
+				" does not exist! Aborting...")
+		}
+		if !smtpAccount.isValid() {
+			return errors.New("The SMTP account \"" + account_name + "\" in " + PERSONAL_CONSTS_FILE +
+				" is incomplete or invalid! Aborting...")
+		}
+	}
+
 	var visor_path GPath = personalConsts._VISOR_DIR
 	if !visor_path.Exists() {
 		return errors.New("The VISOR directory \"" + visor_path.GPathToStringConversion() + "\" does not exist! Aborting...")
@@ -0,0 +1,520 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package Utils
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// _BACKUPS_REL_DIR is the relative path to the backups directory from PersonalConsts._VISOR_DIR.
+	_BACKUPS_REL_DIR string = _DATA_REL_DIR + "Backups"
+	// BACKUP_NAME_FORMAT is the time format of the names of the backup directories.
+	BACKUP_NAME_FORMAT string = "2006-01-02_15-04-05"
+	// BACKUP_MANIFEST_FILE is the name of the SHA-512 manifest file of each backup (in the "sha512sum" format).
+	BACKUP_MANIFEST_FILE string = "manifest.sha512"
+	// _BACKUP_ARCHIVE_EXT is the extension of the archives of the modules' data inside a backup.
+	_BACKUP_ARCHIVE_EXT string = ".tar.gz"
+	// _BACKUP_TMP_SUFFIX is the suffix of the directories of backups and restores still in progress.
+	_BACKUP_TMP_SUFFIX string = ".tmp"
+	// _BACKUP_OLD_SUFFIX is the suffix of the module directories being replaced by a restore.
+	_BACKUP_OLD_SUFFIX string = ".old"
+	// _BACKUP_DEF_STOP_TIMEOUT is the default BackupOptions.Stop_timeout.
+	_BACKUP_DEF_STOP_TIMEOUT time.Duration = 30 * time.Second
+	// _BACKUP_LOCK_TIMEOUT is the maximum time to wait for the lock of the backups directory or of a module directory.
+	_BACKUP_LOCK_TIMEOUT time.Duration = 1 * time.Minute
+)
+
+// _BACKUP_EXCLUDE is the patterns of the files of the modules' data never backed up (running state and locks).
+var _BACKUP_EXCLUDE []string = []string{"PID=*", "STOP", "**/*" + LOCK_FILE_EXT}
+
+// BackupOptions is the options for CreateBackupBACKUP(), RestoreBackupBACKUP() and RunScheduledBackupBACKUP().
+type BackupOptions struct {
+	// Mod_nums is the numbers of the modules to back up or restore, or nil for all of them.
+	Mod_nums []int
+	// Keep_modules_running is true to back up the data of the running modules while they run, which may give an
+	// inconsistent backup (the modules don't lock their data while writing it). By default (false), they're stopped
+	// first (through the STOP file), and restores always stop them. The stopped modules are not started again here -
+	// their numbers are returned for the Modules Manager to start them again.
+	Keep_modules_running bool
+	// Stop_timeout is the maximum time to wait for a module to stop, or 0 for the default (30 seconds).
+	Stop_timeout time.Duration
+	// Retention is the policy to apply after a backup made by RunScheduledBackupBACKUP(), or nil to keep all backups.
+	Retention *BackupRetention
+}
+
+/*
+BackupRetention is the policy of which backups to keep, in the "grandfather-father-son" way: the newest backup of each
+of the last Daily days, Weekly weeks and Monthly months is kept (a backup can count for more than one), and so is the
+newest backup of all. All others are removed.
+*/
+type BackupRetention struct {
+	// Daily is the number of days to keep a backup of.
+	Daily int
+	// Weekly is the number of weeks to keep a backup of.
+	Weekly int
+	// Monthly is the number of months to keep a backup of.
+	Monthly int
+}
+
+// BackupInfo is the information about a backup.
+type BackupInfo struct {
+	// Path is the path of the backup directory.
+	Path GPath
+	// Time is the time the backup was made.
+	Time time.Time
+	// Mod_nums is the numbers of the modules in the backup.
+	Mod_nums []int
+	// Stopped_mod_nums is the numbers of the modules stopped to make the backup, to be started again by the Modules
+	// Manager (only given by CreateBackupBACKUP() and RunScheduledBackupBACKUP(), even if they fail).
+	Stopped_mod_nums []int
+}
+
+/*
+CreateBackupBACKUP creates a backup of the user data of the modules, with one tar.gz archive per module and a SHA-512
+manifest of the archives.
+
+The backup is made in a temporary directory and only renamed to its final name when complete, so incomplete backups are
+never listed. If a backup was already made in the same second, this one waits for the next second for a different name.
+
+-----------------------------------------------------------
+
+– Params:
+  - options – the options for the backup or nil for the default ones (all modules, stopping them)
+
+– Returns:
+  - the information about the backup
+  - nil if the backup was created successfully, an error otherwise
+*/
+func CreateBackupBACKUP(options *BackupOptions) (BackupInfo, error) {
+	options = getBackupOptionsBACKUP(options)
+
+	var backupInfo BackupInfo = BackupInfo{}
+	var backups_dir GPath = getBackupsDirBACKUP()
+	if err := backups_dir.Create(false); nil != err {
+		return backupInfo, err
+	}
+
+	var err error = backups_dir.WithLock(LOCK_EXCLUSIVE, _BACKUP_LOCK_TIMEOUT, func() error {
+		// The names only have seconds, and the lock makes sure nobody else takes the next one meanwhile.
+		var name string
+		for {
+			backupInfo.Time = time.Now()
+			name = backupInfo.Time.Format(BACKUP_NAME_FORMAT)
+			backupInfo.Path = backups_dir.Add2(true, name)
+			if !backupInfo.Path.Exists() {
+				break
+			}
+			time.Sleep(backupInfo.Time.Truncate(time.Second).Add(time.Second).Sub(backupInfo.Time))
+		}
+
+		var tmp_dir GPath = backups_dir.Add2(true, name + _BACKUP_TMP_SUFFIX)
+		if _, err := tmp_dir.RemoveAll(nil); nil != err {
+			return err
+		}
+		if err := tmp_dir.Create(false); nil != err {
+			return err
+		}
+
+		for _, mod_num := range options.Mod_nums {
+			var user_data_dir GPath = getUserDataDirMODULES(mod_num)
+			if !user_data_dir.Exists() {
+				continue
+			}
+
+			if !options.Keep_modules_running {
+				stopped, err := stopModBACKUP(mod_num, options.Stop_timeout)
+				if stopped {
+					backupInfo.Stopped_mod_nums = append(backupInfo.Stopped_mod_nums, mod_num)
+				}
+				if nil != err {
+					return err
+				}
+			}
+
+			var archive GPath = tmp_dir.Add2(false, _MOD_FOLDER_PREFFIX + strconv.Itoa(mod_num) + _BACKUP_ARCHIVE_EXT)
+			// The lock only keeps other backups and restores of the module out - the modules don't take it.
+			var err error = user_data_dir.WithLock(LOCK_EXCLUSIVE, _BACKUP_LOCK_TIMEOUT, func() error {
+				_, err := user_data_dir.ArchiveTo(archive, &ArchiveOptions{Exclude: _BACKUP_EXCLUDE})
+
+				return err
+			})
+			if nil != err {
+				return err
+			}
+			backupInfo.Mod_nums = append(backupInfo.Mod_nums, mod_num)
+		}
+
+		if err := tmp_dir.WriteManifest(tmp_dir.Add2(false, BACKUP_MANIFEST_FILE), nil); nil != err {
+			return err
+		}
+
+		_, err := tmp_dir.MoveTo(backupInfo.Path, nil)
+
+		return err
+	})
+	if nil != err {
+		_, _ = backups_dir.Add2(true, backupInfo.Time.Format(BACKUP_NAME_FORMAT) + _BACKUP_TMP_SUFFIX).RemoveAll(nil)
+	}
+
+	return backupInfo, err
+}
+
+/*
+ListBackupsBACKUP lists the existing backups.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the information about the backups, newest first
+  - nil if the backups were listed successfully, an error otherwise
+*/
+func ListBackupsBACKUP() ([]BackupInfo, error) {
+	var backups_dir GPath = getBackupsDirBACKUP()
+	if !backups_dir.Exists() {
+		return nil, nil
+	}
+
+	dirs, err := backups_dir.List(&FileFilter{Type: FILE_TYPE_DIR})
+	if nil != err {
+		return nil, err
+	}
+
+	var backupInfos []BackupInfo = nil
+	for _, dir := range dirs {
+		backup_time, err := time.ParseInLocation(BACKUP_NAME_FORMAT, dir.Name(), time.Local)
+		if nil != err {
+			// Not a backup (or one still in progress).
+			continue
+		}
+
+		var backupInfo BackupInfo = BackupInfo{
+			Path: dir,
+			Time: backup_time,
+		}
+		archives, _ := dir.List(&FileFilter{Name_pattern: _MOD_FOLDER_PREFFIX + "*" + _BACKUP_ARCHIVE_EXT})
+		for _, archive := range archives {
+			var mod_num_str string = strings.TrimSuffix(strings.TrimPrefix(archive.Name(), _MOD_FOLDER_PREFFIX),
+				_BACKUP_ARCHIVE_EXT)
+			if mod_num, err := strconv.Atoi(mod_num_str); nil == err {
+				backupInfo.Mod_nums = append(backupInfo.Mod_nums, mod_num)
+			}
+		}
+		sort.Ints(backupInfo.Mod_nums)
+
+		backupInfos = append(backupInfos, backupInfo)
+	}
+
+	sort.Slice(backupInfos, func(i int, j int) bool {
+		return backupInfos[i].Time.After(backupInfos[j].Time)
+	})
+
+	return backupInfos, nil
+}
+
+/*
+VerifyBackupBACKUP checks the archives of a backup against its SHA-512 manifest.
+
+-----------------------------------------------------------
+
+– Params:
+  - backup_dir – the path of the backup directory
+
+– Returns:
+  - nil if the archives match the manifest exactly, an error describing the differences otherwise
+*/
+func VerifyBackupBACKUP(backup_dir GPath) error {
+	manifestDiff, err := backup_dir.VerifyManifest(backup_dir.Add2(false, BACKUP_MANIFEST_FILE), nil)
+	if nil != err {
+		return err
+	}
+	if !manifestDiff.IsEmpty() {
+		return errors.New("the backup does not match its manifest:\n" + strings.TrimSuffix(manifestDiff.String(), "\n"))
+	}
+
+	return nil
+}
+
+/*
+RestoreBackupBACKUP restores the user data of modules from a backup, replacing their current data.
+
+The backup is verified first and the modules being restored are stopped (they're not started again here - their
+numbers are returned for the Modules Manager to start them again). Each module's data is extracted to a temporary
+directory and only then swapped with the current one.
+
+-----------------------------------------------------------
+
+– Params:
+  - backup_dir – the path of the backup directory
+  - options – the options for the restore or nil for the default ones (all the modules in the backup)
+
+– Returns:
+  - the numbers of the modules stopped for the restore (even if it failed), to be started again
+  - nil if the data was restored successfully, an error otherwise
+*/
+func RestoreBackupBACKUP(backup_dir GPath, options *BackupOptions) ([]int, error) {
+	options = getBackupOptionsBACKUP(options)
+	if err := VerifyBackupBACKUP(backup_dir); nil != err {
+		return nil, err
+	}
+
+	var user_data_root GPath = PersonalConsts_GL._VISOR_DIR.Add2(true, _USER_DATA_REL_DIR)
+
+	var stopped_mod_nums []int = nil
+	var err error = getBackupsDirBACKUP().WithLock(LOCK_SHARED, _BACKUP_LOCK_TIMEOUT, func() error {
+		for _, mod_num := range options.Mod_nums {
+			var archive GPath = backup_dir.Add2(false, _MOD_FOLDER_PREFFIX + strconv.Itoa(mod_num) + _BACKUP_ARCHIVE_EXT)
+			if !archive.Exists() {
+				continue
+			}
+
+			stopped, err := stopModBACKUP(mod_num, options.Stop_timeout)
+			if stopped {
+				stopped_mod_nums = append(stopped_mod_nums, mod_num)
+			}
+			if nil != err {
+				return err
+			}
+
+			var mod_folder string = _MOD_FOLDER_PREFFIX + strconv.Itoa(mod_num)
+			var user_data_dir GPath = user_data_root.Add2(true, mod_folder)
+			var tmp_dir GPath = user_data_root.Add2(true, mod_folder + _BACKUP_TMP_SUFFIX)
+			var old_dir GPath = user_data_root.Add2(true, mod_folder + _BACKUP_OLD_SUFFIX)
+
+			err = user_data_dir.WithLock(LOCK_EXCLUSIVE, _BACKUP_LOCK_TIMEOUT, func() error {
+				if _, err := tmp_dir.RemoveAll(nil); nil != err {
+					return err
+				}
+				if _, err := archive.ExtractTo(tmp_dir.WithPerms(PermsPrivateFILESDIRS()), nil); nil != err {
+					return err
+				}
+
+				if user_data_dir.Exists() {
+					if _, err := old_dir.RemoveAll(nil); nil != err {
+						return err
+					}
+					if _, err := user_data_dir.MoveTo(old_dir, nil); nil != err {
+						return err
+					}
+				}
+				if _, err := tmp_dir.MoveTo(user_data_dir, nil); nil != err {
+					return err
+				}
+				_, err := old_dir.RemoveAll(nil)
+
+				return err
+			})
+			if nil != err {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return stopped_mod_nums, err
+}
+
+/*
+RotateBackupsBACKUP removes the backups not kept by a retention policy.
+
+-----------------------------------------------------------
+
+– Params:
+  - retention – the retention policy (if all its numbers are 0, nothing is removed)
+
+– Returns:
+  - the paths of the backups removed
+  - nil if the rotation was successful, an error otherwise
+*/
+func RotateBackupsBACKUP(retention BackupRetention) ([]GPath, error) {
+	if 0 == retention.Daily && 0 == retention.Weekly && 0 == retention.Monthly {
+		return nil, nil
+	}
+
+	var removed []GPath = nil
+	var err error = getBackupsDirBACKUP().WithLock(LOCK_EXCLUSIVE, _BACKUP_LOCK_TIMEOUT, func() error {
+		backupInfos, err := ListBackupsBACKUP()
+		if nil != err || 0 == len(backupInfos) {
+			return err
+		}
+
+		var keep []bool = make([]bool, len(backupInfos))
+		keep[0] = true
+		var keepNewestPerPeriod = func(num_periods int, getPeriod func(t time.Time) string) {
+			var periods_seen map[string]bool = map[string]bool{}
+			// The backups are sorted newest first, so the first one of each period is its newest.
+			for i, backupInfo := range backupInfos {
+				var period string = getPeriod(backupInfo.Time)
+				if periods_seen[period] {
+					continue
+				}
+				if len(periods_seen) >= num_periods {
+					break
+				}
+				periods_seen[period] = true
+				keep[i] = true
+			}
+		}
+		keepNewestPerPeriod(retention.Daily, func(t time.Time) string {
+			return t.Format(DATE_FORMAT)
+		})
+		keepNewestPerPeriod(retention.Weekly, func(t time.Time) string {
+			year, week := t.ISOWeek()
+
+			return strconv.Itoa(year) + "-W" + strconv.Itoa(week)
+		})
+		keepNewestPerPeriod(retention.Monthly, func(t time.Time) string {
+			return t.Format("2006-01")
+		})
+
+		for i, backupInfo := range backupInfos {
+			if keep[i] {
+				continue
+			}
+			if _, err = backupInfo.Path.RemoveAll(nil); nil != err {
+				return err
+			}
+			removed = append(removed, backupInfo.Path)
+		}
+
+		return nil
+	})
+
+	return removed, err
+}
+
+/*
+RunScheduledBackupBACKUP creates a backup if the newest one is older than the given interval and then applies the
+retention policy of the options. Meant to be called periodically by the Modules Manager.
+
+-----------------------------------------------------------
+
+– Params:
+  - interval – the minimum time between backups
+  - options – the options for the backup or nil for the default ones
+
+– Returns:
+  - the information about the backup created (only with BackupInfo.Stopped_mod_nums if it failed), or nil if it wasn't
+    time for one yet
+  - nil if the backup was not needed or was created successfully, an error otherwise
+*/
+func RunScheduledBackupBACKUP(interval time.Duration, options *BackupOptions) (*BackupInfo, error) {
+	backupInfos, err := ListBackupsBACKUP()
+	if nil != err {
+		return nil, err
+	}
+	if len(backupInfos) > 0 && time.Since(backupInfos[0].Time) < interval {
+		return nil, nil
+	}
+
+	backupInfo, err := CreateBackupBACKUP(options)
+	if nil != err {
+		return &BackupInfo{Stopped_mod_nums: backupInfo.Stopped_mod_nums}, err
+	}
+
+	if nil != options && nil != options.Retention {
+		if _, err = RotateBackupsBACKUP(*options.Retention); nil != err {
+			return &backupInfo, err
+		}
+	}
+
+	return &backupInfo, nil
+}
+
+/*
+stopModBACKUP stops a module if it's running and waits for it to stop.
+
+-----------------------------------------------------------
+
+– Params:
+  - mod_num – the number of the module
+  - timeout – the maximum time to wait
+
+– Returns:
+  - true if the module was running and was signalled to stop (even if it didn't stop in time), false otherwise
+  - nil if the module is not running, an error if it didn't stop in time
+*/
+func stopModBACKUP(mod_num int, timeout time.Duration) (bool, error) {
+	if !IsModRunningMODULES(mod_num) {
+		return false, nil
+	}
+
+	if !ModSignalStopMODULES(mod_num) {
+		return false, errors.New("could not signal the module " + GetModNameMODULES(mod_num) + " to stop")
+	}
+
+	var end time.Time = time.Now().Add(timeout)
+	for IsModRunningMODULES(mod_num) {
+		if time.Now().After(end) {
+			return true, errors.New("the module " + GetModNameMODULES(mod_num) + " did not stop in time")
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+
+	return true, nil
+}
+
+/*
+getBackupOptionsBACKUP gets the options to use for the backup operations.
+
+-----------------------------------------------------------
+
+– Params:
+  - options – the options given or nil
+
+– Returns:
+  - the options given with the defaults filled in, or the default ones if nil
+*/
+func getBackupOptionsBACKUP(options *BackupOptions) *BackupOptions {
+	var backupOptions BackupOptions = BackupOptions{}
+	if nil != options {
+		backupOptions = *options
+	}
+	if nil == backupOptions.Mod_nums {
+		for mod_num := range MOD_NUMS_NAMES {
+			backupOptions.Mod_nums = append(backupOptions.Mod_nums, mod_num)
+		}
+		sort.Ints(backupOptions.Mod_nums)
+	}
+	if 0 == backupOptions.Stop_timeout {
+		backupOptions.Stop_timeout = _BACKUP_DEF_STOP_TIMEOUT
+	}
+
+	return &backupOptions
+}
+
+/*
+getBackupsDirBACKUP gets the full path to the directory of the backups.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the full path to the directory of the backups, with the PermsPrivateFILESDIRS() permissions policy
+*/
+func getBackupsDirBACKUP() GPath {
+	return PersonalConsts_GL._VISOR_DIR.Add2(true, _BACKUPS_REL_DIR).WithPerms(PermsPrivateFILESDIRS())
+}
@@ -0,0 +1,184 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package Utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBackupCreateVerifyRestore(t *testing.T) {
+	setTestVisorDir(t)
+	var mod_files map[int]string = map[int]string{1: "one", 2: "two"}
+	for mod_num, contents := range mod_files {
+		if err := getUserDataDirMODULES(mod_num).Add2(false, "dir", "data.txt").WriteTextFile(contents); nil != err {
+			t.Fatal(err)
+		}
+	}
+
+	var options *BackupOptions = &BackupOptions{Mod_nums: []int{1, 2, 3}}
+	backupInfo, err := CreateBackupBACKUP(options)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(backupInfo.Mod_nums, []int{1, 2}) || nil != backupInfo.Stopped_mod_nums {
+		t.Errorf("modules %v and stopped %v, want [1 2] and none", backupInfo.Mod_nums, backupInfo.Stopped_mod_nums)
+	}
+	if err = VerifyBackupBACKUP(backupInfo.Path); nil != err {
+		t.Fatal(err)
+	}
+
+	// Another one right away gets a different name.
+	backupInfo2, err := CreateBackupBACKUP(options)
+	if nil != err {
+		t.Fatal(err)
+	}
+	backupInfos, err := ListBackupsBACKUP()
+	if nil != err {
+		t.Fatal(err)
+	}
+	if 2 != len(backupInfos) || backupInfos[0].Path != backupInfo2.Path || backupInfos[1].Path != backupInfo.Path ||
+			!reflect.DeepEqual(backupInfos[1].Mod_nums, []int{1, 2}) {
+		t.Fatalf("listed %+v", backupInfos)
+	}
+
+	// Changed after the backup, and then restored (only module 1).
+	for mod_num := range mod_files {
+		var user_data_dir GPath = getUserDataDirMODULES(mod_num)
+		if err = user_data_dir.Add2(false, "dir", "data.txt").WriteTextFile("changed"); nil != err {
+			t.Fatal(err)
+		}
+		if err = user_data_dir.Add2(false, "new.txt").WriteTextFile("new"); nil != err {
+			t.Fatal(err)
+		}
+	}
+	stopped, err := RestoreBackupBACKUP(backupInfo.Path, &BackupOptions{Mod_nums: []int{1}})
+	if nil != err {
+		t.Fatal(err)
+	}
+	if nil != stopped {
+		t.Errorf("stopped %v, want none", stopped)
+	}
+
+	var tests = []struct {
+		name         string
+		mod_num      int
+		wantContents string
+		wantNew      bool
+	}{
+		{"restored", 1, "one", false},
+		{"not restored", 2, "changed", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var user_data_dir GPath = getUserDataDirMODULES(test.mod_num)
+			var p_contents *string = user_data_dir.Add2(false, "dir", "data.txt").ReadTextFile()
+			if nil == p_contents || test.wantContents != *p_contents {
+				t.Errorf("contents %v, want %q", p_contents, test.wantContents)
+			}
+			if got := user_data_dir.Add2(false, "new.txt").Exists(); test.wantNew != got {
+				t.Errorf("new file exists = %v, want %v", got, test.wantNew)
+			}
+		})
+	}
+}
+
+func TestBackupTampered(t *testing.T) {
+	setTestVisorDir(t)
+	var data_file GPath = getUserDataDirMODULES(1).Add2(false, "data.txt")
+	if err := data_file.WriteTextFile("one"); nil != err {
+		t.Fatal(err)
+	}
+	backupInfo, err := CreateBackupBACKUP(&BackupOptions{Mod_nums: []int{1}})
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	var archive GPath = backupInfo.Path.Add2(false, _MOD_FOLDER_PREFFIX + "1" + _BACKUP_ARCHIVE_EXT)
+	var data []byte = archive.ReadFile()
+	data[len(data) / 2] ^= 0xFF
+	if err = archive.WriteFile(data); nil != err {
+		t.Fatal(err)
+	}
+	if err = VerifyBackupBACKUP(backupInfo.Path); nil == err {
+		t.Fatal("tampered archive verified")
+	}
+
+	if err = data_file.WriteTextFile("changed"); nil != err {
+		t.Fatal(err)
+	}
+	if _, err = RestoreBackupBACKUP(backupInfo.Path, nil); nil == err {
+		t.Fatal("tampered backup restored")
+	}
+	if p_contents := data_file.ReadTextFile(); nil == p_contents || "changed" != *p_contents {
+		t.Errorf("data changed by a failed restore: %v", p_contents)
+	}
+}
+
+func TestRotateBackups(t *testing.T) {
+	setTestVisorDir(t)
+	var names []string = []string{
+		"2026-03-10_12-00-00", // Newest of all, of its day, ISO week 11 and month.
+		"2026-03-10_08-00-00", // Same day, week and month as the newest - removed.
+		"2026-03-09_12-00-00", // Newest of the previous day (and also in week 11).
+		"2026-03-02_12-00-00", // Newest of week 10.
+		"2026-02-01_12-00-00", // Newest of February.
+	}
+	for _, name := range names {
+		if err := getBackupsDirBACKUP().Add2(true, name).Create(false); nil != err {
+			t.Fatal(err)
+		}
+	}
+
+	removed, err := RotateBackupsBACKUP(BackupRetention{})
+	if nil != err || nil != removed {
+		t.Fatalf("removed %v (error %v) with an empty policy", removed, err)
+	}
+
+	removed, err = RotateBackupsBACKUP(BackupRetention{Daily: 2, Weekly: 2, Monthly: 2})
+	if nil != err {
+		t.Fatal(err)
+	}
+	if 1 != len(removed) || names[1] != removed[0].Name() {
+		t.Errorf("removed %v, want only %s", removed, names[1])
+	}
+
+	backupInfos, err := ListBackupsBACKUP()
+	if nil != err {
+		t.Fatal(err)
+	}
+	var kept []string = nil
+	for _, backupInfo := range backupInfos {
+		kept = append(kept, backupInfo.Path.Name())
+	}
+	if want := []string{names[0], names[2], names[3], names[4]}; !reflect.DeepEqual(kept, want) {
+		t.Errorf("kept %v, want %v", kept, want)
+	}
+
+	// Only the newest of all.
+	if _, err = RotateBackupsBACKUP(BackupRetention{Daily: 1}); nil != err {
+		t.Fatal(err)
+	}
+	if backupInfos, _ = ListBackupsBACKUP(); 1 != len(backupInfos) || names[0] != backupInfos[0].Path.Name() {
+		t.Errorf("listed %+v, want only %s", backupInfos, names[0])
+	}
+}
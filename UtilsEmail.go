@@ -24,26 +24,69 @@ package Utils
 import (
 	"bytes"
 	"errors"
+	"fmt"
+	"html"
+	"mime"
 	"mime/quotedprintable"
-	"os"
+	"net/mail"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // EmailInfo is the info needed to send an email through QueueEmail().
 type EmailInfo struct {
 	// Sender name (can be anything)
 	Sender string
-	// Mail_to is the email address to send the email to.
+	// Mail_to is the email address to send the email to, or many separated by commas. They're added to the To ones.
+	//
+	// Deprecated: use To.
 	Mail_to string
+	// To is the list of the main recipients of the email (addresses, optionally with a name as in "Name <addr>").
+	To []string
+	// Cc is the list of the recipients in copy, shown to everyone like the To ones.
+	Cc []string
+	// Bcc is the list of the hidden recipients, not shown to anyone.
+	Bcc []string
+	// Reply_to is the list of addresses the replies go to instead of the sender, or nil for the sender.
+	Reply_to []string
+	// Headers is the map of custom headers to add to the email (like "X-Priority"), or nil for none.
+	Headers map[string]string
 	// Subject of the email.
 	Subject string
 	// Html is the HTML body of the email.
 	Html    string
+	// Text is the plain text alternative to the HTML for the clients that don't show it, or empty to generate it from
+	// the HTML with HtmlToTextEMAIL().
+	Text string
 	// Multipart is the list of multipart items to attach to the email aside from the main HTML.
 	Multiparts []Multipart
+	// Attachments is the list of files attached to the email (see AttachFile() and AttachBytes()).
+	Attachments []Attachment
 }
 
-// Multipart is an item to attach to an email as described in RFC 1521.
+/*
+EmailRouting is the table that decides the recipients of each email, read from the EMAIL_ROUTING object of the
+PersonalConsts_EOG.json file.
+
+The recipients of an email are chosen by the first of the following that has any addresses: the email model, the
+module sending it, DEFAULT and at last PersonalConsts.USER_EMAIL_ADDR. Error emails use ADMIN instead of the first
+two.
+*/
+type EmailRouting struct {
+	// MODELS maps the email model files (MODEL_FILE_RSS, MODEL_FILE_DISKS_SMART, ...) to their recipients.
+	MODELS map[string][]string
+	// MODULES maps the module numbers (as strings, like "2") to their recipients.
+	MODULES map[string][]string
+	// DEFAULT is the list of recipients of emails without a more specific entry.
+	DEFAULT []string
+	// ADMIN is the list of recipients of the module error emails.
+	ADMIN []string
+}
+
+// Multipart is an item to attach to an email as described in RFC 1521, already encoded (Attachment encodes the files
+// automatically).
 type Multipart struct {
 	Content_type              string
 	Content_transfer_encoding string
@@ -92,15 +135,20 @@ const RAND_STR_LEN int = 10
 const TO_SEND_REL_FOLDER string = "to_send"
 const _EMAIL_MODELS_FOLDER string = "email_models"
 
-const _TEMP_EML_FILE string = "msg_temp.eml"
+// _SMTP_EMERGENCY_CONNECT_TIMEOUT is the connection timeout of emergency emails - long enough to wait for a slow
+// connection, but without blocking the caller forever.
+const _SMTP_EMERGENCY_CONNECT_TIMEOUT time.Duration = 5 * time.Minute
+// _SMTP_DEF_ACCOUNT is the name of the SMTP account used when none is configured.
+const _SMTP_DEF_ACCOUNT string = "default"
 
 const MODEL_FILE_INFO string = "model_email_info.html"
 const MODEL_FILE_RSS string = "model_email_rss.html"
 const MODEL_FILE_YT_VIDEO string = "model_email_video_YouTube.html"
 const MODEL_FILE_DISKS_SMART string = "model_email_disks_smart.html"
-const _MODEL_FILE_MESSAGE_EML string = "model_message.eml"
 /*
-GetModelFileEMAIL returns the contents of an email model file.
+GetModelFileEMAIL returns the contents of an email model file, with the recipients of emails not sent from a module.
+
+Check GetModelFileModEMAIL() for more information.
 
 -----------------------------------------------------------
 
@@ -109,100 +157,240 @@ GetModelFileEMAIL returns the contents of an email model file.
   - things_replace – the map of things to replace in the file
 
 – Returns:
-  - an instance of EmailInfo with the EmailInfo.Sender, EmailInfo.Mail_to and EmailInfo.Html filled and ready
+  - an instance of EmailInfo with the EmailInfo.Sender, EmailInfo.To and EmailInfo.Html filled and ready
 */
 func GetModelFileEMAIL(file_name string, things_replace map[string]string) EmailInfo {
-	var sender string
-	switch file_name {
-		case MODEL_FILE_INFO:
-			sender = "VISOR - Info"
-		case MODEL_FILE_RSS:
-			sender = "VISOR - RSS"
-		case MODEL_FILE_YT_VIDEO:
-			sender = "YouTube"
-		case MODEL_FILE_DISKS_SMART:
-			sender = "VISOR - S.M.A.R.T."
+	return GetModelFileModEMAIL(-1, file_name, things_replace)
+}
+
+/*
+GetModelFileModEMAIL returns the contents of an email model file, with the recipients of the module sending it.
+
+The model is turned into an html/template template, with the placeholders as its values, and rendered as the models of
+RenderModelFileEMAIL() are (with the partial templates), so the values are escaped as HTML and the model can use the
+same template features. The values that are HTML are not escaped: MODEL_INFO_MSG_BODY_EMAIL,
+MODEL_RSS_ENTRY_DESCRIPTION_EMAIL, MODEL_YT_VIDEO_VIDEO_DESCRIPTION_EMAIL, MODEL_DISKS_SMART_ERROR_REPORT_EMAIL and
+MODEL_DISKS_SMART_DISKS_SMART_HTML_EMAIL. The HTML comments of the model are kept.
+
+If the model is not a valid template (like one with "{{" in the text), the error is printed and the placeholders are
+just replaced by their values, escaped the same way.
+
+-----------------------------------------------------------
+
+– Params:
+  - mod_num – the number of the module sending the email, used to choose the recipients, or -1 if not from a module
+  - file_name – the name of the file
+  - things_replace – the map of things to replace in the file
+
+– Returns:
+  - an instance of EmailInfo with the EmailInfo.Sender, EmailInfo.To and EmailInfo.Html filled and ready
+*/
+func GetModelFileModEMAIL(mod_num int, file_name string, things_replace map[string]string) EmailInfo {
+	var keys []string = nil
+	for key := range things_replace {
+		keys = append(keys, key)
 	}
 
-	var msg_html string = *getProgramDataDirMODULES(NUM_MOD_EmailSender).Add2(false, _EMAIL_MODELS_FOLDER, file_name).
-		ReadTextFile()
-	for key, value := range things_replace {
-		msg_html = strings.ReplaceAll(msg_html, key, value)
+	var msg_html string = ""
+	if p_model := getModelsDirEMAIL().Add2(false, file_name).ReadTextFile(); nil != p_model {
+		var err error
+		msg_html, err = renderModelEMAIL(file_name, toTemplateModelEMAIL(*p_model, keys),
+			getModelValuesEMAIL(things_replace))
+		if nil != err {
+			// Not a valid template (like a model with "{{" in the text), so the old way, but escaped.
+			fmt.Println("Error rendering the email model \"" + file_name + "\" (replacing the placeholders " +
+				"instead): " + err.Error())
+			msg_html = replaceModelValuesEMAIL(*p_model, things_replace)
+		}
 	}
 
 	return EmailInfo{
-		Sender:     sender,
-		Mail_to:    PersonalConsts_GL.USER_EMAIL_ADDR,
+		Sender:     getModelSenderEMAIL(file_name),
+		To:         GetRecipientsEMAIL(mod_num, file_name),
 		Subject:    "",
 		Html:       msg_html,
 		Multiparts: nil,
 	}
 }
 
+/*
+GetRecipientsEMAIL gets the recipients of an email from the PersonalConsts.EMAIL_ROUTING table.
+
+-----------------------------------------------------------
+
+– Params:
+  - mod_num – the number of the module sending the email or -1 to ignore
+  - file_name – the name of the email model file or "" to ignore
+
+– Returns:
+  - the list of recipients of the email (never empty)
+*/
+func GetRecipientsEMAIL(mod_num int, file_name string) []string {
+	var emailRouting EmailRouting = PersonalConsts_GL.EMAIL_ROUTING
+
+	if recipients := emailRouting.MODELS[file_name]; "" != file_name && len(recipients) > 0 {
+		return recipients
+	}
+	if recipients := emailRouting.MODULES[strconv.Itoa(mod_num)]; -1 != mod_num && len(recipients) > 0 {
+		return recipients
+	}
+
+	return emailRouting.getDefaultRecipients()
+}
+
+/*
+GetAdminRecipientsEMAIL gets the recipients of the module error emails from the PersonalConsts.EMAIL_ROUTING table.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the list of recipients of the error emails (never empty)
+*/
+func GetAdminRecipientsEMAIL() []string {
+	var emailRouting EmailRouting = PersonalConsts_GL.EMAIL_ROUTING
+
+	if len(emailRouting.ADMIN) > 0 {
+		return emailRouting.ADMIN
+	}
+
+	return emailRouting.getDefaultRecipients()
+}
+
 /*
 QueueEmailEMAIL queues an email to be sent by the UEmail Sender module.
 
+The email is queued once for all the recipients in EmailInfo.To, EmailInfo.Cc and EmailInfo.Bcc, which are kept in
+its metadata file (so the EmailInfo.Bcc ones stay hidden), and is then sent by ProcessEmailQueueEMAIL().
+
 -----CONSTANTS-----
   - MODEL_FILE_INFO – model file for information emails.
   - MODEL_FILE_RSS – model file for RSS feed notification emails.
   - MODEL_FILE_YT_VIDEO – model file for YouTube video notification emails.
-  - _MODEL_FILE_MESSAGE_EML – model file for the main message.eml file.
 -----CONSTANTS-----
 
 -----------------------------------------------------------
 
 – Params:
   - emailInfo – the email info
-  - multiparts – the list of multipart items to attach to the email aside from the main HTML or nil to ignore
 
 – Returns:
   - nil if the email was queued successfully, otherwise an error
 */
 func QueueEmailEMAIL(emailInfo EmailInfo) error {
-	message_eml, _, success := prepareEmlEMAIL(emailInfo)
+	message_eml, recipients, success := prepareEmlEMAIL(emailInfo)
 	if !success {
 		return errors.New("error preparing the EML file")
 	}
 
-	var file_name string = ""
+	return queueEmlEMAIL(message_eml, recipients)
+}
+
+/*
+queueEmlEMAIL writes a prepared EML file to the queue of the Email Sender module.
+
+-----------------------------------------------------------
+
+– Params:
+  - message_eml – the complete message to be sent in EML format
+  - recipients – the envelope recipients of the email
+
+– Returns:
+  - nil if the email was queued successfully, otherwise an error
+*/
+func queueEmlEMAIL(message_eml string, recipients []string) error {
 	var to_send_dir GPath = getUserDataDirMODULES(NUM_MOD_EmailSender).Add2(true, TO_SEND_REL_FOLDER)
+
+	// Lock the queue while writing so that the Email Sender (which locks it too) never sees partial files.
+	return to_send_dir.WithLock(LOCK_EXCLUSIVE, _EMAIL_QUEUE_LOCK_TIMEOUT, func() error {
+		return writeEmlEMAIL(to_send_dir, message_eml, recipients)
+	})
+}
+
+/*
+writeEmlEMAIL writes a prepared EML file to a new file with a unique name in the given directory, along with its
+metadata file.
+
+The names begin with the time, so they sort in the order the emails were queued.
+
+-----------------------------------------------------------
+
+– Params:
+  - to_send_dir – the directory of the queue
+  - message_eml – the complete message to be sent in EML format
+  - recipients – the envelope recipients of the email
+
+– Returns:
+  - nil if the email was written successfully, otherwise an error
+*/
+func writeEmlEMAIL(to_send_dir GPath, message_eml string, recipients []string) error {
 	for {
-		var rand_string string = RandStringGENERAL(RAND_STR_LEN)
-		_, err := os.ReadFile(to_send_dir.Add2(false, rand_string + emailInfo.Mail_to + ".eml").
-			GPathToStringConversion())
-		if nil != err {
-			// If the file doesn't exist, choose that name.
-			file_name = rand_string + emailInfo.Mail_to + ".eml"
+		var file_path GPath = to_send_dir.Add2(false, strconv.FormatInt(time.Now().UnixNano(), 36) + "_" +
+			RandStringGENERAL(RAND_STR_LEN) + ".eml")
+		if file_path.Exists() {
+			continue
+		}
 
-			return getUserDataDirMODULES(NUM_MOD_EmailSender).Add2(false, TO_SEND_REL_FOLDER + file_name).
-				WriteTextFile(message_eml)
+		// If the file doesn't exist, choose that name. The metadata goes first, so the email is never without it.
+		var err error = writeEmailMetaEMAIL(file_path, EmailMeta{
+			Recipients: recipients,
+			State:      EMAIL_STATE_QUEUED,
+			Queued:     time.Now(),
+		})
+		if nil != err {
+			return err
 		}
+
+		return file_path.WriteTextFile(message_eml)
 	}
 }
 
 /*
 SendEmailEMAIL sends an email with the given message and receiver.
 
+The email is sent through the SMTP accounts in the PersonalConsts.SMTP_FAILOVER order, with the next account being
+tried if the previous one is down or refuses the login, but not if the server refuses the message or its recipients
+(it would be refused by the others too). The From header of the email is changed to the address of the account used.
+
 ***DO NOT USE OUTSIDE THE EMAIL SENDER MODULE***
 
 -----------------------------------------------------------
 
 – Params:
   - message_eml – the complete message to be sent in EML format
-  - mail_to – the receiver of the email
-  - emergency_email – true if the email is an emergency email and so will make this function halt until the connection
-	is made, false otherwise
+  - mail_to – the receiver of the email, or many separated by commas
+  - emergency_email – true if the email is an emergency email and so will make this function wait longer (up to 5
+	minutes) for the connection with the last account, false otherwise
 
 – Returns:
-  - nil if the email was sent successfully, otherwise an error
+  - nil if the email was sent successfully, otherwise an error (an *SmtpError if the server refused it, or a
+    *RecipientsError if it refused recipients, with the email sent to the others)
 */
 func SendEmailEMAIL(message_eml string, mail_to string, emergency_email bool) error {
-	if err := getModTempDirMODULES(NUM_MOD_EmailSender).Add2(false, _TEMP_EML_FILE).WriteTextFile(message_eml); nil != err {
-		return err
+	var failover []string = PersonalConsts_GL.SMTP_FAILOVER
+	if 0 == len(failover) {
+		return errors.New("no SMTP accounts configured")
 	}
-	_, err := ExecCmdSHELL([]string{getCurlStringEMAIL(mail_to, emergency_email)})
 
-	return err
+	var errs_str []string = nil
+	for i, account_name := range failover {
+		var smtpAccount SmtpAccount = PersonalConsts_GL._SMTP_ACCOUNTS[account_name]
+		if emergency_email && i == len(failover) - 1 {
+			// Only on the last one, or the others would never be tried.
+			smtpAccount.Connect_timeout = _SMTP_EMERGENCY_CONNECT_TIMEOUT
+		}
+
+		err := SendMailSmtpEMAIL(smtpAccount.SmtpServer, smtpAccount.GetFromAddr(), splitRecipientsEMAIL(mail_to),
+			[]byte(setFromHeaderEMAIL(message_eml, smtpAccount)))
+		if nil == err {
+			return nil
+		}
+		if 1 == len(failover) || !isSmtpFailoverErrorEMAIL(err) {
+			return err
+		}
+		errs_str = append(errs_str, account_name + ": " + err.Error())
+	}
+
+	return errors.New("all SMTP accounts failed (" + strings.Join(errs_str, "; ") + ")")
 }
 
 /*
@@ -239,75 +427,323 @@ prepareEmlEMAIL prepares the EML file of the email.
 
 – Params:
   - emailInfo – the email info
-  - multiparts – the list of multipart items to attach to the email aside from the main HTML or nil to ignore
 
 – Returns:
   - the email EML file to be sent
-  - the receiver of the email
-  - nil if the email was queued successfully, otherwise an error
+  - the envelope recipients of the email (the addresses of EmailInfo.To, EmailInfo.Cc and EmailInfo.Bcc, without
+	repetitions)
+  - true if the email was prepared successfully, false otherwise (like if an address or header is invalid)
 */
-func prepareEmlEMAIL(emailInfo EmailInfo) (string, string, bool) {
-	var p_message_eml *string = getProgramDataDirMODULES(NUM_MOD_EmailSender).Add2(false, _EMAIL_MODELS_FOLDER,
-		_MODEL_FILE_MESSAGE_EML).ReadTextFile()
-	if p_message_eml == nil {
-		return "", "", false
-	}
-	var message_eml string = *p_message_eml
-
-	emailInfo.Html = strings.ReplaceAll(emailInfo.Html, "|3234_EML_SUBJECT|", emailInfo.Subject)
-	emailInfo.Html = strings.ReplaceAll(emailInfo.Html, "|3234_EML_SENDER_NAME|", emailInfo.Sender)
-
-	message_eml = strings.ReplaceAll(message_eml, "|3234_EML_HTML|", *ToQuotedPrintableEMAIL(emailInfo.Html))
-	message_eml = strings.ReplaceAll(message_eml, "|3234_EML_SUBJECT|", emailInfo.Subject)
-	message_eml = strings.ReplaceAll(message_eml, "|3234_EML_SENDER_NAME|", emailInfo.Sender)
-
-	var multiparts_str string = ""
-	if nil != emailInfo.Multiparts {
-		for _, multipart := range emailInfo.Multiparts {
-			multiparts_str += "\n--|3234_EML_BOUNDARY|\n" +
-						"Content-Type: " + multipart.Content_type + "\n" +
-						"Content-Transfer-Encoding: " + multipart.Content_transfer_encoding + "\n" +
-						"Content-ID: <" + multipart.Content_id + ">\n" +
-						"\n" +
-						multipart.Body + "\n\n"
+func prepareEmlEMAIL(emailInfo EmailInfo) (string, []string, bool) {
+	emailInfo.Html = strings.ReplaceAll(emailInfo.Html, "|3234_EML_SUBJECT|", html.EscapeString(emailInfo.Subject))
+	emailInfo.Html = strings.ReplaceAll(emailInfo.Html, "|3234_EML_SENDER_NAME|", html.EscapeString(emailInfo.Sender))
+	emailInfo.Text = strings.ReplaceAll(emailInfo.Text, "|3234_EML_SUBJECT|", emailInfo.Subject)
+	emailInfo.Text = strings.ReplaceAll(emailInfo.Text, "|3234_EML_SENDER_NAME|", emailInfo.Sender)
+
+	message_eml, recipients, err := buildEmlEMAIL(emailInfo, getDefaultFromEMAIL())
+	if nil != err {
+		return "", nil, false
+	}
+
+	return message_eml, recipients, true
+}
+
+/*
+getDefaultFromEMAIL gets the sender of the emails before they're sent (SendEmailEMAIL() changes it to the SMTP account
+used).
+
+-----------------------------------------------------------
+
+– Returns:
+  - the address and display name of the first account of PersonalConsts.SMTP_FAILOVER, or VISOR's address if there
+	are no accounts
+*/
+func getDefaultFromEMAIL() mail.Address {
+	for _, account_name := range PersonalConsts_GL.SMTP_FAILOVER {
+		if smtpAccount, ok := PersonalConsts_GL._SMTP_ACCOUNTS[account_name]; ok {
+			return mail.Address{
+				Name:    smtpAccount.Display_name,
+				Address: smtpAccount.GetFromAddr(),
+			}
 		}
 	}
-	message_eml = strings.ReplaceAll(message_eml, "|3234_EML_MULTIPARTS|", multiparts_str)
 
-	var msg_boundary string = RandStringGENERAL(25)
-	for {
-		if !strings.Contains(message_eml, msg_boundary) {
-			break
+	return mail.Address{
+		Address: PersonalConsts_GL._VISOR_EMAIL_ADDR,
+	}
+}
+
+/*
+getRecipientsHeadersEMAIL gets the recipient and custom headers of an email and its envelope recipients.
+
+The EmailInfo.Bcc recipients are in the envelope only. The deprecated EmailInfo.Mail_to recipients are added to the
+EmailInfo.To ones.
+
+-----------------------------------------------------------
+
+– Params:
+  - emailInfo – the email info
+
+– Returns:
+  - the To, Cc, Reply-To and custom headers, each ending with a line break
+  - the envelope recipients (the addresses of EmailInfo.To, EmailInfo.Cc and EmailInfo.Bcc, without repetitions)
+  - nil if all addresses and headers are valid, an error otherwise
+*/
+func getRecipientsHeadersEMAIL(emailInfo EmailInfo) (string, []string, error) {
+	var headers_str string = ""
+	var recipients []string = nil
+	for _, header := range []struct {
+		name      string
+		addresses []string
+		envelope  bool
+	}{
+		{"To", append(append([]string(nil), emailInfo.To...), splitRecipientsEMAIL(emailInfo.Mail_to)...), true},
+		{"Cc", emailInfo.Cc, true},
+		{"Bcc", emailInfo.Bcc, true},
+		{"Reply-To", emailInfo.Reply_to, false},
+	} {
+		var addresses_str []string = nil
+		for _, address_str := range header.addresses {
+			address, err := mail.ParseAddress(address_str)
+			if nil != err {
+				return "", nil, errors.New("invalid " + header.name + " address \"" + address_str + "\": " + err.Error())
+			}
+			addresses_str = append(addresses_str, address.String())
+			if header.envelope && !ContainsSLICES(recipients, address.Address) {
+				recipients = append(recipients, address.Address)
+			}
+		}
+		if len(addresses_str) > 0 && "Bcc" != header.name {
+			headers_str += foldHeaderEMAIL(header.name, strings.Join(addresses_str, ", "))
+		}
+	}
+
+	var header_names []string = nil
+	for name := range emailInfo.Headers {
+		header_names = append(header_names, name)
+	}
+	sort.Strings(header_names)
+	for _, name := range header_names {
+		var value string = emailInfo.Headers[name]
+		if !isHeaderNameValidEMAIL(name) || strings.ContainsAny(value, "\r\n") {
+			return "", nil, errors.New("invalid custom header \"" + name + "\"")
 		}
-		msg_boundary = RandStringGENERAL(25)
+		switch strings.ToLower(name) {
+			case "from", "to", "cc", "bcc", "reply-to", "subject", "date", "message-id", "mime-version",
+					"content-type", "content-transfer-encoding":
+				return "", nil, errors.New("the header \"" + name + "\" can't be a custom header")
+		}
+		headers_str += foldHeaderEMAIL(name, mime.QEncoding.Encode("utf-8", value))
 	}
-	message_eml = strings.ReplaceAll(message_eml, "|3234_EML_BOUNDARY|", msg_boundary)
 
+	return headers_str, recipients, nil
+}
+
+/*
+isHeaderNameValidEMAIL checks if a string is a valid header name (RFC 5322 printable characters except ":").
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the header name
+
+– Returns:
+  - true if the name is valid, false otherwise
+*/
+func isHeaderNameValidEMAIL(name string) bool {
+	if "" == name {
+		return false
+	}
+	for _, char := range name {
+		if char < 33 || char > 126 || ':' == char {
+			return false
+		}
+	}
 
-	return message_eml, emailInfo.Mail_to, true
+	return true
 }
 
 /*
-getCurlStringEMAIL gets the cURL string that sends an email with the default message file path and sender and receiver.
+splitRecipientsEMAIL splits a list of recipients separated by commas, as in the mail_to of SendEmailEMAIL().
 
 -----------------------------------------------------------
 
 – Params:
-  - mail_to – the receiver of the email
-  - emergency_email – true if the email is an emergency email and so will make this function halt until the connection
-    is made, false otherwise
+  - mail_to – the recipients separated by commas
+
+– Returns:
+  - the list of recipients, without empty ones
+*/
+func splitRecipientsEMAIL(mail_to string) []string {
+	var recipients []string = nil
+	for _, recipient := range strings.Split(mail_to, ",") {
+		recipient = strings.TrimSpace(recipient)
+		if "" != recipient {
+			recipients = append(recipients, recipient)
+		}
+	}
+
+	return recipients
+}
+
+/*
+getDefaultRecipients gets the recipients of emails without a more specific entry in the table.
+
+-----------------------------------------------------------
+
+– Returns:
+  - EmailRouting.DEFAULT if it's not empty, else a list with PersonalConsts.USER_EMAIL_ADDR
+*/
+func (emailRouting EmailRouting) getDefaultRecipients() []string {
+	if len(emailRouting.DEFAULT) > 0 {
+		return emailRouting.DEFAULT
+	}
+
+	return []string{PersonalConsts_GL.USER_EMAIL_ADDR}
+}
+
+/*
+isValid checks if all the addresses in the table are valid email addresses (like "a@b.com" or "Name <a@b.com>") and
+the keys of EmailRouting.MODULES are module numbers.
+
+-----------------------------------------------------------
 
 – Returns:
-  - the string ready to be executed by the system
+  - true if all the addresses are valid (or if there are none), false otherwise
 */
-func getCurlStringEMAIL(mail_to string, emergency_email bool) string {
-	var timeout string = "10"
-	if emergency_email {
-		timeout = "100000"
+func (emailRouting EmailRouting) isValid() bool {
+	var lists [][]string = [][]string{emailRouting.DEFAULT, emailRouting.ADMIN}
+	for _, recipients := range emailRouting.MODELS {
+		lists = append(lists, recipients)
+	}
+	for mod_num_str, recipients := range emailRouting.MODULES {
+		if _, err := strconv.Atoi(mod_num_str); nil != err {
+			return false
+		}
+		lists = append(lists, recipients)
+	}
+
+	for _, recipients := range lists {
+		for _, recipient := range recipients {
+			// The same parsing as when the emails are prepared, so that an entry that passes here never fails there.
+			if _, err := mail.ParseAddress(recipient); nil != err {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+/*
+getDefaultSmtpAccountEMAIL gets the SMTP account used when none is configured: VISOR's Gmail address.
+
+-----------------------------------------------------------
+
+– Params:
+  - visor_email_addr – VISOR's email address
+  - visor_email_pw – VISOR's email password
+
+– Returns:
+  - the SMTP account
+*/
+func getDefaultSmtpAccountEMAIL(visor_email_addr string, visor_email_pw string) SmtpAccount {
+	return SmtpAccount{
+		SmtpServer: SmtpServer{
+			Host:     "smtp.gmail.com",
+			Port:     587,
+			Tls_mode: SMTP_TLS_STARTTLS,
+			Auth:     SMTP_AUTH_AUTO,
+			Username: visor_email_addr,
+			Password: visor_email_pw,
+		},
+		From_addr: visor_email_addr,
+	}
+}
+
+/*
+isSmtpFailoverErrorEMAIL checks if an error of SendMailSmtpEMAIL() means the email should be sent through another SMTP
+account.
+
+-----------------------------------------------------------
+
+– Params:
+  - err – the error
+
+– Returns:
+  - false if the server refused the email or its recipients for good or sent it to some of the recipients, true
+    otherwise (like if it was down)
+*/
+func isSmtpFailoverErrorEMAIL(err error) bool {
+	var recipientsError *RecipientsError
+	if errors.As(err, &recipientsError) {
+		// Not if the message was sent to some recipients, or they'd get it again.
+		return !recipientsError.Sent && !recipientsError.IsPermanent()
+	}
+
+	var smtpError *SmtpError
+	if !errors.As(err, &smtpError) || !smtpError.IsPermanent() {
+		return true
+	}
+
+	switch smtpError.Command {
+		case "MAIL FROM", "RCPT TO", "DATA":
+			return false
+	}
+
+	return true
+}
+
+/*
+setFromHeaderEMAIL sets the From header of an email to the address of an SMTP account, keeping the sender name.
+
+-----------------------------------------------------------
+
+– Params:
+  - message_eml – the email in EML format
+  - smtpAccount – the account the email will be sent through
+
+– Returns:
+  - the email with the From header changed (or added if there was none)
+*/
+func setFromHeaderEMAIL(message_eml string, smtpAccount SmtpAccount) string {
+	var headers_end int = len(message_eml)
+	for _, separator := range []string{"\r\n\r\n", "\n\n"} {
+		// The headers keep the line break of their last line.
+		if idx := strings.Index(message_eml, separator); idx >= 0 && idx + len(separator)/2 < headers_end {
+			headers_end = idx + len(separator)/2
+		}
+	}
+	var lines []string = strings.SplitAfter(message_eml[:headers_end], "\n")
+
+	var from_address mail.Address = mail.Address{
+		Name:    smtpAccount.Display_name,
+		Address: smtpAccount.GetFromAddr(),
+	}
+	for i := 0; i < len(lines); i++ {
+		if !strings.HasPrefix(strings.ToLower(lines[i]), "from:") {
+			continue
+		}
+
+		var line_end string = lines[i][len(strings.TrimRight(lines[i], "\r\n")):]
+		// Remove the folded continuation lines of the header too.
+		var value string = strings.TrimSpace(lines[i][len("from:"):])
+		var j int = i + 1
+		for ; j < len(lines) && ("" != lines[j] && (' ' == lines[j][0] || '\t' == lines[j][0])); j++ {
+			value += " " + strings.TrimSpace(lines[j])
+		}
+		if old_address, err := mail.ParseAddress(value); nil == err && "" != old_address.Name {
+			from_address.Name = old_address.Name
+		}
+		if "" == line_end {
+			// The header was the last line of the email, without a line break.
+			line_end = "\n"
+		}
+
+		lines = append(lines[:i], append([]string{"From: " + from_address.String() + line_end}, lines[j:]...)...)
+
+		return strings.Join(lines, "") + message_eml[headers_end:]
 	}
 
-	return "curl{{EXE}} --location --connect-timeout " + timeout + " --verbose \"smtp://smtp.gmail.com:587\" --user \"" +
-		PersonalConsts_GL._VISOR_EMAIL_ADDR + ":" + PersonalConsts_GL._VISOR_EMAIL_PW + "\" --mail-rcpt \"" + mail_to +
-		"\" --upload-file \"" + getModTempDirMODULES(NUM_MOD_EmailSender).Add2(false, _TEMP_EML_FILE).GPathToStringConversion() +
-		"\" --ssl-reqd"
+	return "From: " + from_address.String() + "\n" + message_eml
 }
@@ -0,0 +1,259 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package Utils
+
+import (
+	"encoding/base64"
+	"errors"
+	"mime"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// _EML_BASE64_LINE_LEN is the length of the lines of the base64 bodies (the limit of RFC 2045).
+	_EML_BASE64_LINE_LEN int = 76
+	// _EML_PARAM_SECTION_LEN is the maximum length of the encoded value in each section of a header parameter split in
+	// RFC 2231 continuations.
+	_EML_PARAM_SECTION_LEN int = 48
+)
+
+// Attachment is a file attached to an email.
+type Attachment struct {
+	// Name is the file name shown to the recipients.
+	Name string
+	// Content_type is the MIME type of the file.
+	Content_type string
+	// Data is the contents of the file.
+	Data []byte
+	// Content_id is the ID to reference the file in the HTML with "cid:" if it's shown inline, or empty if it's a
+	// normal attachment.
+	Content_id string
+}
+
+/*
+AttachFile attaches a file to the email, with its type detected from the name or else the contents.
+
+-----------------------------------------------------------
+
+– Params:
+  - gPath – the path to the file
+  - inline – true to show the file inside the HTML (like an image), false for a normal attachment
+
+– Returns:
+  - the Content-ID to use in the HTML as "cid:[Content-ID]" if inline is true, else an empty string
+  - nil if the file was attached, an error otherwise (like if it couldn't be read)
+*/
+func (emailInfo *EmailInfo) AttachFile(gPath GPath, inline bool) (string, error) {
+	if gPath.DescribesDir() {
+		return "", errors.New("the path describes a directory")
+	}
+
+	data, err := readFileFS(gPath.getFS(), gPath.GPathToStringConversion())
+	if nil != err {
+		return "", err
+	}
+
+	return emailInfo.AttachBytes(gPath.Name(), data, inline), nil
+}
+
+/*
+AttachBytes attaches data to the email as a file, with its type detected from the name or else the contents.
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the file name shown to the recipients
+  - data – the contents of the file
+  - inline – true to show the file inside the HTML (like an image), false for a normal attachment
+
+– Returns:
+  - the Content-ID to use in the HTML as "cid:[Content-ID]" if inline is true, else an empty string
+*/
+func (emailInfo *EmailInfo) AttachBytes(name string, data []byte, inline bool) string {
+	var content_type string = mime.TypeByExtension(strings.ToLower(path.Ext(name)))
+	if "" == content_type || strings.HasPrefix(content_type, "application/octet-stream") {
+		// Unknown or generic type from the name, so the contents decide.
+		content_type = http.DetectContentType(data)
+	}
+
+	var content_id string = ""
+	if inline {
+		content_id = RandStringGENERAL(RAND_STR_LEN) + "@" + getAddrDomainEMAIL(getDefaultFromEMAIL().Address)
+	}
+
+	emailInfo.Attachments = append(emailInfo.Attachments, Attachment{
+		Name:         name,
+		Content_type: content_type,
+		Data:         data,
+		Content_id:   content_id,
+	})
+
+	return content_id
+}
+
+/*
+getMimePart gets the part of an email with the attachment.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the part, encoded in base64
+*/
+func (attachment Attachment) getMimePart() *_MimePart {
+	var disposition string = "attachment"
+	var headers [][2]string = nil
+	if "" != attachment.Content_id {
+		disposition = "inline"
+		headers = append(headers, [2]string{"Content-ID", "<" + attachment.Content_id + ">"})
+	}
+
+	media_type, params, err := mime.ParseMediaType(attachment.Content_type)
+	if nil != err {
+		// Invalid type - the name would be lost with it, so it's the generic one.
+		media_type = "application/octet-stream"
+		params = map[string]string{}
+	}
+	params["name"] = attachment.Name
+	var content_type string = formatMediaTypeEMAIL(media_type, params)
+	headers = append(headers,
+		[2]string{"Content-Transfer-Encoding", "base64"},
+		[2]string{"Content-Disposition", formatMediaTypeEMAIL(disposition, map[string]string{"filename": attachment.Name})},
+	)
+
+	return &_MimePart{
+		content_type: content_type,
+		headers:      headers,
+		body:         toBase64LinesEMAIL(attachment.Data),
+	}
+}
+
+/*
+toBase64LinesEMAIL encodes data in base64 with the lines of the email bodies.
+
+-----------------------------------------------------------
+
+– Params:
+  - data – the data to encode
+
+– Returns:
+  - the encoded data, split in lines of _EML_BASE64_LINE_LEN characters, each ending with a line break
+*/
+func toBase64LinesEMAIL(data []byte) string {
+	var encoded string = base64.StdEncoding.EncodeToString(data)
+
+	var lines strings.Builder
+	for len(encoded) > _EML_BASE64_LINE_LEN {
+		lines.WriteString(encoded[:_EML_BASE64_LINE_LEN] + _EML_LINE_BREAK)
+		encoded = encoded[_EML_BASE64_LINE_LEN:]
+	}
+	lines.WriteString(encoded + _EML_LINE_BREAK)
+
+	return lines.String()
+}
+
+/*
+formatMediaTypeEMAIL formats a header value with a media type and parameters, as mime.FormatMediaType(), but with the
+long parameters split in RFC 2231 continuations ("name*0*=utf-8''...; name*1*=..."), so that the header can be folded
+in short lines whatever the length of the values (like long non-ASCII file names).
+
+-----------------------------------------------------------
+
+– Params:
+  - media_type – the media type or disposition
+  - params – the parameters
+
+– Returns:
+  - the header value or an empty string if the media type or a parameter name is invalid
+*/
+func formatMediaTypeEMAIL(media_type string, params map[string]string) string {
+	var short_params map[string]string = map[string]string{}
+	var long_keys []string = nil
+	for key, value := range params {
+		// FormatMediaType() quotes or encodes the value as in RFC 2231 if needed.
+		var param string = strings.TrimPrefix(mime.FormatMediaType("x", map[string]string{key: value}), "x; ")
+		// The parameter must fit in a folded line, with the space before it and the ";" after it.
+		if len(param) + 2 > _EML_MAX_LINE_LEN {
+			long_keys = append(long_keys, key)
+		} else {
+			short_params[key] = value
+		}
+	}
+
+	var header_value string = mime.FormatMediaType(media_type, short_params)
+	if "" == header_value {
+		return ""
+	}
+
+	sort.Strings(long_keys)
+	for _, key := range long_keys {
+		var encoded string = "utf-8''" + encodeParamValueEMAIL(params[key])
+		for section := 0; "" != encoded; section++ {
+			var section_len int = _EML_PARAM_SECTION_LEN
+			if section_len > len(encoded) {
+				section_len = len(encoded)
+			}
+			// Never split a "%XX" sequence.
+			if idx := strings.LastIndex(encoded[:section_len], "%"); -1 != idx && idx + 3 > section_len &&
+					section_len < len(encoded) {
+				section_len = idx
+			}
+			header_value += "; " + strings.ToLower(key) + "*" + strconv.Itoa(section) + "*=" + encoded[:section_len]
+			encoded = encoded[section_len:]
+		}
+	}
+
+	return header_value
+}
+
+/*
+encodeParamValueEMAIL encodes a header parameter value with the percent encoding of RFC 2231.
+
+-----------------------------------------------------------
+
+– Params:
+  - value – the value to encode, in UTF-8
+
+– Returns:
+  - the value with all the bytes that are not attribute characters encoded as "%XX"
+*/
+func encodeParamValueEMAIL(value string) string {
+	const hex_digits string = "0123456789ABCDEF"
+
+	var encoded strings.Builder
+	for i := 0; i < len(value); i++ {
+		var c byte = value[i]
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+				strings.IndexByte("!#$&+-.^_`|~", c) >= 0 {
+			encoded.WriteByte(c)
+		} else {
+			encoded.WriteByte('%')
+			encoded.WriteByte(hex_digits[c >> 4])
+			encoded.WriteByte(hex_digits[c & 0x0F])
+		}
+	}
+
+	return encoded.String()
+}
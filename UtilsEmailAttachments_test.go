@@ -0,0 +1,201 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package Utils
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+// _TestMimeLeaf is a part of an email without other parts inside.
+type _TestMimeLeaf struct {
+	header textproto.MIMEHeader
+	body   []byte
+}
+
+// getTestMimeLeaves gets all the parts without other parts inside of a multipart body, in order.
+func getTestMimeLeaves(t *testing.T, content_type string, body io.Reader) []_TestMimeLeaf {
+	t.Helper()
+	media_type, params, err := mime.ParseMediaType(content_type)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(media_type, "multipart/") {
+		data, err := io.ReadAll(body)
+		if nil != err {
+			t.Fatal(err)
+		}
+
+		return []_TestMimeLeaf{{header: textproto.MIMEHeader{"Content-Type": {content_type}}, body: data}}
+	}
+
+	var leaves []_TestMimeLeaf = nil
+	var reader *multipart.Reader = multipart.NewReader(body, params["boundary"])
+	for {
+		part, err := reader.NextRawPart()
+		if io.EOF == err {
+			break
+		} else if nil != err {
+			t.Fatal(err)
+		}
+		if strings.HasPrefix(part.Header.Get("Content-Type"), "multipart/") {
+			leaves = append(leaves, getTestMimeLeaves(t, part.Header.Get("Content-Type"), part)...)
+
+			continue
+		}
+		data, err := io.ReadAll(part)
+		if nil != err {
+			t.Fatal(err)
+		}
+		leaves = append(leaves, _TestMimeLeaf{header: part.Header, body: data})
+	}
+
+	return leaves
+}
+
+func TestAttachments(t *testing.T) {
+	var png_data []byte = append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0, 1, 2, 3}, 100)...)
+	var long_name string = strings.Repeat("relatório é ", 100) + ".txt"
+
+	var emailInfo EmailInfo = EmailInfo{
+		To:      []string{"a@example.com"},
+		Subject: "Subject",
+	}
+	var content_id string = emailInfo.AttachBytes("image", png_data, true)
+	if "" == content_id || strings.ContainsAny(content_id, "<> ") {
+		t.Fatalf("invalid Content-ID %q", content_id)
+	}
+	if got := emailInfo.AttachBytes("report.pdf", []byte("%PDF-1.4"), false); "" != got {
+		t.Errorf("Content-ID %q for a normal attachment", got)
+	}
+	emailInfo.AttachBytes("relatório.txt", []byte("olá"), false)
+	emailInfo.AttachBytes(long_name, []byte("long"), false)
+	emailInfo.Html = "<p>Hi</p><img src=\"cid:" + content_id + "\">"
+
+	eml, _, err := buildEmlEMAIL(emailInfo, mail.Address{Address: "visor@example.com"})
+	if nil != err {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(eml, "\r\n") {
+		if len(line) > _EML_MAX_LINE_LEN {
+			t.Errorf("line too long: %q", line)
+		}
+	}
+	message, err := mail.ReadMessage(strings.NewReader(eml))
+	if nil != err {
+		t.Fatal(err)
+	}
+	var leaves []_TestMimeLeaf = getTestMimeLeaves(t, message.Header.Get("Content-Type"), message.Body)
+
+	var tests = []struct {
+		name            string
+		filename        string
+		data            []byte
+		wantType        string
+		wantDisposition string
+		wantContentId   string
+	}{
+		{"sniffed inline", "image", png_data, "image/png", "inline", "<" + content_id + ">"},
+		{"attachment", "report.pdf", []byte("%PDF-1.4"), "application/pdf", "attachment", ""},
+		{"non-ASCII name", "relatório.txt", []byte("olá"), "text/plain", "attachment", ""},
+		{"long non-ASCII name", long_name, []byte("long"), "text/plain", "attachment", ""},
+	}
+	// The text and the HTML come first.
+	if 2 + len(tests) != len(leaves) {
+		t.Fatalf("%d parts, want %d", len(leaves), 2 + len(tests))
+	}
+	for i, test := range tests {
+		var leaf _TestMimeLeaf = leaves[2 + i]
+		t.Run(test.name, func(t *testing.T) {
+			media_type, params, err := mime.ParseMediaType(leaf.header.Get("Content-Type"))
+			if nil != err || test.wantType != media_type || test.filename != params["name"] {
+				t.Errorf("Content-Type %q, %v (error %v), want %s with the name", media_type, params, err, test.wantType)
+			}
+			disposition, params, err := mime.ParseMediaType(leaf.header.Get("Content-Disposition"))
+			if nil != err || test.wantDisposition != disposition || test.filename != params["filename"] {
+				t.Errorf("Content-Disposition %q, %v (error %v), want %s with the name", disposition, params, err,
+					test.wantDisposition)
+			}
+			if got := leaf.header.Get("Content-ID"); test.wantContentId != got {
+				t.Errorf("Content-ID %q, want %q", got, test.wantContentId)
+			}
+
+			for _, line := range strings.Split(strings.TrimSuffix(string(leaf.body), "\r\n"), "\r\n") {
+				if len(line) > _EML_BASE64_LINE_LEN {
+					t.Errorf("base64 line of %d characters", len(line))
+				}
+			}
+			data, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(string(leaf.body), "\r\n", ""))
+			if nil != err || !bytes.Equal(test.data, data) {
+				t.Errorf("data %q (error %v), want %q", data, err, test.data)
+			}
+		})
+	}
+
+	// Short non-ASCII names are encoded as in RFC 2231 without continuations.
+	if disposition := leaves[4].header.Get("Content-Disposition"); !strings.Contains(disposition, "filename*=utf-8''") {
+		t.Errorf("Content-Disposition %q, want the name encoded as in RFC 2231", disposition)
+	}
+	if disposition := leaves[5].header.Get("Content-Disposition"); !strings.Contains(disposition, "filename*0*=utf-8''") {
+		t.Errorf("Content-Disposition %q, want the name in RFC 2231 continuations", disposition)
+	}
+}
+
+func TestFormatMediaType(t *testing.T) {
+	var tests = []struct {
+		name   string
+		params map[string]string
+		want   string
+	}{
+		{"short", map[string]string{"filename": "a b.txt"}, "attachment; filename=\"a b.txt\""},
+		{"short non-ASCII", map[string]string{"filename": "é.txt"}, "attachment; filename*=utf-8''%C3%A9.txt"},
+		{"long", map[string]string{"filename": strings.Repeat("a", 60) + "é" + strings.Repeat("b", 40)},
+			"attachment; filename*0*=utf-8''" + strings.Repeat("a", 41) + "; filename*1*=" + strings.Repeat("a", 19) +
+				"%C3%A9" + strings.Repeat("b", 23) + "; filename*2*=" + strings.Repeat("b", 17)},
+		// A "%XX" sequence is never split between sections.
+		{"split before an encoded byte", map[string]string{"filename": strings.Repeat("a", 39) + "é" + strings.Repeat("b", 40)},
+			"attachment; filename*0*=utf-8''" + strings.Repeat("a", 39) + "; filename*1*=%C3%A9" +
+				strings.Repeat("b", 40)},
+		{"invalid parameter name", map[string]string{"file name": "a"}, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got string = formatMediaTypeEMAIL("attachment", test.params)
+			if test.want != got {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+			if "" == got {
+				return
+			}
+			if _, params, err := mime.ParseMediaType(got); nil != err || test.params["filename"] != params["filename"] {
+				t.Errorf("parsed %v (error %v), want %v", params, err, test.params)
+			}
+		})
+	}
+}
@@ -0,0 +1,282 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package Utils
+
+import (
+	"errors"
+	"mime"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// _EML_LINE_BREAK is the line break of the generated emails.
+	_EML_LINE_BREAK string = "\r\n"
+	// _EML_MAX_LINE_LEN is the length after which the header lines are folded (the recommended limit of RFC 5322).
+	_EML_MAX_LINE_LEN int = 78
+	// _EML_BOUNDARY_LEN is the length of the random part of the multipart boundaries.
+	_EML_BOUNDARY_LEN int = 25
+)
+
+// _MimePart is a part of an email as described in RFC 2045 and RFC 2046 - a leaf with a body or a multipart with other
+// parts.
+type _MimePart struct {
+	// content_type is the Content-Type of the part, without the boundary of the multipart ones.
+	content_type string
+	// headers is the list of the other headers of the part, in order, as pairs of name and value.
+	headers [][2]string
+	// body is the already encoded body of a leaf part.
+	body string
+	// parts is the list of the sub-parts of a multipart part.
+	parts []*_MimePart
+}
+
+/*
+buildEmlEMAIL builds the complete EML message of an email.
+
+The HTML goes in a multipart/related part with the EmailInfo.Multiparts and the inline EmailInfo.Attachments if there
+are any, that goes in a multipart/alternative part after the EmailInfo.Text (or the text generated from the HTML),
+and that goes in a multipart/mixed part with the other attachments if there are any.
+
+-----------------------------------------------------------
+
+– Params:
+  - emailInfo – the email info
+  - from – the sender of the email (its name is replaced by EmailInfo.Sender if that's not empty)
+
+– Returns:
+  - the EML message
+  - the envelope recipients of the email (the addresses of EmailInfo.To, EmailInfo.Cc and EmailInfo.Bcc, without
+	repetitions)
+  - nil if the email was built successfully, an error otherwise (like if an address or header is invalid)
+*/
+func buildEmlEMAIL(emailInfo EmailInfo, from mail.Address) (string, []string, error) {
+	headers_str, recipients, err := getRecipientsHeadersEMAIL(emailInfo)
+	if nil != err {
+		return "", nil, err
+	}
+	if 0 == len(recipients) {
+		return "", nil, errors.New("no recipients")
+	}
+	if "" != emailInfo.Sender {
+		from.Name = emailInfo.Sender
+	}
+
+	var text string = emailInfo.Text
+	if "" == text {
+		text = HtmlToTextEMAIL(emailInfo.Html)
+	}
+	var p_text_qp *string = ToQuotedPrintableEMAIL(text)
+	var p_html_qp *string = ToQuotedPrintableEMAIL(emailInfo.Html)
+	if nil == p_text_qp || nil == p_html_qp {
+		return "", nil, errors.New("error encoding the body")
+	}
+	var mimePart *_MimePart = &_MimePart{
+		content_type: "text/html; charset=utf-8",
+		headers:      [][2]string{{"Content-Transfer-Encoding", "quoted-printable"}},
+		body:         *p_html_qp,
+	}
+	var inline_parts []*_MimePart = nil
+	var attached_parts []*_MimePart = nil
+	for _, attachment := range emailInfo.Attachments {
+		if "" != attachment.Content_id {
+			inline_parts = append(inline_parts, attachment.getMimePart())
+		} else {
+			attached_parts = append(attached_parts, attachment.getMimePart())
+		}
+	}
+	if len(emailInfo.Multiparts) > 0 || len(inline_parts) > 0 {
+		mimePart = &_MimePart{
+			content_type: "multipart/related; type=\"text/html\"",
+			parts:        []*_MimePart{mimePart},
+		}
+		for _, multipart := range emailInfo.Multiparts {
+			mimePart.parts = append(mimePart.parts, &_MimePart{
+				content_type: multipart.Content_type,
+				headers: [][2]string{
+					{"Content-Transfer-Encoding", multipart.Content_transfer_encoding},
+					{"Content-ID", "<" + multipart.Content_id + ">"},
+				},
+				body: multipart.Body,
+			})
+		}
+		mimePart.parts = append(mimePart.parts, inline_parts...)
+	}
+	// The preferred alternative goes last.
+	mimePart = &_MimePart{
+		content_type: "multipart/alternative",
+		parts:        []*_MimePart{
+			{
+				content_type: "text/plain; charset=utf-8",
+				headers:      [][2]string{{"Content-Transfer-Encoding", "quoted-printable"}},
+				body:         *p_text_qp,
+			},
+			mimePart,
+		},
+	}
+	if len(attached_parts) > 0 {
+		mimePart = &_MimePart{
+			content_type: "multipart/mixed",
+			parts:        append([]*_MimePart{mimePart}, attached_parts...),
+		}
+	}
+
+	var eml strings.Builder
+	eml.WriteString(foldHeaderEMAIL("From", from.String()))
+	eml.WriteString(headers_str)
+	eml.WriteString(foldHeaderEMAIL("Subject", mime.QEncoding.Encode("utf-8", emailInfo.Subject)))
+	eml.WriteString(foldHeaderEMAIL("Date", time.Now().Format(time.RFC1123Z)))
+	eml.WriteString(foldHeaderEMAIL("Message-ID", getMessageIdEMAIL(from.Address)))
+	eml.WriteString(foldHeaderEMAIL("MIME-Version", "1.0"))
+	mimePart.write(&eml)
+
+	return eml.String(), recipients, nil
+}
+
+/*
+write writes the part (its headers, an empty line and its body or sub-parts) in EML format.
+
+-----------------------------------------------------------
+
+– Params:
+  - eml – the builder to write to
+*/
+func (mimePart *_MimePart) write(eml *strings.Builder) {
+	var content_type string = mimePart.content_type
+	var boundary string = ""
+	if len(mimePart.parts) > 0 {
+		// "=_" can't appear in quoted-printable nor base64 bodies, so the boundary never clashes with them.
+		boundary = "=_" + RandStringGENERAL(_EML_BOUNDARY_LEN)
+		content_type += "; boundary=\"" + boundary + "\""
+	}
+
+	eml.WriteString(foldHeaderEMAIL("Content-Type", content_type))
+	for _, header := range mimePart.headers {
+		eml.WriteString(foldHeaderEMAIL(header[0], header[1]))
+	}
+	eml.WriteString(_EML_LINE_BREAK)
+
+	if "" == boundary {
+		eml.WriteString(toEmlLineBreaksEMAIL(mimePart.body))
+		if !strings.HasSuffix(mimePart.body, "\n") {
+			eml.WriteString(_EML_LINE_BREAK)
+		}
+
+		return
+	}
+
+	for _, part := range mimePart.parts {
+		eml.WriteString("--" + boundary + _EML_LINE_BREAK)
+		part.write(eml)
+	}
+	eml.WriteString("--" + boundary + "--" + _EML_LINE_BREAK)
+}
+
+/*
+foldHeaderEMAIL generates a header line, folded at the spaces so that the lines don't exceed the recommended length.
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the name of the header
+  - value – the value of the header, already encoded
+
+– Returns:
+  - the header line(s), ending with a line break
+*/
+func foldHeaderEMAIL(name string, value string) string {
+	var words []string = strings.Split(strings.TrimSpace(value), " ")
+
+	var header strings.Builder
+	header.WriteString(name + ":")
+	var line_len int = len(name) + 1
+	for _, word := range words {
+		if "" == word {
+			continue
+		}
+		// Never fold right after the name, as the value would start on an empty line.
+		if line_len + 1 + len(word) > _EML_MAX_LINE_LEN && line_len > len(name) + 1 {
+			header.WriteString(_EML_LINE_BREAK)
+			line_len = 0
+		}
+		header.WriteString(" " + word)
+		line_len += 1 + len(word)
+	}
+	header.WriteString(_EML_LINE_BREAK)
+
+	return header.String()
+}
+
+/*
+getMessageIdEMAIL generates a unique Message-ID for an email.
+
+-----------------------------------------------------------
+
+– Params:
+  - from_addr – the address of the sender, whose domain is used in the ID
+
+– Returns:
+  - the Message-ID, with the angle brackets
+*/
+func getMessageIdEMAIL(from_addr string) string {
+	return "<" + strconv.FormatInt(time.Now().UnixNano(), 36) + "." + RandStringGENERAL(RAND_STR_LEN) + "@" +
+		getAddrDomainEMAIL(from_addr) + ">"
+}
+
+/*
+getAddrDomainEMAIL gets the domain of an email address, to generate IDs unique to it.
+
+-----------------------------------------------------------
+
+– Params:
+  - addr – the email address
+
+– Returns:
+  - the domain of the address, or "localhost" if it has none
+*/
+func getAddrDomainEMAIL(addr string) string {
+	if idx := strings.LastIndex(addr, "@"); idx >= 0 && idx < len(addr) - 1 {
+		return addr[idx + 1:]
+	}
+
+	return "localhost"
+}
+
+/*
+toEmlLineBreaksEMAIL converts all line breaks of a string ("\n", "\r\n" or "\r") to the ones of the emails.
+
+-----------------------------------------------------------
+
+– Params:
+  - str – the string
+
+– Returns:
+  - the string with the line breaks converted
+*/
+func toEmlLineBreaksEMAIL(str string) string {
+	str = strings.ReplaceAll(str, "\r\n", "\n")
+	str = strings.ReplaceAll(str, "\r", "\n")
+
+	return strings.ReplaceAll(str, "\n", _EML_LINE_BREAK)
+}
@@ -0,0 +1,128 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package Utils
+
+import (
+	"net/mail"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFoldHeader(t *testing.T) {
+	var long_value string = strings.TrimSpace(strings.Repeat("word ", 30))
+	var long_word string = strings.Repeat("x", 100)
+
+	var tests = []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"short", "value", "Name: value\r\n"},
+		{"trimmed", "  a  b  ", "Name: a b\r\n"},
+		{"long", long_value, "Name:" + strings.Repeat(" word", 14) + "\r\n" + strings.Repeat(" word", 15) + "\r\n" +
+			" word\r\n"},
+		// A word longer than the limit can't be folded, and never goes to a line of its own right after the name.
+		{"long word", long_word + " end", "Name: " + long_word + "\r\n end\r\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got string = foldHeaderEMAIL("Name", test.value)
+			if test.want != got {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+			for _, line := range strings.Split(strings.TrimSuffix(got, "\r\n"), "\r\n") {
+				if len(line) > _EML_MAX_LINE_LEN && !strings.Contains(line, long_word) {
+					t.Errorf("line too long: %q", line)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildEml(t *testing.T) {
+	var from mail.Address = mail.Address{Name: "VISOR", Address: "visor@example.com"}
+
+	var tests = []struct {
+		name           string
+		emailInfo      EmailInfo
+		wantRecipients []string
+		wantTo         string
+		wantCc         string
+		wantErr        bool
+	}{
+		{"to only", EmailInfo{To: []string{"a@example.com"}}, []string{"a@example.com"}, "<a@example.com>", "", false},
+		{"union of To, Cc and Bcc without repetitions", EmailInfo{
+			To:  []string{"A <a@example.com>", "b@example.com"},
+			Cc:  []string{"c@example.com", "a@example.com"},
+			Bcc: []string{"hidden@example.com", "b@example.com"},
+		}, []string{"a@example.com", "b@example.com", "c@example.com", "hidden@example.com"},
+			"\"A\" <a@example.com>, <b@example.com>", "<c@example.com>, <a@example.com>", false},
+		{"deprecated Mail_to", EmailInfo{To: []string{"a@example.com"}, Mail_to: "b@example.com, c@example.com"},
+			[]string{"a@example.com", "b@example.com", "c@example.com"},
+			"<a@example.com>, <b@example.com>, <c@example.com>", "", false},
+		{"Bcc only", EmailInfo{Bcc: []string{"hidden@example.com"}}, []string{"hidden@example.com"}, "", "", false},
+		{"no recipients", EmailInfo{}, nil, "", "", true},
+		{"invalid address", EmailInfo{To: []string{"not an address"}}, nil, "", "", true},
+		{"custom Date header", EmailInfo{To: []string{"a@example.com"}, Headers: map[string]string{"Date": "x"}},
+			nil, "", "", true},
+		{"custom Message-ID header", EmailInfo{To: []string{"a@example.com"},
+			Headers: map[string]string{"message-id": "<x@y>"}}, nil, "", "", true},
+		{"custom header with line break", EmailInfo{To: []string{"a@example.com"},
+			Headers: map[string]string{"X-Test": "a\r\nBcc: evil@example.com"}}, nil, "", "", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.emailInfo.Subject = "Subject"
+			test.emailInfo.Html = "<p>Hi</p>"
+			eml, recipients, err := buildEmlEMAIL(test.emailInfo, from)
+			if (nil != err) != test.wantErr {
+				t.Fatalf("got error %v, want error %v", err, test.wantErr)
+			}
+			if test.wantErr {
+				return
+			}
+
+			if !reflect.DeepEqual(recipients, test.wantRecipients) {
+				t.Errorf("recipients %v, want %v", recipients, test.wantRecipients)
+			}
+			message, err := mail.ReadMessage(strings.NewReader(eml))
+			if nil != err {
+				t.Fatal(err)
+			}
+			if _, ok := message.Header["Bcc"]; ok || strings.Contains(eml, "hidden@example.com") {
+				t.Error("the Bcc recipients are in the message")
+			}
+			if got := message.Header.Get("To"); test.wantTo != got {
+				t.Errorf("To %q, want %q", got, test.wantTo)
+			}
+			if got := message.Header.Get("Cc"); test.wantCc != got {
+				t.Errorf("Cc %q, want %q", got, test.wantCc)
+			}
+			for _, name := range []string{"From", "Subject", "Date", "Message-Id", "Mime-Version", "Content-Type"} {
+				if 1 != len(message.Header[name]) {
+					t.Errorf("%d %s headers", len(message.Header[name]), name)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,557 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package Utils
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// SENDING_REL_FOLDER is the folder of the Email Sender with the emails claimed by ProcessEmailQueueEMAIL() and being sent.
+const SENDING_REL_FOLDER string = "sending"
+// DEAD_LETTER_REL_FOLDER is the folder of the Email Sender with the emails that could not be sent, with their errors in
+// their metadata files.
+const DEAD_LETTER_REL_FOLDER string = "dead_letter"
+// EMAIL_META_EXT is the extension added to the name of a queued email for its metadata file.
+const EMAIL_META_EXT string = ".json"
+
+const (
+	// EMAIL_STATE_QUEUED is the EmailMeta.State of the emails waiting to be sent.
+	EMAIL_STATE_QUEUED int = iota
+	// EMAIL_STATE_SENDING is the EmailMeta.State of the emails being sent.
+	EMAIL_STATE_SENDING
+	// EMAIL_STATE_DEAD is the EmailMeta.State of the emails that could not be sent and were given up on.
+	EMAIL_STATE_DEAD
+)
+
+const (
+	// _EMAIL_QUEUE_DEF_MAX_ATTEMPTS is the default EmailQueueOptions.Max_attempts.
+	_EMAIL_QUEUE_DEF_MAX_ATTEMPTS int = 8
+	// _EMAIL_QUEUE_DEF_BASE_BACKOFF is the default EmailQueueOptions.Base_backoff.
+	_EMAIL_QUEUE_DEF_BASE_BACKOFF time.Duration = 1 * time.Minute
+	// _EMAIL_QUEUE_DEF_MAX_BACKOFF is the default EmailQueueOptions.Max_backoff.
+	_EMAIL_QUEUE_DEF_MAX_BACKOFF time.Duration = 6 * time.Hour
+	// _EMAIL_QUEUE_DEF_MAX_AGE is the default EmailQueueOptions.Max_age.
+	_EMAIL_QUEUE_DEF_MAX_AGE time.Duration = 7 * 24 * time.Hour
+	// _EMAIL_QUEUE_LOCK_TIMEOUT is the maximum time to wait for the lock of the queue, so that a stuck holder doesn't
+	// hang everyone sending emails.
+	_EMAIL_QUEUE_LOCK_TIMEOUT time.Duration = 1 * time.Minute
+	// _EMAIL_QUEUE_CLAIM_TIMEOUT is the time after which a claimed email is considered abandoned (like if the
+	// processor crashed while sending it) and is queued again.
+	_EMAIL_QUEUE_CLAIM_TIMEOUT time.Duration = 1 * time.Hour
+)
+
+// EmailMeta is the delivery state of a queued email, kept in a metadata file next to it.
+type EmailMeta struct {
+	// Recipients is the list of the envelope recipients of the email.
+	Recipients []string
+	// State is the state of the email - one of the EMAIL_STATE_ constants.
+	State int
+	// Queued is when the email was queued.
+	Queued time.Time
+	// Attempts is the number of times the sending failed.
+	Attempts int
+	// Next_attempt is when the email can be sent again, or the zero time for right away.
+	Next_attempt time.Time
+	// Claimed is when the email was claimed to be sent, if it's in the EMAIL_STATE_SENDING state.
+	Claimed time.Time
+	// Errors is the list of the errors of the failed attempts, in order.
+	Errors []string
+	// Failed_recipients is the map of the recipients refused for good by the server to the errors, which are no longer
+	// in the Recipients (unless all were refused).
+	Failed_recipients map[string]string
+}
+
+// EmailQueueOptions is the options for ProcessEmailQueueEMAIL().
+type EmailQueueOptions struct {
+	// Max_attempts is the number of failed attempts after which an email goes to the dead letter folder, or 0 for the
+	// default (8).
+	Max_attempts int
+	// Base_backoff is the time to wait after the first failed attempt, doubled on each of the next ones, or 0 for the
+	// default (1 minute).
+	Base_backoff time.Duration
+	// Max_backoff is the maximum time to wait between attempts, or 0 for the default (6 hours).
+	Max_backoff time.Duration
+	// Max_age is the time after being queued after which an email that still wasn't sent goes to the dead letter
+	// folder, or 0 for the default (7 days).
+	Max_age time.Duration
+	// Send is the function that sends the emails, or nil for SendEmailEMAIL().
+	Send func(message_eml string, recipients []string) error
+}
+
+// EmailQueueReport is the result of ProcessEmailQueueEMAIL().
+type EmailQueueReport struct {
+	// Sent is the number of emails sent, including the ones sent only to some of the recipients (which are also
+	// counted in Retrying or Dead for the others).
+	Sent int
+	// Retrying is the number of emails that failed and will be tried again later.
+	Retrying int
+	// Dead is the number of emails moved to the dead letter folder.
+	Dead int
+}
+
+/*
+ProcessEmailQueueEMAIL sends the emails of the queue that are due, retrying the failed ones later with exponential
+backoff.
+
+Each email is claimed by moving it to the SENDING_REL_FOLDER folder while the queue is locked, so it's never sent twice
+at the same time. The emails that fail are queued again with the error and the time of the next attempt in their
+metadata files, unless they failed EmailQueueOptions.Max_attempts times, are older than EmailQueueOptions.Max_age or
+were refused by the server for good, in which case they're moved to the DEAD_LETTER_REL_FOLDER folder. The emails
+claimed but abandoned (like if the program crashed while sending them) are queued again.
+
+If the server refuses only some recipients, the email is sent to the others and only the ones refused temporarily are
+tried again. The ones refused for good are kept in EmailMeta.Failed_recipients - and if no others are left, the email
+is moved to the dead letter folder with them as the recipients.
+
+***DO NOT USE OUTSIDE THE EMAIL SENDER MODULE***
+
+-----------------------------------------------------------
+
+– Params:
+  - options – the options for the processing or nil for the default ones
+
+– Returns:
+  - the report of what happened to the emails processed
+  - nil if the queue was processed, an error otherwise (the errors of the emails are in their metadata files, not here)
+*/
+func ProcessEmailQueueEMAIL(options *EmailQueueOptions) (EmailQueueReport, error) {
+	options = getEmailQueueOptionsEMAIL(options)
+
+	var report EmailQueueReport
+	var emails_dir GPath = getUserDataDirMODULES(NUM_MOD_EmailSender)
+	var to_send_dir GPath = emails_dir.Add2(true, TO_SEND_REL_FOLDER)
+
+	var claimed []GPath = nil
+	var err error = to_send_dir.WithLock(LOCK_EXCLUSIVE, _EMAIL_QUEUE_LOCK_TIMEOUT, func() error {
+		var err error
+		claimed, err = claimEmailsEMAIL(emails_dir, options, &report)
+
+		return err
+	})
+	if nil != err {
+		return report, err
+	}
+
+	var dead_letter_dir GPath = emails_dir.Add2(true, DEAD_LETTER_REL_FOLDER)
+	for _, eml_path := range claimed {
+		emailMeta, err := readEmailMetaEMAIL(eml_path)
+		if nil != err {
+			// Corrupted after being claimed, so it's not to leave the others claimed.
+			emailMeta.Errors = append(emailMeta.Errors, time.Now().Format(time.RFC3339) + " - " + err.Error())
+			if err = moveEmailEMAIL(eml_path, dead_letter_dir, emailMeta, EMAIL_STATE_DEAD); nil != err {
+				return report, err
+			}
+			report.Dead++
+
+			continue
+		}
+
+		var p_message_eml *string = eml_path.ReadTextFile()
+		if nil == p_message_eml {
+			err = errors.New("the email could not be read")
+		} else {
+			err = options.Send(*p_message_eml, emailMeta.Recipients)
+		}
+		if nil == err {
+			if err = removeEmailEMAIL(eml_path); nil != err {
+				return report, err
+			}
+			report.Sent++
+
+			continue
+		}
+
+		emailMeta.Attempts++
+		emailMeta.Errors = append(emailMeta.Errors, time.Now().Format(time.RFC3339) + " - " + err.Error())
+		var refused bool = isEmailRefusedEMAIL(err)
+		var recipientsError *RecipientsError
+		if errors.As(err, &recipientsError) {
+			if recipientsError.Sent {
+				report.Sent++
+			}
+			refused = updateRecipientsEMAIL(&emailMeta, recipientsError)
+		}
+		if emailMeta.Attempts >= options.Max_attempts || refused {
+			err = moveEmailEMAIL(eml_path, dead_letter_dir, emailMeta, EMAIL_STATE_DEAD)
+			report.Dead++
+		} else {
+			emailMeta.Next_attempt = time.Now().Add(getEmailBackoffEMAIL(emailMeta.Attempts, options))
+			err = to_send_dir.WithLock(LOCK_EXCLUSIVE, _EMAIL_QUEUE_LOCK_TIMEOUT, func() error {
+				return moveEmailEMAIL(eml_path, to_send_dir, emailMeta, EMAIL_STATE_QUEUED)
+			})
+			report.Retrying++
+		}
+		if nil != err {
+			return report, err
+		}
+	}
+
+	return report, nil
+}
+
+/*
+updateRecipientsEMAIL updates the recipients of a queued email after the server refused some of them, leaving only the
+ones to try again and moving the ones refused for good to EmailMeta.Failed_recipients.
+
+-----------------------------------------------------------
+
+– Params:
+  - emailMeta – the metadata of the email
+  - recipientsError – the error with the refused recipients
+
+– Returns:
+  - true if all the recipients left were refused for good (in which case they're left as the recipients), false if
+    there are recipients to try again
+*/
+func updateRecipientsEMAIL(emailMeta *EmailMeta, recipientsError *RecipientsError) bool {
+	var retry []string = nil
+	var failed []string = nil
+	for _, recipient := range emailMeta.Recipients {
+		smtpError, ok := recipientsError.Refused[recipient]
+		if !ok {
+			// Sent to it.
+			continue
+		}
+
+		if smtpError.IsPermanent() {
+			if nil == emailMeta.Failed_recipients {
+				emailMeta.Failed_recipients = make(map[string]string)
+			}
+			emailMeta.Failed_recipients[recipient] = smtpError.Error()
+			failed = append(failed, recipient)
+		} else {
+			retry = append(retry, recipient)
+		}
+	}
+
+	if 0 == len(retry) {
+		emailMeta.Recipients = failed
+
+		return true
+	}
+	emailMeta.Recipients = retry
+
+	return false
+}
+
+/*
+claimEmailsEMAIL claims the emails of the queue that are due, moving them to the SENDING_REL_FOLDER folder. The queue
+must be locked.
+
+It also queues again the abandoned claimed emails and moves the expired ones and the ones with corrupted metadata to the
+DEAD_LETTER_REL_FOLDER folder.
+
+-----------------------------------------------------------
+
+– Params:
+  - emails_dir – the directory of the Email Sender with the queue folders
+  - options – the options for the processing
+  - report – the report to count the expired emails in
+
+– Returns:
+  - the paths of the claimed emails
+  - nil if the emails were claimed, an error otherwise
+*/
+func claimEmailsEMAIL(emails_dir GPath, options *EmailQueueOptions, report *EmailQueueReport) ([]GPath, error) {
+	var to_send_dir GPath = emails_dir.Add2(true, TO_SEND_REL_FOLDER)
+	var sending_dir GPath = emails_dir.Add2(true, SENDING_REL_FOLDER)
+	var now time.Time = time.Now()
+
+	if sending_dir.Exists() {
+		abandoned, err := sending_dir.List(&FileFilter{Name_pattern: "*.eml", Type: FILE_TYPE_FILE})
+		if nil != err {
+			return nil, err
+		}
+		for _, eml_path := range abandoned {
+			emailMeta, err := readEmailMetaEMAIL(eml_path)
+			if nil != err {
+				// Corrupted, so it's not to block the queue.
+				emailMeta.Errors = append(emailMeta.Errors, now.Format(time.RFC3339) + " - " + err.Error())
+				err = moveEmailEMAIL(eml_path, emails_dir.Add2(true, DEAD_LETTER_REL_FOLDER), emailMeta, EMAIL_STATE_DEAD)
+				if nil != err {
+					return nil, err
+				}
+				report.Dead++
+
+				continue
+			}
+			if now.Sub(emailMeta.Claimed) < _EMAIL_QUEUE_CLAIM_TIMEOUT {
+				// Maybe still being sent by another processor.
+				continue
+			}
+			if err = moveEmailEMAIL(eml_path, to_send_dir, emailMeta, EMAIL_STATE_QUEUED); nil != err {
+				return nil, err
+			}
+		}
+	}
+
+	if !to_send_dir.Exists() {
+		return nil, nil
+	}
+	queued, err := to_send_dir.List(&FileFilter{Name_pattern: "*.eml", Type: FILE_TYPE_FILE})
+	if nil != err {
+		return nil, err
+	}
+
+	var claimed []GPath = nil
+	for _, eml_path := range queued {
+		emailMeta, err := readEmailMetaEMAIL(eml_path)
+		if nil != err || now.Sub(emailMeta.Queued) > options.Max_age {
+			if nil == err {
+				err = errors.New("expired after " + options.Max_age.String() + " without being sent")
+			}
+			emailMeta.Errors = append(emailMeta.Errors, now.Format(time.RFC3339) + " - " + err.Error())
+			err = moveEmailEMAIL(eml_path, emails_dir.Add2(true, DEAD_LETTER_REL_FOLDER), emailMeta, EMAIL_STATE_DEAD)
+			if nil != err {
+				return nil, err
+			}
+			report.Dead++
+
+			continue
+		}
+		if now.Before(emailMeta.Next_attempt) {
+			continue
+		}
+
+		emailMeta.Claimed = now
+		if err = moveEmailEMAIL(eml_path, sending_dir, emailMeta, EMAIL_STATE_SENDING); nil != err {
+			return nil, err
+		}
+		claimed = append(claimed, sending_dir.Add2(false, eml_path.Name()))
+	}
+
+	return claimed, nil
+}
+
+/*
+moveEmailEMAIL moves a queued email and its metadata file to another folder, updating the metadata.
+
+The new metadata file is written first, so if something fails in the middle, the email always has a metadata file
+next to it (the ones left without an email are just overwritten later).
+
+-----------------------------------------------------------
+
+– Params:
+  - eml_path – the path to the email
+  - dst_dir – the folder to move it to
+  - emailMeta – the metadata of the email
+  - state – the new state of the email - one of the EMAIL_STATE_ constants
+
+– Returns:
+  - nil if the email was moved, an error otherwise
+*/
+func moveEmailEMAIL(eml_path GPath, dst_dir GPath, emailMeta EmailMeta, state int) error {
+	var dst_eml_path GPath = dst_dir.Add2(false, eml_path.Name())
+	emailMeta.State = state
+	if err := writeEmailMetaEMAIL(dst_eml_path, emailMeta); nil != err {
+		return err
+	}
+	if _, err := eml_path.MoveTo(dst_eml_path, nil); nil != err {
+		return err
+	}
+
+	var meta_path GPath = getEmailMetaPathEMAIL(eml_path)
+	if meta_path.Exists() {
+		return meta_path.Remove()
+	}
+
+	return nil
+}
+
+/*
+removeEmailEMAIL removes a queued email and its metadata file.
+
+-----------------------------------------------------------
+
+– Params:
+  - eml_path – the path to the email
+
+– Returns:
+  - nil if the email was removed, an error otherwise
+*/
+func removeEmailEMAIL(eml_path GPath) error {
+	if err := eml_path.Remove(); nil != err {
+		return err
+	}
+
+	var meta_path GPath = getEmailMetaPathEMAIL(eml_path)
+	if meta_path.Exists() {
+		return meta_path.Remove()
+	}
+
+	return nil
+}
+
+/*
+readEmailMetaEMAIL reads the metadata file of a queued email.
+
+The emails queued before the metadata files existed have the recipients in the file name, after RAND_STR_LEN random
+characters and separated by commas, and so their metadata is created from it.
+
+-----------------------------------------------------------
+
+– Params:
+  - eml_path – the path to the email
+
+– Returns:
+  - the metadata of the email
+  - nil if the metadata was read, an error otherwise (like if the file is corrupted)
+*/
+func readEmailMetaEMAIL(eml_path GPath) (EmailMeta, error) {
+	var emailMeta EmailMeta
+	var meta_path GPath = getEmailMetaPathEMAIL(eml_path)
+	if !meta_path.Exists() {
+		var name string = strings.TrimSuffix(eml_path.Name(), ".eml")
+		if len(name) <= RAND_STR_LEN || !strings.Contains(name, "@") {
+			return emailMeta, errors.New("the email \"" + eml_path.Name() + "\" has no metadata file")
+		}
+
+		return EmailMeta{
+			Recipients: splitRecipientsEMAIL(name[RAND_STR_LEN:]),
+			State:      EMAIL_STATE_QUEUED,
+			Queued:     time.Now(),
+		}, nil
+	}
+
+	if !FromJsonGENERAL(meta_path.ReadFile(), &emailMeta) {
+		return emailMeta, errors.New("the metadata file of the email \"" + eml_path.Name() + "\" is corrupted")
+	}
+
+	return emailMeta, nil
+}
+
+/*
+writeEmailMetaEMAIL writes the metadata file of a queued email, atomically.
+
+-----------------------------------------------------------
+
+– Params:
+  - eml_path – the path to the email
+  - emailMeta – the metadata of the email
+
+– Returns:
+  - nil if the metadata was written, an error otherwise
+*/
+func writeEmailMetaEMAIL(eml_path GPath, emailMeta EmailMeta) error {
+	var p_json *string = ToJsonGENERAL(emailMeta)
+	if nil == p_json {
+		return errors.New("the metadata of the email could not be converted to JSON")
+	}
+
+	return getEmailMetaPathEMAIL(eml_path).WriteFileAtomic([]byte(*p_json), nil)
+}
+
+/*
+getEmailMetaPathEMAIL gets the path to the metadata file of a queued email.
+
+-----------------------------------------------------------
+
+– Params:
+  - eml_path – the path to the email
+
+– Returns:
+  - the path to the metadata file
+*/
+func getEmailMetaPathEMAIL(eml_path GPath) GPath {
+	return eml_path.Dir().Add2(false, eml_path.Name() + EMAIL_META_EXT)
+}
+
+/*
+getEmailBackoffEMAIL gets the time to wait before the next attempt to send an email.
+
+-----------------------------------------------------------
+
+– Params:
+  - attempts – the number of failed attempts so far
+  - options – the options for the processing
+
+– Returns:
+  - EmailQueueOptions.Base_backoff doubled for each attempt after the first, up to EmailQueueOptions.Max_backoff
+*/
+func getEmailBackoffEMAIL(attempts int, options *EmailQueueOptions) time.Duration {
+	var backoff time.Duration = options.Base_backoff
+	for i := 1; i < attempts && backoff < options.Max_backoff; i++ {
+		backoff *= 2
+	}
+	if backoff > options.Max_backoff {
+		backoff = options.Max_backoff
+	}
+
+	return backoff
+}
+
+/*
+isEmailRefusedEMAIL checks if an error of the sending of an email means it will never be accepted, so there's no point
+in trying again.
+
+-----------------------------------------------------------
+
+– Params:
+  - err – the error
+
+– Returns:
+  - true if the server refused the email or its recipients for good, false otherwise
+*/
+func isEmailRefusedEMAIL(err error) bool {
+	var smtpError *SmtpError
+
+	return errors.As(err, &smtpError) && smtpError.IsPermanent() && !isSmtpFailoverErrorEMAIL(err)
+}
+
+/*
+getEmailQueueOptionsEMAIL gets the options for ProcessEmailQueueEMAIL() with the defaults filled in.
+
+-----------------------------------------------------------
+
+– Params:
+  - options – the options given or nil for the default ones
+
+– Returns:
+  - a copy of the options with the defaults filled in
+*/
+func getEmailQueueOptionsEMAIL(options *EmailQueueOptions) *EmailQueueOptions {
+	var ret EmailQueueOptions
+	if nil != options {
+		ret = *options
+	}
+	if ret.Max_attempts <= 0 {
+		ret.Max_attempts = _EMAIL_QUEUE_DEF_MAX_ATTEMPTS
+	}
+	if ret.Base_backoff <= 0 {
+		ret.Base_backoff = _EMAIL_QUEUE_DEF_BASE_BACKOFF
+	}
+	if ret.Max_backoff <= 0 {
+		ret.Max_backoff = _EMAIL_QUEUE_DEF_MAX_BACKOFF
+	}
+	if ret.Max_age <= 0 {
+		ret.Max_age = _EMAIL_QUEUE_DEF_MAX_AGE
+	}
+	if nil == ret.Send {
+		ret.Send = func(message_eml string, recipients []string) error {
+			return SendEmailEMAIL(message_eml, strings.Join(recipients, ","), false)
+		}
+	}
+
+	return &ret
+}
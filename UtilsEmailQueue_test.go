@@ -0,0 +1,294 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package Utils
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestGetEmailBackoff(t *testing.T) {
+	var options *EmailQueueOptions = getEmailQueueOptionsEMAIL(&EmailQueueOptions{
+		Base_backoff: 1 * time.Minute,
+		Max_backoff:  10 * time.Minute,
+	})
+
+	var tests = []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, 1 * time.Minute},
+		{1, 1 * time.Minute},
+		{2, 2 * time.Minute},
+		{3, 4 * time.Minute},
+		{4, 8 * time.Minute},
+		{5, 10 * time.Minute},
+		{100, 10 * time.Minute},
+	}
+	for _, test := range tests {
+		if got := getEmailBackoffEMAIL(test.attempts, options); test.want != got {
+			t.Errorf("attempts %d: got %v, want %v", test.attempts, got, test.want)
+		}
+	}
+}
+
+func TestProcessEmailQueue(t *testing.T) {
+	var tests = []struct {
+		name       string
+		recipients []string
+		sendErr    error
+		wantReport EmailQueueReport
+		wantQueued []string
+		wantDead   []string
+		wantFailed []string
+	}{
+		{"sent", []string{"a@example.com"}, nil, EmailQueueReport{Sent: 1}, nil, nil, nil},
+		{"temporary error", []string{"a@example.com"}, errors.New("connection refused"),
+			EmailQueueReport{Retrying: 1}, []string{"a@example.com"}, nil, nil},
+		{"message refused", []string{"a@example.com"}, &SmtpError{Command: "DATA", Code: 554},
+			EmailQueueReport{Dead: 1}, nil, []string{"a@example.com"}, nil},
+		{"one recipient refused", []string{"a@example.com", "b@example.com"}, &RecipientsError{
+			Refused: map[string]*SmtpError{"b@example.com": {Command: "RCPT TO", Code: 550}},
+			Sent:    true,
+		}, EmailQueueReport{Sent: 1, Dead: 1}, nil, []string{"b@example.com"}, []string{"b@example.com"}},
+		{"one recipient refused and one to try again", []string{"a@example.com", "b@example.com", "c@example.com"},
+			&RecipientsError{
+				Refused: map[string]*SmtpError{
+					"b@example.com": {Command: "RCPT TO", Code: 451},
+					"c@example.com": {Command: "RCPT TO", Code: 550},
+				},
+				Sent: true,
+			}, EmailQueueReport{Sent: 1, Retrying: 1}, []string{"b@example.com"}, nil, []string{"c@example.com"}},
+		{"all recipients refused", []string{"a@example.com", "b@example.com"}, &RecipientsError{
+			Refused: map[string]*SmtpError{
+				"a@example.com": {Command: "RCPT TO", Code: 550},
+				"b@example.com": {Command: "RCPT TO", Code: 553},
+			},
+		}, EmailQueueReport{Dead: 1}, nil, []string{"a@example.com", "b@example.com"},
+			[]string{"a@example.com", "b@example.com"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var emails_dir GPath = setTestVisorDir(t)
+			if err := queueEmlEMAIL("Subject: test\n\nbody\n", test.recipients); nil != err {
+				t.Fatal(err)
+			}
+
+			var sent_to [][]string = nil
+			report, err := ProcessEmailQueueEMAIL(&EmailQueueOptions{
+				Send: func(message_eml string, recipients []string) error {
+					sent_to = append(sent_to, recipients)
+
+					return test.sendErr
+				},
+			})
+			if nil != err {
+				t.Fatal(err)
+			}
+			if test.wantReport != report {
+				t.Errorf("report %+v, want %+v", report, test.wantReport)
+			}
+			if !reflect.DeepEqual(sent_to, [][]string{test.recipients}) {
+				t.Errorf("sent to %v, want %v", sent_to, test.recipients)
+			}
+
+			if emails := listTestEmails(t, emails_dir, SENDING_REL_FOLDER); 0 != len(emails) {
+				t.Errorf("%d emails left claimed", len(emails))
+			}
+			checkTestEmail(t, emails_dir, TO_SEND_REL_FOLDER, EMAIL_STATE_QUEUED, test.wantQueued, test.wantFailed)
+			checkTestEmail(t, emails_dir, DEAD_LETTER_REL_FOLDER, EMAIL_STATE_DEAD, test.wantDead, test.wantFailed)
+
+			// The ones to try again are not due yet.
+			sent_to = nil
+			if _, err = ProcessEmailQueueEMAIL(&EmailQueueOptions{
+				Send: func(message_eml string, recipients []string) error {
+					sent_to = append(sent_to, recipients)
+
+					return nil
+				},
+			}); nil != err {
+				t.Fatal(err)
+			}
+			if 0 != len(sent_to) {
+				t.Errorf("sent again to %v before the backoff", sent_to)
+			}
+		})
+	}
+}
+
+func TestProcessEmailQueueCorruptedClaimed(t *testing.T) {
+	var emails_dir GPath = setTestVisorDir(t)
+	for i := 0; i < 3; i++ {
+		if err := queueEmlEMAIL("Subject: test\n\nbody\n", []string{"a@example.com"}); nil != err {
+			t.Fatal(err)
+		}
+	}
+
+	// The first one to be sent corrupts the metadata of the second one, already claimed.
+	var sent int = 0
+	report, err := ProcessEmailQueueEMAIL(&EmailQueueOptions{
+		Send: func(message_eml string, recipients []string) error {
+			if 0 == sent {
+				var emails []GPath = listTestEmails(t, emails_dir, SENDING_REL_FOLDER)
+				if err := getEmailMetaPathEMAIL(emails[1]).WriteTextFile("{"); nil != err {
+					t.Fatal(err)
+				}
+			}
+			sent++
+
+			return nil
+		},
+	})
+	if nil != err {
+		t.Fatal(err)
+	}
+	if want := (EmailQueueReport{Sent: 2, Dead: 1}); want != report {
+		t.Errorf("report %+v, want %+v", report, want)
+	}
+	if emails := listTestEmails(t, emails_dir, SENDING_REL_FOLDER); 0 != len(emails) {
+		t.Errorf("%d emails left claimed", len(emails))
+	}
+	if emails := listTestEmails(t, emails_dir, DEAD_LETTER_REL_FOLDER); 1 != len(emails) {
+		t.Errorf("%d emails in the dead letter folder, want 1", len(emails))
+	}
+}
+
+func TestProcessEmailQueueAbandoned(t *testing.T) {
+	var emails_dir GPath = setTestVisorDir(t)
+	var sending_dir GPath = emails_dir.Add2(true, SENDING_REL_FOLDER)
+	var tests = []struct {
+		name     string
+		claimed  time.Time
+		wantSent bool
+	}{
+		{"abandoned", time.Now().Add(-2 * _EMAIL_QUEUE_CLAIM_TIMEOUT), true},
+		{"still being sent", time.Now(), false},
+	}
+	for _, test := range tests {
+		var eml_path GPath = sending_dir.Add2(false, test.name + ".eml")
+		if err := writeEmailMetaEMAIL(eml_path, EmailMeta{
+			Recipients: []string{test.name + "@example.com"},
+			State:      EMAIL_STATE_SENDING,
+			Queued:     time.Now(),
+			Claimed:    test.claimed,
+		}); nil != err {
+			t.Fatal(err)
+		}
+		if err := eml_path.WriteTextFile("Subject: test\n\nbody\n"); nil != err {
+			t.Fatal(err)
+		}
+	}
+
+	var sent_to []string = nil
+	report, err := ProcessEmailQueueEMAIL(&EmailQueueOptions{
+		Send: func(message_eml string, recipients []string) error {
+			sent_to = append(sent_to, recipients...)
+
+			return nil
+		},
+	})
+	if nil != err {
+		t.Fatal(err)
+	}
+	if want := (EmailQueueReport{Sent: 1}); want != report {
+		t.Errorf("report %+v, want %+v", report, want)
+	}
+	for _, test := range tests {
+		if got := ContainsSLICES(sent_to, test.name + "@example.com"); test.wantSent != got {
+			t.Errorf("%s: sent %v, want %v", test.name, got, test.wantSent)
+		}
+	}
+}
+
+// setTestVisorDir sets the VISOR directory to a temporary one for the test and returns the directory of the Email
+// Sender with the queue folders.
+func setTestVisorDir(t *testing.T) GPath {
+	t.Helper()
+
+	var old_visor_dir GPath = PersonalConsts_GL._VISOR_DIR
+	PersonalConsts_GL._VISOR_DIR = PathFILESDIRS(true, "", t.TempDir())
+	t.Cleanup(func() {
+		PersonalConsts_GL._VISOR_DIR = old_visor_dir
+	})
+
+	return getUserDataDirMODULES(NUM_MOD_EmailSender)
+}
+
+// listTestEmails lists the emails of a queue folder.
+func listTestEmails(t *testing.T, emails_dir GPath, rel_folder string) []GPath {
+	t.Helper()
+
+	var dir GPath = emails_dir.Add2(true, rel_folder)
+	if !dir.Exists() {
+		return nil
+	}
+	emails, err := dir.List(&FileFilter{Name_pattern: "*.eml", Type: FILE_TYPE_FILE})
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	return emails
+}
+
+// checkTestEmail checks that a queue folder has no emails if want_recipients is nil, else one email with the given
+// state, recipients and failed recipients.
+func checkTestEmail(t *testing.T, emails_dir GPath, rel_folder string, state int, want_recipients []string,
+			want_failed []string) {
+	t.Helper()
+
+	var emails []GPath = listTestEmails(t, emails_dir, rel_folder)
+	if nil == want_recipients {
+		if 0 != len(emails) {
+			t.Errorf("%s: %d emails, want none", rel_folder, len(emails))
+		}
+
+		return
+	}
+	if 1 != len(emails) {
+		t.Fatalf("%s: %d emails, want 1", rel_folder, len(emails))
+	}
+
+	emailMeta, err := readEmailMetaEMAIL(emails[0])
+	if nil != err {
+		t.Fatal(err)
+	}
+	if state != emailMeta.State {
+		t.Errorf("%s: state %d, want %d", rel_folder, emailMeta.State, state)
+	}
+	if !reflect.DeepEqual(emailMeta.Recipients, want_recipients) {
+		t.Errorf("%s: recipients %v, want %v", rel_folder, emailMeta.Recipients, want_recipients)
+	}
+	if 1 != emailMeta.Attempts || 1 != len(emailMeta.Errors) {
+		t.Errorf("%s: %d attempts and %d errors, want 1", rel_folder, emailMeta.Attempts, len(emailMeta.Errors))
+	}
+	var failed []string = nil
+	for recipient := range emailMeta.Failed_recipients {
+		failed = append(failed, recipient)
+	}
+	sort.Strings(failed)
+	if !reflect.DeepEqual(failed, want_failed) {
+		t.Errorf("%s: failed recipients %v, want %v", rel_folder, failed, want_failed)
+	}
+}
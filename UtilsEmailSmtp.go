@@ -0,0 +1,567 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package Utils
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// SMTP_TLS_STARTTLS is the SmtpServer.Tls_mode that connects in plain text and upgrades with STARTTLS (usually on
+	// port 587). The upgrade is required.
+	SMTP_TLS_STARTTLS int = iota
+	// SMTP_TLS_IMPLICIT is the SmtpServer.Tls_mode that connects with TLS right away (usually on port 465).
+	SMTP_TLS_IMPLICIT
+	// SMTP_TLS_NONE is the SmtpServer.Tls_mode without any encryption - only for local servers (authentication is
+	// refused on it except for localhost).
+	SMTP_TLS_NONE
+)
+
+const (
+	// SMTP_AUTH_AUTO is the SmtpServer.Auth that chooses PLAIN or LOGIN from what the server supports.
+	SMTP_AUTH_AUTO int = iota
+	// SMTP_AUTH_PLAIN is the SmtpServer.Auth of the PLAIN mechanism.
+	SMTP_AUTH_PLAIN
+	// SMTP_AUTH_LOGIN is the SmtpServer.Auth of the LOGIN mechanism.
+	SMTP_AUTH_LOGIN
+	// SMTP_AUTH_NONE is the SmtpServer.Auth that doesn't authenticate.
+	SMTP_AUTH_NONE
+)
+
+const (
+	// _SMTP_DEF_CONNECT_TIMEOUT is the default SmtpServer.Connect_timeout.
+	_SMTP_DEF_CONNECT_TIMEOUT time.Duration = 10 * time.Second
+	// _SMTP_DEF_TIMEOUT is the default SmtpServer.Timeout.
+	_SMTP_DEF_TIMEOUT time.Duration = 1 * time.Minute
+)
+
+// SmtpServer is the information to connect to an SMTP server.
+type SmtpServer struct {
+	// Host is the host name of the server.
+	Host string
+	// Port is the port of the server.
+	Port int
+	// Tls_mode is how the connection is encrypted - one of the SMTP_TLS_ constants.
+	Tls_mode int
+	// Auth is the authentication mechanism - one of the SMTP_AUTH_ constants.
+	Auth int
+	// Username is the username to authenticate with.
+	Username string
+	// Password is the password to authenticate with.
+	Password string
+	// Connect_timeout is the maximum time to wait for the connection, or 0 for the default (10 seconds).
+	Connect_timeout time.Duration
+	// Timeout is the maximum time to wait for each reply of the server, or 0 for the default (1 minute).
+	Timeout time.Duration
+	// Tls_config is the TLS configuration to use instead of the default one (like to trust the certificate of a local
+	// test server), or nil.
+	Tls_config *tls.Config `json:"-"`
+}
+
+/*
+SmtpAccount is an account to send the emails through, read from the SMTP_ACCOUNTS object of the
+PersonalConsts_EOG.json file.
+
+The SmtpServer fields are at the same level of the From_addr and Display_name ones in the JSON object, with Tls_mode
+and Auth being the values of the SMTP_TLS_ and SMTP_AUTH_ constants.
+*/
+type SmtpAccount struct {
+	SmtpServer
+	// From_addr is the address the emails are sent from, or empty to use the Username.
+	From_addr string
+	// Display_name is the name shown with the From_addr when the email doesn't have a sender name, or empty for none.
+	Display_name string
+}
+
+// SmtpError is an error reply of an SMTP server.
+type SmtpError struct {
+	// Command is the command that got the error reply.
+	Command string
+	// Code is the reply code (like 550).
+	Code int
+	// Message is the text of the reply.
+	Message string
+}
+
+// RecipientsError is the error of a message with recipients refused by the SMTP server. The message is still sent to
+// the accepted recipients, if there are any.
+type RecipientsError struct {
+	// Refused is the map of the refused recipients to the error replies of the server.
+	Refused map[string]*SmtpError
+	// Sent is true if the message was sent to the other recipients, false if all were refused.
+	Sent bool
+}
+
+// _LoginAuth is the smtp.Auth of the LOGIN mechanism, which net/smtp doesn't have.
+type _LoginAuth struct {
+	// username is the username to authenticate with.
+	username string
+	// password is the password to authenticate with.
+	password string
+	// host is the host name of the server, which must match the one connected to.
+	host string
+}
+
+/*
+SendMailSmtpEMAIL sends a message through an SMTP server, without any external programs.
+
+-----------------------------------------------------------
+
+– Params:
+  - smtpServer – the server to send through
+  - from – the envelope sender address
+  - recipients – the envelope recipient addresses
+  - message – the complete message in EML format (the line breaks are converted to "\r\n")
+
+– Returns:
+  - nil if the message was accepted by the server, an error otherwise (an *SmtpError if the server refused something,
+    or a *RecipientsError if it refused recipients, with the message sent to the others)
+*/
+func SendMailSmtpEMAIL(smtpServer SmtpServer, from string, recipients []string, message []byte) error {
+	if 0 == len(recipients) {
+		return errors.New("no recipients")
+	}
+	if 0 == smtpServer.Connect_timeout {
+		smtpServer.Connect_timeout = _SMTP_DEF_CONNECT_TIMEOUT
+	}
+	if 0 == smtpServer.Timeout {
+		smtpServer.Timeout = _SMTP_DEF_TIMEOUT
+	}
+
+	var tls_config *tls.Config = &tls.Config{ServerName: smtpServer.Host}
+	if nil != smtpServer.Tls_config {
+		tls_config = smtpServer.Tls_config.Clone()
+		if "" == tls_config.ServerName {
+			tls_config.ServerName = smtpServer.Host
+		}
+	}
+
+	var address string = net.JoinHostPort(smtpServer.Host, strconv.Itoa(smtpServer.Port))
+	var dialer *net.Dialer = &net.Dialer{Timeout: smtpServer.Connect_timeout}
+	var conn net.Conn
+	var err error
+	if SMTP_TLS_IMPLICIT == smtpServer.Tls_mode {
+		conn, err = tls.DialWithDialer(dialer, "tcp", address, tls_config)
+	} else {
+		conn, err = dialer.Dial("tcp", address)
+	}
+	if nil != err {
+		return err
+	}
+
+	// Each step gets the whole timeout, so that big messages don't time out just for being big.
+	var step = func(command string, f func() error) error {
+		_ = conn.SetDeadline(time.Now().Add(smtpServer.Timeout))
+
+		return toSmtpErrorEMAIL(command, f())
+	}
+
+	var client *smtp.Client
+	if err = step("CONNECT", func() error {
+		client, err = smtp.NewClient(conn, smtpServer.Host)
+
+		return err
+	}); nil != err {
+		_ = conn.Close()
+
+		return err
+	}
+	defer client.Close()
+
+	if err = step("EHLO", func() error {
+		return client.Hello(getEhloNameEMAIL())
+	}); nil != err {
+		return err
+	}
+
+	if SMTP_TLS_STARTTLS == smtpServer.Tls_mode {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.New("the server does not support STARTTLS")
+		}
+		if err = step("STARTTLS", func() error {
+			return client.StartTLS(tls_config)
+		}); nil != err {
+			return err
+		}
+	}
+
+	if auth, err := getSmtpAuthEMAIL(client, smtpServer); nil != err {
+		return err
+	} else if nil != auth {
+		if err = step("AUTH", func() error {
+			return client.Auth(auth)
+		}); nil != err {
+			return err
+		}
+	}
+
+	if err = step("MAIL FROM", func() error {
+		return client.Mail(from)
+	}); nil != err {
+		return err
+	}
+	// A refused recipient doesn't stop the message from going to the others.
+	var recipientsError *RecipientsError = nil
+	var accepted int = 0
+	for _, recipient := range recipients {
+		if err = step("RCPT TO", func() error {
+			return client.Rcpt(recipient)
+		}); nil != err {
+			var smtpError *SmtpError
+			if !errors.As(err, &smtpError) {
+				return err
+			}
+			if nil == recipientsError {
+				recipientsError = &RecipientsError{Refused: make(map[string]*SmtpError)}
+			}
+			recipientsError.Refused[recipient] = smtpError
+
+			continue
+		}
+		accepted++
+	}
+	if 0 == accepted {
+		return recipientsError
+	}
+
+	if err = step("DATA", func() error {
+		writer, err := client.Data()
+		if nil != err {
+			return err
+		}
+		if _, err = writer.Write(message); nil != err {
+			_ = writer.Close()
+
+			return err
+		}
+
+		// The reply to the message comes on the close.
+		return writer.Close()
+	}); nil != err {
+		return err
+	}
+
+	if err = step("QUIT", func() error {
+		return client.Quit()
+	}); nil != err {
+		return err
+	}
+
+	if nil != recipientsError {
+		recipientsError.Sent = true
+
+		return recipientsError
+	}
+
+	return nil
+}
+
+/*
+Error implements the error interface.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the description of the error
+*/
+func (smtpError *SmtpError) Error() string {
+	return "SMTP server replied to " + smtpError.Command + " with " + strconv.Itoa(smtpError.Code) + ": " +
+		smtpError.Message
+}
+
+/*
+IsPermanent checks if the error is permanent (a 5xx reply), in which case trying again won't help.
+
+-----------------------------------------------------------
+
+– Returns:
+  - true if the error is permanent, false if it's temporary
+*/
+func (smtpError *SmtpError) IsPermanent() bool {
+	return smtpError.Code >= 500
+}
+
+/*
+Error implements the error interface.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the description of the error
+*/
+func (recipientsError *RecipientsError) Error() string {
+	var errs_str []string = nil
+	for _, recipient := range recipientsError.getRecipients() {
+		var smtpError *SmtpError = recipientsError.Refused[recipient]
+		errs_str = append(errs_str, recipient + ": " + strconv.Itoa(smtpError.Code) + " " + smtpError.Message)
+	}
+
+	var error_str string = "SMTP server refused the recipients (" + strings.Join(errs_str, "; ") + ")"
+	if recipientsError.Sent {
+		error_str += ", the message was sent to the others"
+	}
+
+	return error_str
+}
+
+/*
+Unwrap gets the error replies of the refused recipients, so that errors.As() finds them.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the error replies, in the order of the recipients
+*/
+func (recipientsError *RecipientsError) Unwrap() []error {
+	var errs []error = nil
+	for _, recipient := range recipientsError.getRecipients() {
+		errs = append(errs, recipientsError.Refused[recipient])
+	}
+
+	return errs
+}
+
+/*
+IsPermanent checks if all the recipients were refused for good, in which case trying again won't help.
+
+-----------------------------------------------------------
+
+– Returns:
+  - true if all the error replies are permanent, false if any of them is temporary
+*/
+func (recipientsError *RecipientsError) IsPermanent() bool {
+	for _, smtpError := range recipientsError.Refused {
+		if !smtpError.IsPermanent() {
+			return false
+		}
+	}
+
+	return true
+}
+
+/*
+getRecipients gets the refused recipients, sorted.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the refused recipients
+*/
+func (recipientsError *RecipientsError) getRecipients() []string {
+	var recipients []string = nil
+	for recipient := range recipientsError.Refused {
+		recipients = append(recipients, recipient)
+	}
+	sort.Strings(recipients)
+
+	return recipients
+}
+
+/*
+GetFromAddr gets the address the emails are sent from with the account.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the From_addr, or the Username if the From_addr is empty
+*/
+func (smtpAccount SmtpAccount) GetFromAddr() string {
+	if "" != smtpAccount.From_addr {
+		return smtpAccount.From_addr
+	}
+
+	return smtpAccount.Username
+}
+
+/*
+isValid checks if the account has everything needed to send emails.
+
+-----------------------------------------------------------
+
+– Returns:
+  - true if the account is valid, false otherwise
+*/
+func (smtpAccount SmtpAccount) isValid() bool {
+	return "" != smtpAccount.Host && smtpAccount.Port > 0 && smtpAccount.Port <= 65535 &&
+		smtpAccount.Tls_mode >= SMTP_TLS_STARTTLS && smtpAccount.Tls_mode <= SMTP_TLS_NONE &&
+		smtpAccount.Auth >= SMTP_AUTH_AUTO && smtpAccount.Auth <= SMTP_AUTH_NONE &&
+		strings.Contains(smtpAccount.GetFromAddr(), "@") && !strings.ContainsAny(smtpAccount.GetFromAddr(), ",\r\n")
+}
+
+/*
+getSmtpAuthEMAIL gets the authentication to use with a server.
+
+-----------------------------------------------------------
+
+– Params:
+  - client – the client connected to the server
+  - smtpServer – the information about the server
+
+– Returns:
+  - the authentication to use, or nil for none
+  - nil if the authentication was chosen successfully, an error otherwise (like if the server doesn't support it)
+*/
+func getSmtpAuthEMAIL(client *smtp.Client, smtpServer SmtpServer) (smtp.Auth, error) {
+	if SMTP_AUTH_NONE == smtpServer.Auth || "" == smtpServer.Username {
+		return nil, nil
+	}
+
+	ok, mechanisms_str := client.Extension("AUTH")
+	if !ok {
+		return nil, errors.New("the server does not support authentication")
+	}
+	var mechanisms []string = strings.Fields(strings.ToUpper(mechanisms_str))
+
+	var auth int = smtpServer.Auth
+	if SMTP_AUTH_AUTO == auth {
+		if ContainsSLICES(mechanisms, "PLAIN") {
+			auth = SMTP_AUTH_PLAIN
+		} else if ContainsSLICES(mechanisms, "LOGIN") {
+			auth = SMTP_AUTH_LOGIN
+		} else {
+			return nil, errors.New("the server does not support PLAIN or LOGIN authentication: " + mechanisms_str)
+		}
+	}
+
+	switch auth {
+		case SMTP_AUTH_PLAIN:
+			return smtp.PlainAuth("", smtpServer.Username, smtpServer.Password, smtpServer.Host), nil
+		case SMTP_AUTH_LOGIN:
+			return &_LoginAuth{
+				username: smtpServer.Username,
+				password: smtpServer.Password,
+				host:     smtpServer.Host,
+			}, nil
+	}
+
+	return nil, errors.New("invalid SMTP authentication mechanism: " + strconv.Itoa(auth))
+}
+
+/*
+getEhloNameEMAIL gets the name to identify this computer with in the EHLO command.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the host name of the computer, or "localhost" if it can't be got or is not a valid domain name
+*/
+func getEhloNameEMAIL() string {
+	host_name, err := os.Hostname()
+	if nil != err || "" == host_name || len(host_name) > 255 {
+		return "localhost"
+	}
+	for _, c := range host_name {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || '-' == c || '.' == c) {
+			return "localhost"
+		}
+	}
+
+	return host_name
+}
+
+/*
+toSmtpErrorEMAIL converts an error reply of the server into an *SmtpError.
+
+-----------------------------------------------------------
+
+– Params:
+  - command – the command that got the error
+  - err – the error
+
+– Returns:
+  - an *SmtpError if the error is a reply of the server, else the same error
+*/
+func toSmtpErrorEMAIL(command string, err error) error {
+	var textproto_error *textproto.Error
+	if errors.As(err, &textproto_error) {
+		return &SmtpError{
+			Command: command,
+			Code:    textproto_error.Code,
+			Message: textproto_error.Msg,
+		}
+	}
+
+	return err
+}
+
+/*
+Start starts the LOGIN authentication (implements smtp.Auth).
+
+-----------------------------------------------------------
+
+– Params:
+  - server – the information about the server
+
+– Returns:
+  - the name of the mechanism ("LOGIN")
+  - the initial response, always nil (the username is only sent when the server asks for it)
+  - nil if the authentication can go on, an error if the connection is not encrypted (except to localhost) or the
+    host name doesn't match the one of the server
+*/
+func (loginAuth *_LoginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	// Same rules as smtp.PlainAuth: never send the password unencrypted, except to localhost.
+	if !server.TLS && "localhost" != server.Name && "127.0.0.1" != server.Name && "::1" != server.Name {
+		return "", nil, errors.New("unencrypted connection")
+	}
+	if server.Name != loginAuth.host {
+		return "", nil, errors.New("wrong host name")
+	}
+
+	return "LOGIN", nil, nil
+}
+
+/*
+Next answers a challenge of the server during the LOGIN authentication (implements smtp.Auth).
+
+-----------------------------------------------------------
+
+– Params:
+  - from_server – the challenge of the server ("Username:" or "Password:")
+  - more – true if the server expects an answer, false if the authentication is over
+
+– Returns:
+  - the answer to the challenge, or nil if the authentication is over
+  - nil if the challenge is known, an error otherwise
+*/
+func (loginAuth *_LoginAuth) Next(from_server []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+
+	switch strings.ToLower(strings.TrimSpace(string(from_server))) {
+		case "username:":
+			return []byte(loginAuth.username), nil
+		case "password:":
+			return []byte(loginAuth.password), nil
+	}
+
+	return nil, errors.New("unexpected LOGIN challenge from the server: " + string(from_server))
+}
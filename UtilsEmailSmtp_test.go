@@ -0,0 +1,310 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package Utils
+
+import (
+	"errors"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// _StubSmtpSession is what a stub SMTP server received in a session.
+type _StubSmtpSession struct {
+	// commands is the commands received, without their arguments (like "MAIL" or "RCPT").
+	commands []string
+	// recipients is the addresses of the RCPT TO commands.
+	recipients []string
+	// data is the message received with DATA.
+	data string
+}
+
+func TestSendMailSmtp(t *testing.T) {
+	var tests = []struct {
+		name           string
+		replies        map[string]string
+		recipients     []string
+		wantErr        error
+		wantRecipients []string
+	}{
+		{"success", nil, []string{"a@example.com", "b@example.com"}, nil,
+			[]string{"a@example.com", "b@example.com"}},
+		{"MAIL FROM refused", map[string]string{"MAIL": "550 sender refused"}, []string{"a@example.com"},
+			&SmtpError{Command: "MAIL FROM", Code: 550, Message: "sender refused"}, nil},
+		{"RCPT TO temporary error", map[string]string{"RCPT": "451 try later"}, []string{"a@example.com"},
+			&RecipientsError{Refused: map[string]*SmtpError{
+				"a@example.com": {Command: "RCPT TO", Code: 451, Message: "try later"},
+			}}, nil},
+		{"one recipient refused", map[string]string{"RCPT b@example.com": "550 no such user"},
+			[]string{"a@example.com", "b@example.com", "c@example.com"},
+			&RecipientsError{Refused: map[string]*SmtpError{
+				"b@example.com": {Command: "RCPT TO", Code: 550, Message: "no such user"},
+			}, Sent: true}, []string{"a@example.com", "c@example.com"}},
+		{"message refused", map[string]string{".": "554 spam"}, []string{"a@example.com"},
+			&SmtpError{Command: "DATA", Code: 554, Message: "spam"}, []string{"a@example.com"}},
+		{"login refused", map[string]string{"AUTH": "535 bad credentials"}, []string{"a@example.com"},
+			&SmtpError{Command: "AUTH", Code: 535, Message: "bad credentials"}, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			port, sessions := startStubSmtpServer(t, test.replies)
+
+			var err error = SendMailSmtpEMAIL(SmtpServer{
+				Host:     "127.0.0.1",
+				Port:     port,
+				Tls_mode: SMTP_TLS_NONE,
+				Auth:     SMTP_AUTH_PLAIN,
+				Username: "user",
+				Password: "pass",
+				Timeout:  5 * time.Second,
+			}, "from@example.com", test.recipients, []byte("Subject: test\n\nline 1\n.line 2\n"))
+
+			if !reflect.DeepEqual(err, test.wantErr) {
+				t.Fatalf("got error %#v, want %#v", err, test.wantErr)
+			}
+
+			var session _StubSmtpSession = <-sessions
+			if !reflect.DeepEqual(session.recipients, test.wantRecipients) {
+				t.Errorf("recipients %v, want %v", session.recipients, test.wantRecipients)
+			}
+			if 0 != len(test.wantRecipients) && "Subject: test\r\n\r\nline 1\r\n.line 2\r\n" != session.data {
+				t.Errorf("data %q", session.data)
+			}
+		})
+	}
+}
+
+func TestSmtpErrorClassification(t *testing.T) {
+	var tests = []struct {
+		name          string
+		err           error
+		wantPermanent bool
+		wantFailover  bool
+	}{
+		{"connection error", errors.New("connection refused"), false, true},
+		{"temporary reply", &SmtpError{Command: "MAIL FROM", Code: 421}, false, true},
+		{"login refused", &SmtpError{Command: "AUTH", Code: 535}, true, true},
+		{"EHLO refused", &SmtpError{Command: "EHLO", Code: 554}, true, true},
+		{"sender refused", &SmtpError{Command: "MAIL FROM", Code: 550}, true, false},
+		{"recipient refused", &SmtpError{Command: "RCPT TO", Code: 550}, true, false},
+		{"message refused", &SmtpError{Command: "DATA", Code: 554}, true, false},
+		{"all recipients refused temporarily", &RecipientsError{Refused: map[string]*SmtpError{
+			"a@example.com": {Command: "RCPT TO", Code: 451},
+		}}, false, true},
+		{"all recipients refused", &RecipientsError{Refused: map[string]*SmtpError{
+			"a@example.com": {Command: "RCPT TO", Code: 550},
+		}}, true, false},
+		{"some recipients refused temporarily", &RecipientsError{Refused: map[string]*SmtpError{
+			"a@example.com": {Command: "RCPT TO", Code: 451},
+		}, Sent: true}, false, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var smtpError *SmtpError
+			if errors.As(test.err, &smtpError) && smtpError.IsPermanent() != test.wantPermanent {
+				t.Errorf("IsPermanent() = %v, want %v", smtpError.IsPermanent(), test.wantPermanent)
+			}
+			if got := isSmtpFailoverErrorEMAIL(test.err); got != test.wantFailover {
+				t.Errorf("isSmtpFailoverErrorEMAIL() = %v, want %v", got, test.wantFailover)
+			}
+		})
+	}
+}
+
+func TestLoginAuth(t *testing.T) {
+	var loginAuth *_LoginAuth = &_LoginAuth{username: "user", password: "pass", host: "smtp.example.com"}
+
+	var start_tests = []struct {
+		name    string
+		server  smtp.ServerInfo
+		wantErr bool
+	}{
+		{"TLS", smtp.ServerInfo{Name: "smtp.example.com", TLS: true}, false},
+		{"no TLS", smtp.ServerInfo{Name: "smtp.example.com", TLS: false}, true},
+		{"wrong host", smtp.ServerInfo{Name: "other.example.com", TLS: true}, true},
+	}
+	for _, test := range start_tests {
+		t.Run("Start/" + test.name, func(t *testing.T) {
+			mechanism, _, err := loginAuth.Start(&test.server)
+			if (nil != err) != test.wantErr {
+				t.Fatalf("got error %v, want error %v", err, test.wantErr)
+			}
+			if nil == err && "LOGIN" != mechanism {
+				t.Errorf("mechanism %q", mechanism)
+			}
+		})
+	}
+
+	var next_tests = []struct {
+		name        string
+		from_server string
+		more        bool
+		want        string
+		wantErr     bool
+	}{
+		{"username", "Username:", true, "user", false},
+		{"password", "password: ", true, "pass", false},
+		{"unknown", "Token:", true, "", true},
+		{"done", "", false, "", false},
+	}
+	for _, test := range next_tests {
+		t.Run("Next/" + test.name, func(t *testing.T) {
+			answer, err := loginAuth.Next([]byte(test.from_server), test.more)
+			if (nil != err) != test.wantErr {
+				t.Fatalf("got error %v, want error %v", err, test.wantErr)
+			}
+			if test.want != string(answer) {
+				t.Errorf("answer %q, want %q", answer, test.want)
+			}
+		})
+	}
+}
+
+func TestGetEhloName(t *testing.T) {
+	var ehlo_name string = getEhloNameEMAIL()
+	if "" == ehlo_name || strings.ContainsAny(ehlo_name, " \r\n") {
+		t.Errorf("invalid EHLO name %q", ehlo_name)
+	}
+}
+
+/*
+startStubSmtpServer starts an SMTP server for one session on a random port of localhost, which answers all commands
+successfully except the ones given.
+
+-----------------------------------------------------------
+
+– Params:
+  - t – the test
+  - replies – the replies to give instead of the successful ones, by command (like "RCPT", or "." for the end of the
+    message)
+
+– Returns:
+  - the port of the server
+  - the channel that gets what the server received when the session ends
+*/
+func startStubSmtpServer(t *testing.T, replies map[string]string) (int, <-chan _StubSmtpSession) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if nil != err {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+
+	var sessions chan _StubSmtpSession = make(chan _StubSmtpSession, 1)
+	go func() {
+		var session _StubSmtpSession = _StubSmtpSession{}
+		defer func() {
+			sessions <- session
+		}()
+
+		conn, err := listener.Accept()
+		if nil != err {
+			return
+		}
+		defer conn.Close()
+		_ = conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+		var text_conn *textproto.Conn = textproto.NewConn(conn)
+		var reply = func(command string, def string) {
+			if custom, ok := replies[command]; ok {
+				def = custom
+			}
+			_ = text_conn.PrintfLine("%s", def)
+		}
+
+		reply("CONNECT", "220 stub ESMTP")
+		for {
+			line, err := text_conn.ReadLine()
+			if nil != err {
+				return
+			}
+			var command string = strings.ToUpper(strings.Fields(line + " ")[0])
+			session.commands = append(session.commands, command)
+
+			switch command {
+				case "EHLO":
+					reply(command, "250-stub\r\n250-AUTH PLAIN LOGIN\r\n250 8BITMIME")
+				case "AUTH":
+					reply(command, "235 authenticated")
+				case "RCPT":
+					// A reply for a specific recipient has the key "RCPT [address]".
+					var recipient string = strings.Trim(line[strings.Index(line, ":") + 1:], "<> ")
+					var rcpt_reply string = "250 OK"
+					if custom, ok := replies[command]; ok {
+						rcpt_reply = custom
+					}
+					if custom, ok := replies[command + " " + recipient]; ok {
+						rcpt_reply = custom
+					}
+					_ = text_conn.PrintfLine("%s", rcpt_reply)
+					if strings.HasPrefix(rcpt_reply, "250") {
+						session.recipients = append(session.recipients, recipient)
+					}
+				case "DATA":
+					reply(command, "354 go on")
+					lines, err := text_conn.ReadDotLines()
+					if nil != err {
+						return
+					}
+					for _, data_line := range lines {
+						session.data += data_line + "\r\n"
+					}
+					reply(".", "250 queued")
+				case "QUIT":
+					reply(command, "221 bye")
+
+					return
+				default:
+					reply(command, "250 OK")
+			}
+		}
+	}()
+
+	return listener.Addr().(*net.TCPAddr).Port, sessions
+}
+
+func TestRecipientsError(t *testing.T) {
+	var recipientsError *RecipientsError = &RecipientsError{Refused: map[string]*SmtpError{
+		"b@example.com": {Command: "RCPT TO", Code: 550, Message: "no such user"},
+		"a@example.com": {Command: "RCPT TO", Code: 451, Message: "try later"},
+	}, Sent: true}
+
+	var want string = "SMTP server refused the recipients (a@example.com: 451 try later; b@example.com: 550 no such " +
+		"user), the message was sent to the others"
+	if got := recipientsError.Error(); want != got {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if recipientsError.IsPermanent() {
+		t.Error("IsPermanent() = true with a temporary reply")
+	}
+	var smtpError *SmtpError
+	if !errors.As(recipientsError, &smtpError) || 451 != smtpError.Code {
+		t.Errorf("errors.As() got %v, want the reply of the first recipient", smtpError)
+	}
+}
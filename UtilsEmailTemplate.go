@@ -0,0 +1,339 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package Utils
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"html/template"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// _EMAIL_PARTIALS_FOLDER is the folder inside the email models folder with the partial templates the models can
+// include with {{template "[file name]" .}}.
+const _EMAIL_PARTIALS_FOLDER string = "partials"
+
+// model_html_keys_GL is the list of the keys of GetModelFileModEMAIL() whose values are HTML and so are not escaped.
+// The message body and the error report are here because the callers have always been able to use tags (like <br>) in
+// them.
+var model_html_keys_GL []string = []string{
+	MODEL_INFO_MSG_BODY_EMAIL,
+	MODEL_RSS_ENTRY_DESCRIPTION_EMAIL,
+	MODEL_YT_VIDEO_VIDEO_DESCRIPTION_EMAIL,
+	MODEL_DISKS_SMART_DISKS_SMART_HTML_EMAIL,
+	MODEL_DISKS_SMART_ERROR_REPORT_EMAIL,
+}
+
+// model_comment_regex_GL matches the HTML comments of the models, which html/template would strip (like the conditional
+// comments of Outlook).
+var model_comment_regex_GL *regexp.Regexp = regexp.MustCompile(`(?s)<!--.*?-->`)
+
+/*
+RenderModelFileEMAIL renders an email model file as an html/template template.
+
+The values are escaped according to where they are in the HTML (text, attributes, URLs...), except for those of type
+template.HTML. The model can use conditionals, loops and the partial templates of the partials folder of the email
+models folder.
+
+html/template removes the HTML comments, so the ones to keep in the email (like the conditional comments of Outlook)
+must be written with the "comment" function: {{comment "[if mso]>...<![endif]"}} gives <!--[if mso]>...<![endif]-->.
+Its arguments alternate between text written as is and values escaped as HTML (unless of type template.HTML).
+
+-----------------------------------------------------------
+
+– Params:
+  - mod_num – the number of the module sending the email, used to choose the recipients, or -1 if not from a module
+  - file_name – the name of the file
+  - data – the data given to the template (like a struct or a map)
+
+– Returns:
+  - an instance of EmailInfo with the EmailInfo.Sender, EmailInfo.To and EmailInfo.Html filled and ready
+  - nil if the model was rendered successfully, an error otherwise
+*/
+func RenderModelFileEMAIL(mod_num int, file_name string, data any) (EmailInfo, error) {
+	var p_model *string = getModelsDirEMAIL().Add2(false, file_name).ReadTextFile()
+	if nil == p_model {
+		return EmailInfo{}, errors.New("the email model \"" + file_name + "\" could not be read")
+	}
+
+	msg_html, err := renderModelEMAIL(file_name, *p_model, data)
+	if nil != err {
+		return EmailInfo{}, err
+	}
+
+	return EmailInfo{
+		Sender: getModelSenderEMAIL(file_name),
+		To:     GetRecipientsEMAIL(mod_num, file_name),
+		Html:   msg_html,
+	}, nil
+}
+
+/*
+renderModelEMAIL renders an email model as an html/template template, with the partial templates available.
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the name of the model
+  - model – the contents of the model
+  - data – the data given to the template
+
+– Returns:
+  - the rendered HTML
+  - nil if the model was rendered successfully, an error otherwise
+*/
+func renderModelEMAIL(name string, model string, data any) (string, error) {
+	tmpl, err := template.New(name).Option("missingkey=zero").Funcs(template.FuncMap{
+		"comment": commentTemplateEMAIL,
+	}).Parse(model)
+	if nil != err {
+		return "", err
+	}
+
+	var partials_dir GPath = getModelsDirEMAIL().Add2(true, _EMAIL_PARTIALS_FOLDER)
+	if partials_dir.Exists() {
+		partials, err := partials_dir.List(&FileFilter{Type: FILE_TYPE_FILE})
+		if nil != err {
+			return "", err
+		}
+		for _, partial := range partials {
+			var p_partial *string = partial.ReadTextFile()
+			if nil == p_partial {
+				return "", errors.New("the partial template \"" + partial.Name() + "\" could not be read")
+			}
+			if _, err = tmpl.New(partial.Name()).Parse(*p_partial); nil != err {
+				return "", err
+			}
+		}
+	}
+
+	var msg_html strings.Builder
+	if err = tmpl.ExecuteTemplate(&msg_html, name, data); nil != err {
+		return "", err
+	}
+
+	return msg_html.String(), nil
+}
+
+/*
+toTemplateModelEMAIL converts a model with the old placeholders (like MODEL_INFO_MSG_BODY_EMAIL) into an
+html/template template, replacing each placeholder by an action that gets its value from a map with the same keys.
+
+The HTML comments outside style and script elements are converted into "comment" actions (with the placeholders inside
+them as arguments), so that html/template does not remove them.
+
+-----------------------------------------------------------
+
+– Params:
+  - model – the contents of the model
+  - keys – the placeholders to convert
+
+– Returns:
+  - the template
+*/
+func toTemplateModelEMAIL(model string, keys []string) string {
+	keys = append([]string(nil), keys...)
+	// The longer ones first, in case one contains another.
+	sort.SliceStable(keys, func(i, j int) bool {
+		return len(keys[i]) > len(keys[j])
+	})
+
+	var old_new []string = nil
+	var old_new_comment []string = nil
+	for _, key := range keys {
+		if "" != key {
+			old_new = append(old_new, key, "{{index . " + strconv.Quote(key) + "}}")
+			// The keys have no characters that strconv.Quote() changes, so they can be replaced in the quoted text.
+			old_new_comment = append(old_new_comment, key, "\" (index . " + strconv.Quote(key) + ") \"")
+		}
+	}
+	var replacer *strings.Replacer = strings.NewReplacer(old_new...)
+	var replacer_comment *strings.Replacer = strings.NewReplacer(old_new_comment...)
+
+	var template_model strings.Builder
+	var last int = 0
+	for _, loc := range model_comment_regex_GL.FindAllStringIndex(model, -1) {
+		if isInRawTextEMAIL(model[:loc[0]]) {
+			// html/template keeps the comments in style and script elements.
+			continue
+		}
+
+		template_model.WriteString(replacer.Replace(model[last:loc[0]]))
+		template_model.WriteString("{{comment " +
+			replacer_comment.Replace(strconv.Quote(model[loc[0] + len("<!--"):loc[1] - len("-->")])) + "}}")
+		last = loc[1]
+	}
+	template_model.WriteString(replacer.Replace(model[last:]))
+
+	return template_model.String()
+}
+
+/*
+isInRawTextEMAIL checks if the end of an HTML text is inside a style or script element.
+
+-----------------------------------------------------------
+
+– Params:
+  - html_text – the HTML text
+
+– Returns:
+  - true if the end of the text is inside a style or script element, false otherwise
+*/
+func isInRawTextEMAIL(html_text string) bool {
+	html_text = strings.ToLower(html_text)
+	for _, tag := range []string{"style", "script"} {
+		if strings.LastIndex(html_text, "<" + tag) > strings.LastIndex(html_text, "</" + tag) {
+			return true
+		}
+	}
+
+	return false
+}
+
+/*
+commentTemplateEMAIL is the "comment" function of the email templates, which writes an HTML comment.
+
+-----------------------------------------------------------
+
+– Params:
+  - parts – the contents of the comment, alternating between text written as is and values escaped as HTML (unless of
+    type template.HTML)
+
+– Returns:
+  - the HTML comment
+*/
+func commentTemplateEMAIL(parts ...any) template.HTML {
+	var comment strings.Builder
+	comment.WriteString("<!--")
+	for i, part := range parts {
+		switch part := part.(type) {
+			case template.HTML:
+				comment.WriteString(string(part))
+			case string:
+				if 0 == i % 2 {
+					comment.WriteString(part)
+				} else {
+					comment.WriteString(html.EscapeString(part))
+				}
+			default:
+				if nil != part {
+					comment.WriteString(html.EscapeString(fmt.Sprint(part)))
+				}
+		}
+	}
+	comment.WriteString("-->")
+
+	return template.HTML(comment.String())
+}
+
+/*
+getModelValuesEMAIL converts the values of the old placeholders into the data of a template converted with
+toTemplateModelEMAIL().
+
+-----------------------------------------------------------
+
+– Params:
+  - things_replace – the map of placeholders to their values
+
+– Returns:
+  - the map of placeholders to their values, with the ones in model_html_keys_GL as template.HTML
+*/
+func getModelValuesEMAIL(things_replace map[string]string) map[string]any {
+	var values map[string]any = make(map[string]any, len(things_replace))
+	for key, value := range things_replace {
+		if ContainsSLICES(model_html_keys_GL, key) {
+			values[key] = template.HTML(value)
+		} else {
+			values[key] = value
+		}
+	}
+
+	return values
+}
+
+/*
+replaceModelValuesEMAIL replaces the old placeholders of a model by their values, escaped as HTML text except for the
+ones in model_html_keys_GL.
+
+This is the fallback for the models that are not valid templates.
+
+-----------------------------------------------------------
+
+– Params:
+  - model – the contents of the model
+  - things_replace – the map of placeholders to their values
+
+– Returns:
+  - the model with the placeholders replaced
+*/
+func replaceModelValuesEMAIL(model string, things_replace map[string]string) string {
+	for key, value := range things_replace {
+		if !ContainsSLICES(model_html_keys_GL, key) {
+			value = html.EscapeString(value)
+		}
+		model = strings.ReplaceAll(model, key, value)
+	}
+
+	return model
+}
+
+/*
+getModelSenderEMAIL gets the sender name of the emails of a model.
+
+-----------------------------------------------------------
+
+– Params:
+  - file_name – the name of the model file
+
+– Returns:
+  - the sender name, or an empty string if the model has none
+*/
+func getModelSenderEMAIL(file_name string) string {
+	switch file_name {
+		case MODEL_FILE_INFO:
+			return "VISOR - Info"
+		case MODEL_FILE_RSS:
+			return "VISOR - RSS"
+		case MODEL_FILE_YT_VIDEO:
+			return "YouTube"
+		case MODEL_FILE_DISKS_SMART:
+			return "VISOR - S.M.A.R.T."
+	}
+
+	return ""
+}
+
+/*
+getModelsDirEMAIL gets the directory of the email models.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the path to the directory
+*/
+func getModelsDirEMAIL() GPath {
+	return getProgramDataDirMODULES(NUM_MOD_EmailSender).Add2(true, _EMAIL_MODELS_FOLDER)
+}
@@ -0,0 +1,89 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package Utils
+
+import (
+	"testing"
+)
+
+func TestToTemplateModel(t *testing.T) {
+	var tests = []struct {
+		name   string
+		model  string
+		values map[string]string
+		want   string
+	}{
+		{"text escaped", "<p>|3234_ENTRY_TITLE|</p>",
+			map[string]string{MODEL_RSS_ENTRY_TITLE_EMAIL: "A <b> & B"}, "<p>A &lt;b&gt; &amp; B</p>"},
+		{"attribute escaped", "<a href=\"|3234_ENTRY_URL|\">x</a>",
+			map[string]string{MODEL_RSS_ENTRY_URL_EMAIL: "https://example.com/?a=1&b=\"2\""},
+			"<a href=\"https://example.com/?a=1&amp;b=%222%22\">x</a>"},
+		{"message body is HTML", "<p>|3234_MSG_BODY|</p>",
+			map[string]string{MODEL_INFO_MSG_BODY_EMAIL: "Line<br>break"}, "<p>Line<br>break</p>"},
+		{"error report is HTML", "<div>|3234_ERROR_REPORT|</div>",
+			map[string]string{MODEL_DISKS_SMART_ERROR_REPORT_EMAIL: "<b>bad</b>"}, "<div><b>bad</b></div>"},
+		{"longer key first", "|3234_DATE_TIME_START| |3234_DATE_TIME|",
+			map[string]string{MODEL_INFO_DATE_TIME_EMAIL: "now", MODEL_DISKS_SMART_DATE_TIME_START_EMAIL: "start"},
+			"start now"},
+		{"Outlook conditional comment kept",
+			"<!--[if mso]><table><tr><td>|3234_ENTRY_TITLE|</td></tr></table><![endif]--><p>Text</p>",
+			map[string]string{MODEL_RSS_ENTRY_TITLE_EMAIL: "a < b"},
+			"<!--[if mso]><table><tr><td>a &lt; b</td></tr></table><![endif]--><p>Text</p>"},
+		{"comment with HTML value", "<!--|3234_MSG_BODY|-->",
+			map[string]string{MODEL_INFO_MSG_BODY_EMAIL: "<br>"}, "<!--<br>-->"},
+		{"comment in style kept as is", "<style><!-- p { color: red; } --></style>", nil,
+			"<style><!-- p { color: red; } --></style>"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var keys []string = nil
+			for key := range test.values {
+				keys = append(keys, key)
+			}
+			got, err := renderModelEMAIL("model", toTemplateModelEMAIL(test.model, keys), getModelValuesEMAIL(test.values))
+			if nil != err {
+				t.Fatal(err)
+			}
+			if test.want != got {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestToTemplateModelComment(t *testing.T) {
+	var got string = toTemplateModelEMAIL("a<!--x |3234_MSG_BODY| \"y\"-->b", []string{MODEL_INFO_MSG_BODY_EMAIL})
+	var want string = "a{{comment \"x \" (index . \"|3234_MSG_BODY|\") \" \\\"y\\\"\"}}b"
+	if want != got {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestReplaceModelValues(t *testing.T) {
+	var got string = replaceModelValuesEMAIL("{{ |3234_ENTRY_TITLE| |3234_MSG_BODY|", map[string]string{
+		MODEL_RSS_ENTRY_TITLE_EMAIL: "<i>",
+		MODEL_INFO_MSG_BODY_EMAIL:   "<br>",
+	})
+	if want := "{{ &lt;i&gt; <br>"; want != got {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
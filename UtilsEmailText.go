@@ -0,0 +1,479 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package Utils
+
+import (
+	"html"
+	"strconv"
+	"strings"
+)
+
+// _HtmlToText is the state of the conversion of an HTML document to plain text.
+type _HtmlToText struct {
+	// text is the text converted so far.
+	text strings.Builder
+	// links is the list of the link footnotes, in order.
+	links []string
+	// href is the address of the link being converted, or empty if not inside a link.
+	href string
+	// link_text_start is the length of the text when the link being converted started.
+	link_text_start int
+	// skip_tag is the name of the tag whose contents are being skipped (like "script"), or empty.
+	skip_tag string
+	// pre_depth is the number of "pre" tags the conversion is inside of, where the whitespace is kept.
+	pre_depth int
+	// lists is the stack of the lists the conversion is inside of: -1 for unordered ones, else the number of the
+	// last item.
+	lists []int
+	// cell_num is the number of cells already converted in the current table row.
+	cell_num int
+}
+
+/*
+HtmlToTextEMAIL converts an HTML email to a readable plain text alternative.
+
+The links become footnotes listed at the end, the tables have their cells separated by " | " in one line per row, the
+lists have their items prefixed by "- " or their numbers, and the whitespace is normalized as a browser would do.
+
+-----------------------------------------------------------
+
+– Params:
+  - html_str – the HTML to convert
+
+– Returns:
+  - the plain text
+*/
+func HtmlToTextEMAIL(html_str string) string {
+	var htmlToText _HtmlToText
+	for i := 0; i < len(html_str); {
+		if '<' != html_str[i] {
+			var end int = strings.IndexByte(html_str[i:], '<')
+			if end < 0 {
+				end = len(html_str)
+			} else {
+				end += i
+			}
+			htmlToText.addText(html.UnescapeString(html_str[i:end]))
+			i = end
+
+			continue
+		}
+
+		// Comments, doctypes and processing instructions
+		if strings.HasPrefix(html_str[i:], "<!--") {
+			var end int = strings.Index(html_str[i + 4:], "-->")
+			if end < 0 {
+				break
+			}
+			i += 4 + end + 3
+
+			continue
+		}
+		if i + 1 < len(html_str) && ('!' == html_str[i + 1] || '?' == html_str[i + 1]) {
+			var end int = strings.IndexByte(html_str[i:], '>')
+			if end < 0 {
+				break
+			}
+			i += end + 1
+
+			continue
+		}
+
+		name, attrs, closing, length := parseHtmlTagEMAIL(html_str[i:])
+		if 0 == length {
+			// Not a tag, just a "<" in the text.
+			htmlToText.addText("<")
+			i++
+
+			continue
+		}
+		i += length
+		if "" != htmlToText.skip_tag {
+			if closing && name == htmlToText.skip_tag {
+				htmlToText.skip_tag = ""
+			}
+
+			continue
+		}
+		htmlToText.addTag(name, attrs, closing)
+	}
+
+	var text string = normalizeTextLinesEMAIL(htmlToText.text.String())
+	if len(htmlToText.links) > 0 {
+		text += "\n\n"
+		for i, link := range htmlToText.links {
+			text += "[" + strconv.Itoa(i + 1) + "] " + link + "\n"
+		}
+	}
+
+	return text
+}
+
+/*
+addTag converts a tag.
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the lowercase name of the tag
+  - attrs – the attributes of the tag, with lowercase names
+  - closing – true if it's a closing tag, false otherwise
+*/
+func (htmlToText *_HtmlToText) addTag(name string, attrs map[string]string, closing bool) {
+	switch name {
+		case "head", "script", "style", "title", "template":
+			if !closing {
+				htmlToText.skip_tag = name
+			}
+		case "br":
+			htmlToText.text.WriteString("\n")
+		case "hr":
+			htmlToText.addBreaks(1)
+			htmlToText.text.WriteString("--------------------")
+			htmlToText.addBreaks(1)
+		case "p", "h1", "h2", "h3", "h4", "h5", "h6", "blockquote", "table":
+			htmlToText.addBreaks(2)
+		case "div", "section", "article", "header", "footer", "center", "tbody", "thead", "tfoot", "dl", "dt", "dd",
+				"form":
+			htmlToText.addBreaks(1)
+		case "pre":
+			htmlToText.addBreaks(2)
+			if closing {
+				if htmlToText.pre_depth > 0 {
+					htmlToText.pre_depth--
+				}
+			} else {
+				htmlToText.pre_depth++
+			}
+		case "ul", "ol":
+			htmlToText.addBreaks(1)
+			if closing {
+				if len(htmlToText.lists) > 0 {
+					htmlToText.lists = htmlToText.lists[:len(htmlToText.lists) - 1]
+				}
+			} else if "ul" == name {
+				htmlToText.lists = append(htmlToText.lists, -1)
+			} else {
+				htmlToText.lists = append(htmlToText.lists, 0)
+			}
+		case "li":
+			htmlToText.addBreaks(1)
+			if !closing {
+				var depth int = len(htmlToText.lists)
+				if depth > 1 {
+					htmlToText.text.WriteString(strings.Repeat("  ", depth - 1))
+				}
+				if depth > 0 && htmlToText.lists[depth - 1] >= 0 {
+					htmlToText.lists[depth - 1]++
+					htmlToText.text.WriteString(strconv.Itoa(htmlToText.lists[depth - 1]) + ". ")
+				} else {
+					htmlToText.text.WriteString("- ")
+				}
+			}
+		case "tr":
+			htmlToText.addBreaks(1)
+			htmlToText.cell_num = 0
+		case "td", "th":
+			if !closing {
+				if htmlToText.cell_num > 0 {
+					htmlToText.trimTrailingSpaces()
+					htmlToText.text.WriteString(" | ")
+				}
+				htmlToText.cell_num++
+			}
+		case "img":
+			if alt := strings.TrimSpace(attrs["alt"]); "" != alt {
+				htmlToText.addText("[" + alt + "]")
+			}
+		case "a":
+			if !closing {
+				htmlToText.href = strings.TrimSpace(attrs["href"])
+				htmlToText.link_text_start = htmlToText.text.Len()
+			} else if "" != htmlToText.href {
+				htmlToText.addLink()
+			}
+	}
+}
+
+/*
+addLink adds the footnote of the link that just ended, unless it's useless (like if the text is the address itself).
+*/
+func (htmlToText *_HtmlToText) addLink() {
+	var href string = htmlToText.href
+	htmlToText.href = ""
+	if strings.HasPrefix(href, "#") || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+		return
+	}
+
+	var link_text string = ""
+	// The text may have been trimmed to before the link started.
+	if htmlToText.link_text_start < htmlToText.text.Len() {
+		link_text = strings.TrimSpace(htmlToText.text.String()[htmlToText.link_text_start:])
+	}
+	if link_text == href || "mailto:" + link_text == href {
+		return
+	}
+
+	var num int = 0
+	for i, link := range htmlToText.links {
+		if link == href {
+			num = i + 1
+
+			break
+		}
+	}
+	if 0 == num {
+		htmlToText.links = append(htmlToText.links, href)
+		num = len(htmlToText.links)
+	}
+	htmlToText.trimTrailingSpaces()
+	htmlToText.text.WriteString(" [" + strconv.Itoa(num) + "]")
+}
+
+/*
+addText adds text, with the whitespace collapsed unless inside a "pre" tag.
+
+-----------------------------------------------------------
+
+– Params:
+  - text – the text, already unescaped
+*/
+func (htmlToText *_HtmlToText) addText(text string) {
+	if "" != htmlToText.skip_tag || "" == text {
+		return
+	}
+	if htmlToText.pre_depth > 0 {
+		htmlToText.text.WriteString(text)
+
+		return
+	}
+
+	// Non-breaking spaces are kept, as they're not to be collapsed.
+	var words []string = strings.FieldsFunc(text, func(r rune) bool {
+		return ' ' == r || '\t' == r || '\n' == r || '\r' == r || '\f' == r
+	})
+	var starts_space bool = strings.IndexAny(text[:1], " \t\n\r\f") == 0
+	var ends_space bool = strings.IndexAny(text[len(text) - 1:], " \t\n\r\f") == 0
+	if 0 == len(words) {
+		htmlToText.addSpace()
+
+		return
+	}
+	if starts_space {
+		htmlToText.addSpace()
+	}
+	htmlToText.text.WriteString(strings.Join(words, " "))
+	if ends_space {
+		htmlToText.addSpace()
+	}
+}
+
+/*
+addSpace adds a space, unless the text is empty or already ends with whitespace.
+*/
+func (htmlToText *_HtmlToText) addSpace() {
+	var text string = htmlToText.text.String()
+	if "" != text && !strings.HasSuffix(text, " ") && !strings.HasSuffix(text, "\n") {
+		htmlToText.text.WriteString(" ")
+	}
+}
+
+/*
+addBreaks makes the text end with at least the given number of line breaks, unless it's empty.
+
+-----------------------------------------------------------
+
+– Params:
+  - num – the number of line breaks
+*/
+func (htmlToText *_HtmlToText) addBreaks(num int) {
+	htmlToText.trimTrailingSpaces()
+	var text string = htmlToText.text.String()
+	if "" == text {
+		return
+	}
+
+	var existing int = len(text) - len(strings.TrimRight(text, "\n"))
+	if existing < num {
+		htmlToText.text.WriteString(strings.Repeat("\n", num - existing))
+	}
+}
+
+/*
+trimTrailingSpaces removes the spaces at the end of the text.
+*/
+func (htmlToText *_HtmlToText) trimTrailingSpaces() {
+	var text string = htmlToText.text.String()
+	var trimmed string = strings.TrimRight(text, " ")
+	if len(trimmed) != len(text) {
+		htmlToText.text.Reset()
+		htmlToText.text.WriteString(trimmed)
+	}
+}
+
+/*
+parseHtmlTagEMAIL parses the tag at the beginning of a string.
+
+-----------------------------------------------------------
+
+– Params:
+  - str – the string, beginning with "<"
+
+– Returns:
+  - the lowercase name of the tag
+  - the attributes of the tag, with lowercase names and unescaped values
+  - true if it's a closing tag, false otherwise
+  - the length of the tag, or 0 if the string doesn't begin with a tag
+*/
+func parseHtmlTagEMAIL(str string) (string, map[string]string, bool, int) {
+	var i int = 1
+	var closing bool = false
+	if i < len(str) && '/' == str[i] {
+		closing = true
+		i++
+	}
+
+	var name_start int = i
+	for i < len(str) && isHtmlNameCharEMAIL(str[i]) {
+		i++
+	}
+	if name_start == i || !isAsciiLetterEMAIL(str[name_start]) {
+		return "", nil, false, 0
+	}
+	var name string = strings.ToLower(str[name_start:i])
+
+	var attrs map[string]string = make(map[string]string)
+	for i < len(str) {
+		for i < len(str) && strings.IndexByte(" \t\n\r\f/", str[i]) >= 0 {
+			i++
+		}
+		if i >= len(str) {
+			break
+		}
+		if '>' == str[i] {
+			return name, attrs, closing, i + 1
+		}
+
+		var attr_start int = i
+		for i < len(str) && strings.IndexByte(" \t\n\r\f/>=", str[i]) < 0 {
+			i++
+		}
+		var attr_name string = strings.ToLower(str[attr_start:i])
+		for i < len(str) && strings.IndexByte(" \t\n\r\f", str[i]) >= 0 {
+			i++
+		}
+		if i >= len(str) || '=' != str[i] {
+			attrs[attr_name] = ""
+
+			continue
+		}
+		i++
+		for i < len(str) && strings.IndexByte(" \t\n\r\f", str[i]) >= 0 {
+			i++
+		}
+		if i >= len(str) {
+			break
+		}
+
+		var value string
+		if '"' == str[i] || '\'' == str[i] {
+			var end int = strings.IndexByte(str[i + 1:], str[i])
+			if end < 0 {
+				break
+			}
+			value = str[i + 1:i + 1 + end]
+			i += 1 + end + 1
+		} else {
+			var value_start int = i
+			for i < len(str) && strings.IndexByte(" \t\n\r\f>", str[i]) < 0 {
+				i++
+			}
+			value = str[value_start:i]
+		}
+		attrs[attr_name] = html.UnescapeString(value)
+	}
+
+	// The tag never ends.
+	return "", nil, false, 0
+}
+
+/*
+isHtmlNameCharEMAIL checks if a character can be part of the name of an HTML tag.
+
+-----------------------------------------------------------
+
+– Params:
+  - char – the character
+
+– Returns:
+  - true if it can, false otherwise
+*/
+func isHtmlNameCharEMAIL(char byte) bool {
+	return isAsciiLetterEMAIL(char) || (char >= '0' && char <= '9') || '-' == char
+}
+
+/*
+isAsciiLetterEMAIL checks if a character is an ASCII letter.
+
+-----------------------------------------------------------
+
+– Params:
+  - char – the character
+
+– Returns:
+  - true if it is, false otherwise
+*/
+func isAsciiLetterEMAIL(char byte) bool {
+	return (char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z')
+}
+
+/*
+normalizeTextLinesEMAIL removes the spaces around the lines and the repeated empty lines of a text.
+
+-----------------------------------------------------------
+
+– Params:
+  - text – the text
+
+– Returns:
+  - the text, with at most one empty line in a row and none at the beginning or end
+*/
+func normalizeTextLinesEMAIL(text string) string {
+	var lines []string = strings.Split(text, "\n")
+	var result []string = nil
+	var empty_lines int = 0
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t\r")
+		if "" == strings.TrimSpace(line) {
+			empty_lines++
+
+			continue
+		}
+		if empty_lines > 0 && len(result) > 0 {
+			result = append(result, "")
+		}
+		empty_lines = 0
+		result = append(result, line)
+	}
+
+	return strings.Join(result, "\n")
+}
@@ -0,0 +1,61 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package Utils
+
+import (
+	"testing"
+)
+
+func TestHtmlToText(t *testing.T) {
+	var tests = []struct {
+		name string
+		html string
+		want string
+	}{
+		{"empty", "", ""},
+		{"paragraphs and whitespace", "<p>Hello   <b>world</b></p><p>Second</p>", "Hello world\n\nSecond"},
+		{"line break", "Line<br>break", "Line\nbreak"},
+		{"links as footnotes, repeated once",
+			"<a href=\"https://example.com\">link</a> and <a href=\"https://example.com\">again</a>",
+			"link [1] and again [1]\n\n[1] https://example.com\n"},
+		{"link with its own address as text", "<a href=\"https://example.com\">https://example.com</a>",
+			"https://example.com"},
+		{"mailto link", "<a href=\"mailto:x@example.com\">x@example.com</a>", "x@example.com"},
+		{"unordered list", "<ul><li>one</li><li>two</li></ul>", "- one\n- two"},
+		{"ordered list", "<ol><li>one</li><li>two</li></ol>", "1. one\n2. two"},
+		{"table", "<table><tr><td>a</td><td>b</td></tr><tr><td>c</td><td>d</td></tr></table>", "a | b\nc | d"},
+		{"hidden elements",
+			"<html><head><title>T</title><style>p{}</style></head><body>Body<script>x()</script></body></html>",
+			"Body"},
+		{"comments and entities", "<!-- comment -->A &amp; B &lt;tag&gt; 1 < 2", "A & B <tag> 1 < 2"},
+		{"Outlook conditional comment", "<!--[if mso]><table><tr><td>mso</td></tr></table><![endif]-->Text",
+			"Text"},
+		{"preformatted", "<pre>  keep\n  spaces</pre>", "  keep\n  spaces"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := HtmlToTextEMAIL(test.html); test.want != got {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package Utils
+
+import (
+	"testing"
+)
+
+func TestEmailRoutingIsValid(t *testing.T) {
+	var tests = []struct {
+		name         string
+		emailRouting EmailRouting
+		want         bool
+	}{
+		{"empty", EmailRouting{}, true},
+		{"valid", EmailRouting{
+			DEFAULT: []string{"a@example.com"},
+			ADMIN:   []string{"Admin <admin@example.com>"},
+			MODELS:  map[string][]string{MODEL_FILE_RSS: {"\"Doe, John\" <john@example.com>"}},
+			MODULES: map[string][]string{"3": {"b@example.com"}},
+		}, true},
+		{"no at sign", EmailRouting{DEFAULT: []string{"example.com"}}, false},
+		{"two addresses in one", EmailRouting{ADMIN: []string{"a@example.com, b@example.com"}}, false},
+		{"unclosed angle bracket", EmailRouting{MODELS: map[string][]string{MODEL_FILE_RSS: {"A <a@example.com"}}},
+			false},
+		{"only the at sign", EmailRouting{DEFAULT: []string{"@"}}, false},
+		{"module not a number", EmailRouting{MODULES: map[string][]string{"RSS": {"a@example.com"}}}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.emailRouting.isValid(); test.want != got {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
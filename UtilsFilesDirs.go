@@ -45,6 +45,13 @@ type GPath struct {
 	// dir is true if the path *describes* a directory, false if it *describes* a file (means no matter if it exists and
 	// we have permissions to read it or not).
 	dir bool
+	// perms is the permissions policy used to create the path (passed on to the paths added to it), or an empty one to
+	// use the default one. It's stored by value so that GPaths stay comparable with ==.
+	perms PermsPolicy
+	// fs is the file system of the path (passed on to the paths added to it), or nil to use the default one.
+	fs FileSystem
+	// sandbox is the path of the directory the path and the ones added to it can't go out of, or "" for none.
+	sandbox string
 }
 
 /*
@@ -52,6 +59,11 @@ PathFILESDIRS combines a path from the given subpaths of type string or GPath (O
 
 Note: the path separators used are always converted to the OS ones.
 
+Note 2: the file system, the permissions policy and the sandbox of the first GPath subpath (if any) are passed on to the
+final path. If the final path goes out of that sandbox (with ".." for example), it keeps the sandbox, so it can be read
+but not changed (check GPath.Sandbox()) - use GPath.Child() for names that can't be trusted, which refuses the path
+right away.
+
 -----------------------------------------------------------
 
 – Params:
@@ -63,6 +75,7 @@ Note: the path separators used are always converted to the OS ones.
 */
 func PathFILESDIRS(describes_dir bool, separator string, sub_paths ...any) GPath {
 	var sub_paths_str []string = nil
+	var base_gPath *GPath = nil
 	for _, sub_path := range sub_paths {
 		val_str, ok := sub_path.(string)
 		if ok {
@@ -74,6 +87,9 @@ func PathFILESDIRS(describes_dir bool, separator string, sub_paths ...any) GPath
 		val_GPath, ok := sub_path.(GPath)
 		if ok {
 			sub_paths_str = append(sub_paths_str, val_GPath.p)
+			if nil == base_gPath {
+				base_gPath = &val_GPath
+			}
 
 			continue
 		}
@@ -86,6 +102,10 @@ func PathFILESDIRS(describes_dir bool, separator string, sub_paths ...any) GPath
 		return GPath{}
 	}
 
+	if "" == separator {
+		separator = string(os.PathSeparator)
+	}
+
 	if describes_dir {
 		// If the path describes a directory, make sure it ends with a path separator.
 		if !strings.HasSuffix(sub_paths_str[len(sub_paths_str)-1], separator) {
@@ -108,10 +128,6 @@ func PathFILESDIRS(describes_dir bool, separator string, sub_paths ...any) GPath
 		ends_in_separator = true
 	}
 
-	if "" == separator {
-		separator = string(os.PathSeparator)
-	}
-
 	// The call to Join() is on purpose - it correctly joins *and cleans* the final path string (only if it's used with
 	// the OS path separator - which is always the case).
 	var gPath GPath = GPath{
@@ -119,6 +135,12 @@ func PathFILESDIRS(describes_dir bool, separator string, sub_paths ...any) GPath
 		s:   separator,
 		dir: false,
 	}
+	if nil != base_gPath {
+		gPath.perms = base_gPath.perms
+		gPath.fs = base_gPath.fs
+		// Kept even if the path goes out of it, so that no changes can be done out of it.
+		gPath.sandbox = base_gPath.sandbox
+	}
 	gPath.dir = gPath.DescribesDir()
 
 	// Check if the path describes a directory and if it does, make sure the path separator is at the end (especially
@@ -131,6 +153,7 @@ func PathFILESDIRS(describes_dir bool, separator string, sub_paths ...any) GPath
 		// As last resort, check through the last character on the subpaths list (project convention).
 		if ends_in_separator && !strings.HasSuffix(gPath.p, gPath.s) {
 			gPath.p += gPath.s
+			gPath.dir = true
 		}
 	}
 
@@ -194,6 +217,53 @@ func (gPath GPath) GPathToStringConversion() string {
 	return gPath.p
 }
 
+/*
+Name gets the last element of the path - the name of the file or directory it describes.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the name of the file or directory, without any path separators
+*/
+func (gPath GPath) Name() string {
+	var path string = strings.TrimSuffix(gPath.p, gPath.s)
+	if "" == gPath.s {
+		return path
+	}
+
+	return path[strings.LastIndex(path, gPath.s)+len(gPath.s):]
+}
+
+/*
+Dir gets the directory that contains the path.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the parent directory of the path, describing a directory
+*/
+func (gPath GPath) Dir() GPath {
+	var name string = gPath.Name()
+	if "" == name {
+		// Root directory.
+		return gPath
+	}
+
+	var path string = gPath.p
+	if gPath.dir {
+		path = path[:len(path)-len(gPath.s)]
+	}
+	path = path[:len(path)-len(name)]
+	if "" == path {
+		path = "."
+	}
+	// Given as a GPath to keep the file system, the permissions policy and the sandbox.
+	var base_gPath GPath = gPath
+	base_gPath.p = path
+
+	return PathFILESDIRS(true, gPath.s, base_gPath)
+}
+
 /*
 ReadTextFile reads the contents of a file.
 
@@ -209,7 +279,7 @@ func (gPath GPath) ReadTextFile() *string {
 		return nil
 	}
 
-	data, err := os.ReadFile(gPath.p)
+	data, err := readFileFS(gPath.getFS(), gPath.p)
 	if nil != err {
 		return nil
 	}
@@ -234,7 +304,7 @@ func (gPath GPath) ReadFile() []byte {
 		return nil
 	}
 
-	data, err := os.ReadFile(gPath.p)
+	data, err := readFileFS(gPath.getFS(), gPath.p)
 	if nil != err {
 		return nil
 	}
@@ -257,22 +327,15 @@ for any other, "\r\n" and "\r" are replaced by "\n".
   - nil if the file was written successfully, an error otherwise (including if the path describes a directory)
 */
 func (gPath GPath) WriteTextFile(content string) error {
-	var new_content string = content
-	if "windows" == runtime.GOOS {
-		new_content = strings.ReplaceAll(new_content, "\r\n", "\n")
-		new_content = strings.ReplaceAll(new_content, "\r", "\n")
-		new_content = strings.ReplaceAll(new_content, "\n", "\r\n")
-	} else {
-		new_content = strings.ReplaceAll(new_content, "\r\n", "\n")
-		new_content = strings.ReplaceAll(new_content, "\r", "\n")
-	}
-
-	return gPath.WriteFile([]byte(new_content))
+	return gPath.WriteFile([]byte(toOsLineBreaksFILESDIRS(content)))
 }
 
 /*
 WriteFile writes the raw contents of a file, creating it and any directories if necessary.
 
+Note: a path that describes a directory and the errors creating the file or its directories are returned as errors.
+Older versions returned nil in those cases, as if the file had been written.
+
 -----------------------------------------------------------
 
 – Params:
@@ -282,12 +345,16 @@ WriteFile writes the raw contents of a file, creating it and any directories if
   - nil if the file was written successfully, an error otherwise (including if the path describes a directory)
  */
 func (gPath GPath) WriteFile(content []byte) error {
-	if gPath.dir || nil != gPath.Create(true) {
-		return nil
+	if gPath.dir {
+		return errors.New("the path describes a directory")
+	}
+	if err := gPath.Create(true); nil != err {
+		return err
 	}
 
-	var err = os.WriteFile(gPath.p, content, 0o777)
-	_ = os.Chmod(gPath.p, 0o777)
+	var perms PermsPolicy = gPath.getPerms()
+	var err = writeFileFS(gPath.getFS(), gPath.p, content, perms.Files)
+	_ = gPath.getFS().Chmod(gPath.p, perms.Files)
 
 	return err
 }
@@ -306,7 +373,7 @@ doesn't is a file.
   - true if the path describes a directory, false if it describes a file
  */
 func (gPath GPath) DescribesDir() bool {
-	file_info, err := os.Stat(gPath.p)
+	file_info, err := gPath.getFS().Stat(gPath.p)
 	if nil == err {
 		return file_info.IsDir()
 	}
@@ -327,13 +394,15 @@ func (gPath GPath) Exists() bool {
 		return false
 	}
 
-	_, err := os.Stat(gPath.p)
+	_, err := gPath.getFS().Stat(gPath.p)
 	return nil == err
 }
 
 /*
 Create creates a path and any necessary subdirectories in case they don't exist already.
 
+The permissions of what's created are the ones of the permissions policy of the path (check WithPerms()).
+
 -----------------------------------------------------------
 
 – Params:
@@ -346,8 +415,14 @@ func (gPath GPath) Create(create_file bool) error {
 	if err := gPath.IsSupported(); nil != err {
 		return err
 	}
+	// Checked here too because the parent directories are created without the sandbox (the ones of the sandbox itself
+	// may not exist yet).
+	if !gPath.isInSandbox() {
+		return &os.PathError{Op: "create", Path: gPath.p, Err: ErrOutOfSandbox}
+	}
 
-	var path_list []string = strings.Split(gPath.p, gPath.s)
+	// The separator at the end of directory paths is removed so that there's no empty last element.
+	var path_list []string = strings.Split(strings.TrimSuffix(gPath.p, gPath.s), gPath.s)
 	var describes_file bool = false
 	if !gPath.dir {
 		// If the path is a file, remove the file part of the file from the list so that it describes a directory only,
@@ -356,9 +431,19 @@ func (gPath GPath) Create(create_file bool) error {
 		path_list = path_list[:len(path_list) - 1]
 	}
 
+	var perms PermsPolicy = gPath.getPerms()
+
+	// The directory of the path (itself if it's a directory), checked on the same file system.
+	var dir_gPath GPath = gPath
+	if describes_file {
+		dir_gPath = gPath.Dir()
+	}
+
 	// Create all parent directories if they don't exist.
-	if !PathFILESDIRS(true, "", gPath.p[:FindAllIndexesGENERAL(gPath.p, gPath.s)[len(path_list)-1]+1]).Exists() {
-		var current_path GPath = GPath{}
+	if len(path_list) > 0 && !dir_gPath.Exists() {
+		var current_path GPath = GPath{
+			fs: gPath.fs,
+		}
 		if strings.HasPrefix(gPath.p, gPath.s) {
 			current_path.p = gPath.s
 		}
@@ -371,8 +456,8 @@ func (gPath GPath) Create(create_file bool) error {
 			current_path.p += sub_path + gPath.s
 
 			if !current_path.Exists() {
-				if err := os.Mkdir(current_path.p, 0o777); nil == err {
-					_ = os.Chmod(current_path.p, 0o777)
+				if err := current_path.getFS().Mkdir(current_path.p, perms.Dirs); nil == err {
+					_ = current_path.getFS().Chmod(current_path.p, perms.Dirs)
 				} else {
 					return err
 				}
@@ -382,12 +467,12 @@ func (gPath GPath) Create(create_file bool) error {
 
 	// Create the file if the path represents a file.
 	if create_file && describes_file && !gPath.Exists() {
-		_, err := os.Create(gPath.p)
-		_ = os.Chmod(gPath.p, 0o777)
-
+		file, err := gPath.getFS().OpenFile(gPath.p, os.O_RDWR|os.O_CREATE|os.O_TRUNC, perms.Files)
 		if nil != err {
 			return err
 		}
+		_ = file.Close()
+		_ = gPath.getFS().Chmod(gPath.p, perms.Files)
 	}
 
 	return nil
@@ -406,7 +491,7 @@ func (gPath GPath) Remove() error {
 		return err
 	}
 
-	return os.Remove(gPath.p)
+	return gPath.getFS().Remove(gPath.p)
 }
 
 /*
@@ -443,16 +528,43 @@ func (gPath GPath) IsSupported() error {
 	return nil
 }
 
+/*
+toOsLineBreaksFILESDIRS replaces all line breaks in a string with the OS line break(s).
+
+For Windows, "\r" and "\n" are replaced with "\r\n" and for any other, "\r\n" and "\r" are replaced by "\n".
+
+-----------------------------------------------------------
+
+– Params:
+  - content – the string to convert
+
+– Returns:
+  - the string with the OS line breaks
+*/
+func toOsLineBreaksFILESDIRS(content string) string {
+	var new_content string = content
+	if "windows" == runtime.GOOS {
+		new_content = strings.ReplaceAll(new_content, "\r\n", "\n")
+		new_content = strings.ReplaceAll(new_content, "\r", "\n")
+		new_content = strings.ReplaceAll(new_content, "\n", "\r\n")
+	} else {
+		new_content = strings.ReplaceAll(new_content, "\r\n", "\n")
+		new_content = strings.ReplaceAll(new_content, "\r", "\n")
+	}
+
+	return new_content
+}
+
 /*
 GetBinDirFILESDIRS gets the full path to the directory of the binaries.
 
 -----------------------------------------------------------
 
 – Returns:
-  - the full path to the directory of the binaries
+  - the full path to the directory of the binaries, with the PermsProgramsFILESDIRS() permissions policy
 */
 func GetBinDirFILESDIRS() GPath {
-	return PersonalConsts_GL._VISOR_DIR.Add2(true, _BIN_REL_DIR)
+	return PersonalConsts_GL._VISOR_DIR.Add2(true, _BIN_REL_DIR).WithPerms(PermsProgramsFILESDIRS())
 }
 
 /*
@@ -0,0 +1,638 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package Utils
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// ARCHIVE_AUTO is the ArchiveOptions.Format that chooses the format from the extension of the archive file.
+	ARCHIVE_AUTO int = iota
+	// ARCHIVE_ZIP is the ArchiveOptions.Format of zip archives.
+	ARCHIVE_ZIP
+	// ARCHIVE_TAR_GZ is the ArchiveOptions.Format of gzip-compressed tar archives.
+	ARCHIVE_TAR_GZ
+)
+
+// ArchiveOptions is the options for GPath.ArchiveTo() and GPath.ExtractTo().
+type ArchiveOptions struct {
+	// Format is the format of the archive - one of the ARCHIVE_ constants.
+	Format int
+	// Include is the patterns (as in GPath.Glob(), relative to the archived directory) of the paths to include, or nil
+	// to include all. A directory that matches includes all its contents.
+	Include []string
+	// Exclude is the patterns (as in Include) of the paths to leave out. A directory that matches leaves out all its
+	// contents. Takes precedence over Include.
+	Exclude []string
+}
+
+// _ArchiveEntry is an entry of an archive being extracted, independent of the archive format.
+type _ArchiveEntry struct {
+	// name is the path of the entry inside the archive.
+	name string
+	// mode is the type and permissions of the entry.
+	mode os.FileMode
+	// mod_time is the modification time of the entry.
+	mod_time time.Time
+	// link_target is the target of the entry if it's a symbolic link.
+	link_target string
+	// open opens the contents of the entry if it's a file.
+	open func() (io.ReadCloser, error)
+}
+
+/*
+ArchiveTo writes the contents of the directory to an archive file (zip or tar.gz), keeping the permissions,
+modification times and symbolic links.
+
+-----------------------------------------------------------
+
+– Params:
+  - dst – the path of the archive file to write
+  - options – the options for the archive or nil for the default ones (format from the extension, all paths)
+
+– Returns:
+  - the paths archived
+  - nil if the archive was written successfully, an error otherwise (including if the path is not a directory)
+*/
+func (gPath GPath) ArchiveTo(dst GPath, options *ArchiveOptions) ([]GPath, error) {
+	options = getArchiveOptionsFILESDIRS(options)
+	format, err := getArchiveFormatFILESDIRS(dst, options.Format)
+	if nil != err {
+		return nil, err
+	}
+
+	tree_entries, err := gPath.collectTree(SYMLINKS_KEEP)
+	if nil != err {
+		return nil, err
+	}
+	if 0 == len(tree_entries) || !tree_entries[0].file_info.IsDir() {
+		return nil, errors.New("the path to archive is not a directory")
+	}
+
+	file, err := dst.OpenCreate()
+	if nil != err {
+		return nil, err
+	}
+
+	var gPaths []GPath = nil
+	switch format {
+		case ARCHIVE_ZIP:
+			gPaths, err = gPath.writeZip(file, dst, tree_entries, options)
+		case ARCHIVE_TAR_GZ:
+			gPaths, err = gPath.writeTarGz(file, dst, tree_entries, options)
+	}
+	if err_close := file.Close(); nil == err {
+		err = err_close
+	}
+
+	return gPaths, err
+}
+
+/*
+ExtractTo extracts an archive file (zip or tar.gz) into a directory, keeping the permissions, modification times and
+symbolic links.
+
+Entries that would end up outside the directory (with ".." or absolute names, or through symbolic links - "zip-slip")
+stop the extraction with an error.
+
+-----------------------------------------------------------
+
+– Params:
+  - dst – the directory to extract to, created if necessary
+  - options – the options for the archive or nil for the default ones (format from the extension, all paths)
+
+– Returns:
+  - the paths extracted
+  - nil if the archive was extracted successfully, an error otherwise
+*/
+func (gPath GPath) ExtractTo(dst GPath, options *ArchiveOptions) ([]GPath, error) {
+	options = getArchiveOptionsFILESDIRS(options)
+	format, err := getArchiveFormatFILESDIRS(gPath, options.Format)
+	if nil != err {
+		return nil, err
+	}
+
+	file, err := gPath.Open()
+	if nil != err {
+		return nil, err
+	}
+	defer file.Close()
+
+	if err = dst.Create(false); nil != err {
+		return nil, err
+	}
+	var dst_sandbox GPath = dst.Sandbox()
+
+	var gPaths []GPath = nil
+	var dirs_extracted []_ArchiveEntry = nil
+	var extract = func(archiveEntry _ArchiveEntry) error {
+		extracted, err := extractEntryFILESDIRS(dst_sandbox, archiveEntry, options)
+		if nil != err || nil == extracted {
+			return err
+		}
+
+		gPaths = append(gPaths, *extracted)
+		if archiveEntry.mode.IsDir() {
+			archiveEntry.name = extracted.p
+			dirs_extracted = append(dirs_extracted, archiveEntry)
+		}
+
+		return nil
+	}
+
+	switch format {
+		case ARCHIVE_ZIP:
+			err = readZipFILESDIRS(file, extract)
+		case ARCHIVE_TAR_GZ:
+			err = readTarGzFILESDIRS(file, extract)
+	}
+
+	// Children before parents, so that the parents' times are not changed afterwards.
+	var dst_fs FileSystem = dst.getFS()
+	for i := len(dirs_extracted) - 1; i >= 0; i-- {
+		_ = dst_fs.Chmod(dirs_extracted[i].name, dirs_extracted[i].mode.Perm())
+		_ = dst_fs.Chtimes(dirs_extracted[i].name, dirs_extracted[i].mod_time, dirs_extracted[i].mod_time)
+	}
+
+	return gPaths, err
+}
+
+/*
+writeZip writes the entries of the directory to a zip archive.
+
+-----------------------------------------------------------
+
+– Params:
+  - writer – where to write the archive
+  - dst – the path of the archive file (left out if it's inside the directory)
+  - tree_entries – the entries of the directory
+  - options – the options for the archive
+
+– Returns:
+  - the paths archived
+  - nil if the archive was written successfully, an error otherwise
+*/
+func (gPath GPath) writeZip(writer io.Writer, dst GPath, tree_entries []_TreeEntry,
+							options *ArchiveOptions) ([]GPath, error) {
+	var zip_writer *zip.Writer = zip.NewWriter(writer)
+
+	var gPaths []GPath = nil
+	for _, tree_entry := range tree_entries {
+		if !isArchiveEntryWantedFILESDIRS(tree_entry, dst, options) {
+			continue
+		}
+
+		header, err := zip.FileInfoHeader(tree_entry.file_info)
+		if nil != err {
+			return gPaths, err
+		}
+		header.Name = tree_entry.rel_path
+		if tree_entry.file_info.IsDir() {
+			header.Name += "/"
+		} else {
+			header.Method = zip.Deflate
+		}
+
+		entry_writer, err := zip_writer.CreateHeader(header)
+		if nil != err {
+			return gPaths, err
+		}
+		if err = gPath.writeArchiveEntryData(entry_writer, tree_entry); nil != err {
+			return gPaths, err
+		}
+		gPaths = append(gPaths, tree_entry.gPath)
+	}
+
+	return gPaths, zip_writer.Close()
+}
+
+/*
+writeTarGz writes the entries of the directory to a tar.gz archive.
+
+-----------------------------------------------------------
+
+– Params:
+  - writer – where to write the archive
+  - dst – the path of the archive file (left out if it's inside the directory)
+  - tree_entries – the entries of the directory
+  - options – the options for the archive
+
+– Returns:
+  - the paths archived
+  - nil if the archive was written successfully, an error otherwise
+*/
+func (gPath GPath) writeTarGz(writer io.Writer, dst GPath, tree_entries []_TreeEntry,
+								options *ArchiveOptions) ([]GPath, error) {
+	var gzip_writer *gzip.Writer = gzip.NewWriter(writer)
+	var tar_writer *tar.Writer = tar.NewWriter(gzip_writer)
+
+	var gPaths []GPath = nil
+	var err error = nil
+	for _, tree_entry := range tree_entries {
+		if !isArchiveEntryWantedFILESDIRS(tree_entry, dst, options) {
+			continue
+		}
+
+		var link_target string = ""
+		if 0 != tree_entry.file_info.Mode() & os.ModeSymlink {
+			if link_target, err = gPath.getFS().Readlink(tree_entry.gPath.p); nil != err {
+				return gPaths, err
+			}
+		}
+
+		var header *tar.Header
+		if header, err = tar.FileInfoHeader(tree_entry.file_info, link_target); nil != err {
+			return gPaths, err
+		}
+		header.Name = tree_entry.rel_path
+		if tree_entry.file_info.IsDir() {
+			header.Name += "/"
+		}
+
+		if err = tar_writer.WriteHeader(header); nil != err {
+			return gPaths, err
+		}
+		if tree_entry.file_info.Mode().IsRegular() {
+			if err = gPath.writeArchiveEntryData(tar_writer, tree_entry); nil != err {
+				return gPaths, err
+			}
+		}
+		gPaths = append(gPaths, tree_entry.gPath)
+	}
+
+	if err = tar_writer.Close(); nil != err {
+		return gPaths, err
+	}
+
+	return gPaths, gzip_writer.Close()
+}
+
+/*
+writeArchiveEntryData writes the data of an entry to an archive: the contents of files or the target of symbolic links
+(as zip stores them). Directories have no data.
+
+-----------------------------------------------------------
+
+– Params:
+  - writer – where to write the data
+  - tree_entry – the entry
+
+– Returns:
+  - nil if the data was written successfully, an error otherwise
+*/
+func (gPath GPath) writeArchiveEntryData(writer io.Writer, tree_entry _TreeEntry) error {
+	var fileSystem FileSystem = gPath.getFS()
+	var mode os.FileMode = tree_entry.file_info.Mode()
+	if 0 != mode & os.ModeSymlink {
+		link_target, err := fileSystem.Readlink(tree_entry.gPath.p)
+		if nil == err {
+			_, err = io.WriteString(writer, filepath.ToSlash(link_target))
+		}
+
+		return err
+	}
+	if !mode.IsRegular() {
+		return nil
+	}
+
+	file, err := fileSystem.OpenFile(tree_entry.gPath.p, os.O_RDONLY, 0)
+	if nil != err {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(writer, file)
+
+	return err
+}
+
+/*
+readZipFILESDIRS reads the entries of a zip archive.
+
+-----------------------------------------------------------
+
+– Params:
+  - file – the archive file
+  - entryFunc – the function called for each entry
+
+– Returns:
+  - nil if the archive was read successfully, an error otherwise (including the ones returned by entryFunc)
+*/
+func readZipFILESDIRS(file FSFile, entryFunc func(archiveEntry _ArchiveEntry) error) error {
+	file_info, err := file.Stat()
+	if nil != err {
+		return err
+	}
+
+	// The zip reader needs random access, which not all file systems give.
+	reader_at, ok := file.(io.ReaderAt)
+	if !ok {
+		data, err := io.ReadAll(file)
+		if nil != err {
+			return err
+		}
+		reader_at = bytes.NewReader(data)
+	}
+
+	zip_reader, err := zip.NewReader(reader_at, file_info.Size())
+	if nil != err {
+		return err
+	}
+
+	for _, zip_file := range zip_reader.File {
+		var zip_file *zip.File = zip_file
+		var archiveEntry _ArchiveEntry = _ArchiveEntry{
+			name:     zip_file.Name,
+			mode:     zip_file.Mode(),
+			mod_time: zip_file.Modified,
+			open: func() (io.ReadCloser, error) {
+				return zip_file.Open()
+			},
+		}
+		if 0 != archiveEntry.mode & os.ModeSymlink {
+			// The target of symbolic links is stored as the contents.
+			reader, err := zip_file.Open()
+			if nil != err {
+				return err
+			}
+			link_target, err := io.ReadAll(io.LimitReader(reader, 4096))
+			_ = reader.Close()
+			if nil != err {
+				return err
+			}
+			archiveEntry.link_target = string(link_target)
+		}
+
+		if err = entryFunc(archiveEntry); nil != err {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/*
+readTarGzFILESDIRS reads the entries of a tar.gz archive.
+
+-----------------------------------------------------------
+
+– Params:
+  - file – the archive file
+  - entryFunc – the function called for each entry
+
+– Returns:
+  - nil if the archive was read successfully, an error otherwise (including the ones returned by entryFunc)
+*/
+func readTarGzFILESDIRS(file FSFile, entryFunc func(archiveEntry _ArchiveEntry) error) error {
+	gzip_reader, err := gzip.NewReader(file)
+	if nil != err {
+		return err
+	}
+	defer gzip_reader.Close()
+
+	var tar_reader *tar.Reader = tar.NewReader(gzip_reader)
+	for {
+		header, err := tar_reader.Next()
+		if io.EOF == err {
+			return nil
+		} else if nil != err {
+			return err
+		}
+
+		var archiveEntry _ArchiveEntry = _ArchiveEntry{
+			name:        header.Name,
+			mode:        header.FileInfo().Mode(),
+			mod_time:    header.ModTime,
+			link_target: header.Linkname,
+			open: func() (io.ReadCloser, error) {
+				return io.NopCloser(tar_reader), nil
+			},
+		}
+		switch header.Typeflag {
+			case tar.TypeDir, tar.TypeReg, tar.TypeSymlink:
+				if err = entryFunc(archiveEntry); nil != err {
+					return err
+				}
+			default:
+				// Hard links, devices and others are not supported and ignored.
+		}
+	}
+}
+
+/*
+extractEntryFILESDIRS extracts an entry of an archive into a directory (except the permissions and time of
+directories, which must be set after their contents are extracted).
+
+-----------------------------------------------------------
+
+– Params:
+  - dst – the directory to extract to, as a sandbox
+  - archiveEntry – the entry
+  - options – the options for the archive
+
+– Returns:
+  - the path extracted, or nil if the entry was left out
+  - nil if the entry was extracted successfully or left out, an error otherwise
+*/
+func extractEntryFILESDIRS(dst GPath, archiveEntry _ArchiveEntry, options *ArchiveOptions) (*GPath, error) {
+	rel_path, err := getArchiveRelPathFILESDIRS(archiveEntry.name)
+	if nil != err {
+		return nil, err
+	}
+	if "" == rel_path || !isPathWantedFILESDIRS(rel_path, options.Include, options.Exclude) {
+		return nil, nil
+	}
+
+	// The sandbox of dst makes sure the path is inside it, also through symbolic links extracted before.
+	var extracted GPath = dst.Add2(archiveEntry.mode.IsDir(), rel_path)
+	if !extracted.isInSandbox() {
+		return nil, errors.New("path going out of the directory in the archive: \"" + archiveEntry.name + "\"")
+	}
+	if err = extracted.checkSymlinksInSandbox(); nil != err {
+		return nil, err
+	}
+
+	var dst_fs FileSystem = dst.getFS()
+	var parent_dir string = filepath.Dir(strings.TrimSuffix(extracted.p, extracted.s))
+	if err = mkdirAllFS(dst_fs, parent_dir, dst.getPerms().Dirs); nil != err {
+		return nil, err
+	}
+
+	if archiveEntry.mode.IsDir() {
+		return &extracted, mkdirAllFS(dst_fs, extracted.p, dst.getPerms().Dirs)
+	}
+
+	if 0 != archiveEntry.mode & os.ModeSymlink {
+		// The link must point inside the directory too, or files could be extracted through it later.
+		var link_target string = filepath.FromSlash(archiveEntry.link_target)
+		if filepath.IsAbs(link_target) || strings.HasPrefix(archiveEntry.link_target, "/") {
+			return nil, errors.New("the symbolic link \"" + rel_path + "\" points outside the directory")
+		}
+		var target_rel string = path.Join(path.Dir(rel_path), archiveEntry.link_target)
+		if ".." == target_rel || strings.HasPrefix(target_rel, "../") {
+			return nil, errors.New("the symbolic link \"" + rel_path + "\" points outside the directory")
+		}
+
+		_ = dst_fs.Remove(extracted.p)
+
+		return &extracted, dst_fs.Symlink(link_target, extracted.p)
+	}
+
+	reader, err := archiveEntry.open()
+	if nil != err {
+		return nil, err
+	}
+	defer reader.Close()
+
+	file, err := dst_fs.OpenFile(extracted.p, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, archiveEntry.mode.Perm())
+	if nil != err {
+		return nil, err
+	}
+	_, err = io.Copy(file, reader)
+	if err_close := file.Close(); nil == err {
+		err = err_close
+	}
+	if nil != err {
+		return nil, err
+	}
+	_ = dst_fs.Chmod(extracted.p, archiveEntry.mode.Perm())
+	_ = dst_fs.Chtimes(extracted.p, archiveEntry.mod_time, archiveEntry.mod_time)
+
+	return &extracted, nil
+}
+
+/*
+getArchiveRelPathFILESDIRS gets the relative path of an entry of an archive, rejecting names that would end up outside
+the directory of extraction.
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the name of the entry in the archive
+
+– Returns:
+  - the relative path with "/" as the separator, or "" for the root directory itself
+  - nil if the name is safe, an error otherwise
+*/
+func getArchiveRelPathFILESDIRS(name string) (string, error) {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if strings.HasPrefix(name, "/") || filepath.IsAbs(filepath.FromSlash(name)) ||
+			(len(name) >= 2 && ':' == name[1]) {
+		return "", errors.New("absolute path in the archive: \"" + name + "\"")
+	}
+
+	var parts []string = nil
+	for _, part := range strings.Split(name, "/") {
+		switch part {
+			case "", ".":
+				continue
+			case "..":
+				return "", errors.New("path going out of the directory in the archive: \"" + name + "\"")
+		}
+		parts = append(parts, part)
+	}
+
+	return strings.Join(parts, "/"), nil
+}
+
+/*
+isArchiveEntryWantedFILESDIRS checks if an entry of a directory is to be put in an archive.
+
+-----------------------------------------------------------
+
+– Params:
+  - tree_entry – the entry
+  - dst – the path of the archive file
+  - options – the options for the archive
+
+– Returns:
+  - true if the entry is to be archived, false otherwise
+*/
+func isArchiveEntryWantedFILESDIRS(tree_entry _TreeEntry, dst GPath, options *ArchiveOptions) bool {
+	// The root is implicit and the archive can't contain itself.
+	if "" == tree_entry.rel_path || strings.TrimSuffix(tree_entry.gPath.p, tree_entry.gPath.s) == dst.p {
+		return false
+	}
+
+	return isPathWantedFILESDIRS(tree_entry.rel_path, options.Include, options.Exclude)
+}
+
+/*
+getArchiveFormatFILESDIRS gets the format of an archive.
+
+-----------------------------------------------------------
+
+– Params:
+  - gPath – the path of the archive file
+  - format – the format given in the options
+
+– Returns:
+  - the format - ARCHIVE_ZIP or ARCHIVE_TAR_GZ
+  - nil if the format is known, an error otherwise
+*/
+func getArchiveFormatFILESDIRS(gPath GPath, format int) (int, error) {
+	switch format {
+		case ARCHIVE_ZIP, ARCHIVE_TAR_GZ:
+			return format, nil
+		case ARCHIVE_AUTO:
+			var name string = strings.ToLower(gPath.Name())
+			if strings.HasSuffix(name, ".zip") {
+				return ARCHIVE_ZIP, nil
+			} else if strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".tgz") {
+				return ARCHIVE_TAR_GZ, nil
+			}
+
+			return 0, errors.New("unknown archive extension: \"" + gPath.Name() + "\"")
+	}
+
+	return 0, errors.New("invalid archive format")
+}
+
+/*
+getArchiveOptionsFILESDIRS gets the options to use for the archive operations.
+
+-----------------------------------------------------------
+
+– Params:
+  - options – the options given or nil
+
+– Returns:
+  - the options given or the default ones if nil
+*/
+func getArchiveOptionsFILESDIRS(options *ArchiveOptions) *ArchiveOptions {
+	if nil == options {
+		return &ArchiveOptions{}
+	}
+
+	return options
+}
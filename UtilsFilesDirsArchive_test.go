@@ -0,0 +1,186 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package Utils
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// _TestArchiveEntry is an entry of an archive built for the tests.
+type _TestArchiveEntry struct {
+	name        string
+	link_target string
+	data        string
+}
+
+func TestExtractToRejectsEscapes(t *testing.T) {
+	var tests = []struct {
+		name         string
+		entries      []_TestArchiveEntry
+		needsSymlink bool
+		wantErr      bool
+	}{
+		{"safe file", []_TestArchiveEntry{{name: "dir/file.txt", data: "x"}}, false, false},
+		{"dot dot", []_TestArchiveEntry{{name: "../evil.txt", data: "x"}}, false, true},
+		{"dot dot in the middle", []_TestArchiveEntry{{name: "dir/../../evil.txt", data: "x"}}, false, true},
+		{"absolute", []_TestArchiveEntry{{name: "/evil.txt", data: "x"}}, false, true},
+		{"drive letter", []_TestArchiveEntry{{name: "C:/evil.txt", data: "x"}}, false, true},
+		{"backslashes", []_TestArchiveEntry{{name: "..\\evil.txt", data: "x"}}, false, true},
+		{"symlink inside", []_TestArchiveEntry{{name: "link", link_target: "dir"}}, true, false},
+		{"symlink out relative", []_TestArchiveEntry{{name: "link", link_target: "../.."}}, true, true},
+		{"symlink out absolute", []_TestArchiveEntry{{name: "link", link_target: "/etc"}}, true, true},
+		{"symlink out nested", []_TestArchiveEntry{{name: "dir/link", link_target: "../../x"}}, true, true},
+	}
+	for _, format := range []int{ARCHIVE_ZIP, ARCHIVE_TAR_GZ} {
+		for _, test := range tests {
+			t.Run(map[int]string{ARCHIVE_ZIP: "zip", ARCHIVE_TAR_GZ: "tar.gz"}[format] + "/" + test.name, func(t *testing.T) {
+				if test.needsSymlink && "windows" == runtime.GOOS {
+					t.Skip("symbolic links need special permissions on Windows")
+				}
+
+				var dir string = t.TempDir()
+				var archive GPath = writeTestArchive(t, dir, format, test.entries)
+				_, err := archive.ExtractTo(PathFILESDIRS(true, "", dir, "out", "box"), nil)
+				if (nil != err) != test.wantErr {
+					t.Fatalf("got error %v, want error %v", err, test.wantErr)
+				}
+				if _, err := os.Lstat(filepath.Join(dir, "out", "evil.txt")); !errors.Is(err, os.ErrNotExist) {
+					t.Error("a file was extracted outside the directory")
+				}
+			})
+		}
+	}
+}
+
+func TestExtractToThroughSymlink(t *testing.T) {
+	if "windows" == runtime.GOOS {
+		t.Skip("symbolic links need special permissions on Windows")
+	}
+
+	var dir string = t.TempDir()
+	var dst string = filepath.Join(dir, "box")
+	if err := os.MkdirAll(dst, 0o777); nil != err {
+		t.Fatal(err)
+	}
+	// A link already in the directory, pointing out of it.
+	if err := os.Symlink(dir, filepath.Join(dst, "link")); nil != err {
+		t.Fatal(err)
+	}
+
+	for _, format := range []int{ARCHIVE_ZIP, ARCHIVE_TAR_GZ} {
+		t.Run(map[int]string{ARCHIVE_ZIP: "zip", ARCHIVE_TAR_GZ: "tar.gz"}[format], func(t *testing.T) {
+			var archive GPath = writeTestArchive(t, t.TempDir(), format,
+				[]_TestArchiveEntry{{name: "link/evil.txt", data: "x"}})
+			if _, err := archive.ExtractTo(PathFILESDIRS(true, "", dst), nil); nil == err {
+				t.Error("extracting through a symbolic link out of the directory didn't fail")
+			}
+			if _, err := os.Lstat(filepath.Join(dir, "evil.txt")); !errors.Is(err, os.ErrNotExist) {
+				t.Error("a file was extracted outside the directory")
+			}
+		})
+	}
+}
+
+/*
+writeTestArchive writes an archive with the given entries.
+
+-----------------------------------------------------------
+
+– Params:
+  - t – the test
+  - dir – the directory to write the archive to
+  - format – the format of the archive - ARCHIVE_ZIP or ARCHIVE_TAR_GZ
+  - entries – the entries of the archive
+
+– Returns:
+  - the path of the archive
+*/
+func writeTestArchive(t *testing.T, dir string, format int, entries []_TestArchiveEntry) GPath {
+	t.Helper()
+
+	var buf bytes.Buffer
+	var name string = "archive.zip"
+	if ARCHIVE_ZIP == format {
+		var zip_writer *zip.Writer = zip.NewWriter(&buf)
+		for _, entry := range entries {
+			var header *zip.FileHeader = &zip.FileHeader{Name: entry.name, Method: zip.Store}
+			var data string = entry.data
+			if "" != entry.link_target {
+				header.SetMode(os.ModeSymlink | 0o777)
+				data = entry.link_target
+			} else {
+				header.SetMode(0o644)
+			}
+			writer, err := zip_writer.CreateHeader(header)
+			if nil != err {
+				t.Fatal(err)
+			}
+			if _, err = writer.Write([]byte(data)); nil != err {
+				t.Fatal(err)
+			}
+		}
+		if err := zip_writer.Close(); nil != err {
+			t.Fatal(err)
+		}
+	} else {
+		name = "archive.tar.gz"
+		var gzip_writer *gzip.Writer = gzip.NewWriter(&buf)
+		var tar_writer *tar.Writer = tar.NewWriter(gzip_writer)
+		for _, entry := range entries {
+			var header *tar.Header = &tar.Header{Name: entry.name, Mode: 0o644, Size: int64(len(entry.data)),
+				Typeflag: tar.TypeReg}
+			if "" != entry.link_target {
+				header = &tar.Header{Name: entry.name, Mode: 0o777, Linkname: entry.link_target,
+					Typeflag: tar.TypeSymlink}
+			}
+			if err := tar_writer.WriteHeader(header); nil != err {
+				t.Fatal(err)
+			}
+			if tar.TypeReg == header.Typeflag {
+				if _, err := tar_writer.Write([]byte(entry.data)); nil != err {
+					t.Fatal(err)
+				}
+			}
+		}
+		if err := tar_writer.Close(); nil != err {
+			t.Fatal(err)
+		}
+		if err := gzip_writer.Close(); nil != err {
+			t.Fatal(err)
+		}
+	}
+
+	var archive_path string = filepath.Join(dir, name)
+	if err := os.WriteFile(archive_path, buf.Bytes(), 0o644); nil != err {
+		t.Fatal(err)
+	}
+
+	return PathFILESDIRS(false, "", archive_path)
+}
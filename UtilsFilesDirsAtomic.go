@@ -0,0 +1,199 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package Utils
+
+import (
+	"errors"
+	"io"
+	"os"
+	"runtime"
+)
+
+// BACKUP_FILE_EXT is the extension added to the backup copies kept by the atomic writes.
+const BACKUP_FILE_EXT string = ".bak"
+
+// AtomicWriteOptions is the options for GPath.WriteFileAtomic() and GPath.WriteTextFileAtomic().
+type AtomicWriteOptions struct {
+	// Keep_backup is true to keep a copy of the previous contents of the file (if it existed) in a file with the same
+	// name plus BACKUP_FILE_EXT.
+	Keep_backup bool
+	// Perm is the permissions of the written file, or 0 for the ones of the permissions policy of the path.
+	Perm os.FileMode
+}
+
+/*
+WriteTextFileAtomic is the same as WriteTextFile() but writes the file atomically. Check WriteFileAtomic() for more
+information.
+
+-----------------------------------------------------------
+
+– Params:
+  - content – the contents to write
+  - options – the options for the write or nil for the default ones
+
+– Returns:
+  - nil if the file was written successfully, an error otherwise (including if the path describes a directory)
+*/
+func (gPath GPath) WriteTextFileAtomic(content string, options *AtomicWriteOptions) error {
+	return gPath.WriteFileAtomic([]byte(toOsLineBreaksFILESDIRS(content)), options)
+}
+
+/*
+WriteFileAtomic writes the raw contents of a file atomically and durably, creating any directories if necessary.
+
+The contents are first written to a unique temporary file in the same directory, which is synced to the disk and then
+renamed to the final name, after which the directory is synced too. So the file either has all the previous contents or
+all the new ones, even if the program or the machine crashes in the middle of the write.
+
+-----------------------------------------------------------
+
+– Params:
+  - content – the contents to write
+  - options – the options for the write or nil for the default ones
+
+– Returns:
+  - nil if the file was written successfully, an error otherwise (including if the path describes a directory)
+*/
+func (gPath GPath) WriteFileAtomic(content []byte, options *AtomicWriteOptions) error {
+	if gPath.dir {
+		return errors.New("the path describes a directory")
+	}
+	if err := gPath.Create(false); nil != err {
+		return err
+	}
+
+	var perm os.FileMode = gPath.getPerms().Files
+	var keep_backup bool = false
+	if nil != options {
+		if 0 != options.Perm {
+			perm = options.Perm
+		}
+		keep_backup = options.Keep_backup
+	}
+
+	var fileSystem FileSystem = gPath.getFS()
+	var dir GPath = gPath.Dir()
+	file, err := fileSystem.CreateTemp(dir.p, "." + gPath.Name() + ".tmp*")
+	if nil != err {
+		return err
+	}
+	var tmp_path string = file.Name()
+
+	_, err = file.Write(content)
+	if nil == err {
+		err = file.Sync()
+	}
+	if err_close := file.Close(); nil == err {
+		err = err_close
+	}
+	if nil == err {
+		err = fileSystem.Chmod(tmp_path, perm)
+	}
+	if nil == err && keep_backup && gPath.Exists() {
+		err = copyFileFILESDIRS(fileSystem, gPath.p, fileSystem, gPath.p + BACKUP_FILE_EXT, perm, true)
+	}
+	if nil == err {
+		err = fileSystem.Rename(tmp_path, gPath.p)
+	}
+	if nil != err {
+		_ = fileSystem.Remove(tmp_path)
+
+		return err
+	}
+
+	return syncDirFILESDIRS(dir)
+}
+
+/*
+copyFileFILESDIRS copies a file to another one, replacing it if it exists.
+
+-----------------------------------------------------------
+
+– Params:
+  - src_fs – the file system of the file to copy
+  - src_path – the path of the file to copy
+  - dst_fs – the file system of the copy
+  - dst_path – the path of the copy
+  - perm – the permissions of the copy
+  - sync – true to sync the copy to the disk before returning, false otherwise
+
+– Returns:
+  - nil if the file was copied successfully, an error otherwise
+*/
+func copyFileFILESDIRS(src_fs FileSystem, src_path string, dst_fs FileSystem, dst_path string, perm os.FileMode,
+					   sync bool) error {
+	src_file, err := src_fs.OpenFile(src_path, os.O_RDONLY, 0)
+	if nil != err {
+		return err
+	}
+	defer src_file.Close()
+
+	dst_file, err := dst_fs.OpenFile(dst_path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if nil != err {
+		return err
+	}
+
+	_, err = io.Copy(dst_file, src_file)
+	if nil == err && sync {
+		err = dst_file.Sync()
+	}
+	if err_close := dst_file.Close(); nil == err {
+		err = err_close
+	}
+	if nil == err {
+		// In case the file already existed with other permissions.
+		err = dst_fs.Chmod(dst_path, perm)
+	}
+
+	return err
+}
+
+/*
+syncDirFILESDIRS syncs a directory to the disk so that renames and creations inside it are durable.
+
+On Windows this does nothing, since directories can't be opened for syncing there (and NTFS already journals renames).
+Neither on file systems other than the OS one.
+
+-----------------------------------------------------------
+
+– Params:
+  - dir – the directory to sync
+
+– Returns:
+  - nil if the directory was synced successfully (or on Windows), an error otherwise
+*/
+func syncDirFILESDIRS(dir GPath) error {
+	if "windows" == runtime.GOOS || !dir.isOsFS() {
+		return nil
+	}
+
+	dir_file, err := os.Open(dir.p)
+	if nil != err {
+		return err
+	}
+	err = dir_file.Sync()
+	if err_close := dir_file.Close(); nil == err {
+		err = err_close
+	}
+
+	return err
+}
@@ -0,0 +1,247 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package Utils
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// ENCRYPTED_FILE_MAGIC is the start of the header of the files written by GPath.WriteEncrypted().
+	ENCRYPTED_FILE_MAGIC string = "VISORENC"
+	// _ENCRYPTED_FILE_VERSION_1 is the version of the format AES-256-GCM with a 96-bit random nonce.
+	_ENCRYPTED_FILE_VERSION_1 byte = 1
+	// _ENCRYPTED_FILE_VERSION is the version of the format used to write files.
+	_ENCRYPTED_FILE_VERSION byte = _ENCRYPTED_FILE_VERSION_1
+	// _ENCRYPTION_KEY_MIN_LEN is the minimum length in bytes of an encryption key.
+	_ENCRYPTION_KEY_MIN_LEN int = 32
+	// _ENCRYPTION_KEY_CONTEXT is mixed in the derivation of the file encryption key, to keep it apart from other uses
+	// of the same key.
+	_ENCRYPTION_KEY_CONTEXT string = "VISOR file encryption v1"
+)
+
+var (
+	// encryption_key_GL is the encryption key set with SetEncryptionKeyFILESDIRS(), or nil to use the profile one.
+	encryption_key_GL []byte = nil
+	// encryption_key_mutex_GL protects encryption_key_GL.
+	encryption_key_mutex_GL sync.Mutex
+)
+
+/*
+WriteEncrypted writes the contents of a file encrypted (atomically), creating it and any directories if necessary.
+
+The file starts with a header with ENCRYPTED_FILE_MAGIC and the version of the format, followed by the data encrypted
+and authenticated with AES-256-GCM. The key is the one set with SetEncryptionKeyFILESDIRS() or else the one of the
+profile (ENCRYPTION_KEY or ENCRYPTION_KEY_FILE in PersonalConsts_EOG.json).
+
+-----------------------------------------------------------
+
+– Params:
+  - content – the contents to write
+
+– Returns:
+  - nil if the file was written successfully, an error otherwise (including if there's no key)
+*/
+func (gPath GPath) WriteEncrypted(content []byte) error {
+	aead, err := getFileAeadFILESDIRS()
+	if nil != err {
+		return err
+	}
+
+	var header []byte = append([]byte(ENCRYPTED_FILE_MAGIC), _ENCRYPTED_FILE_VERSION)
+	var nonce []byte = make([]byte, aead.NonceSize())
+	if _, err = rand.Read(nonce); nil != err {
+		return err
+	}
+
+	var data []byte = make([]byte, 0, len(header) + len(nonce) + len(content) + aead.Overhead())
+	data = append(data, header...)
+	data = append(data, nonce...)
+	// The header is authenticated too, so that the version can't be changed.
+	data = aead.Seal(data, nonce, content, header)
+
+	return gPath.WriteFileAtomic(data, nil)
+}
+
+/*
+ReadEncrypted reads the contents of a file written by WriteEncrypted().
+
+-----------------------------------------------------------
+
+– Returns:
+  - the decrypted contents of the file or nil if an error occurred
+  - nil if the file was read and decrypted successfully, an error otherwise (including if the file is not encrypted,
+	was changed, or the key is wrong or missing)
+*/
+func (gPath GPath) ReadEncrypted() ([]byte, error) {
+	if gPath.dir {
+		return nil, errors.New("the path describes a directory")
+	}
+
+	data, err := readFileFS(gPath.getFS(), gPath.p)
+	if nil != err {
+		return nil, err
+	}
+
+	var header_len int = len(ENCRYPTED_FILE_MAGIC) + 1
+	if !bytes.HasPrefix(data, []byte(ENCRYPTED_FILE_MAGIC)) || len(data) < header_len {
+		return nil, errors.New("the file is not encrypted")
+	}
+
+	switch data[header_len - 1] {
+		case _ENCRYPTED_FILE_VERSION_1:
+			aead, err := getFileAeadFILESDIRS()
+			if nil != err {
+				return nil, err
+			}
+			if len(data) < header_len + aead.NonceSize() + aead.Overhead() {
+				return nil, errors.New("the encrypted file is truncated")
+			}
+
+			var nonce []byte = data[header_len:header_len + aead.NonceSize()]
+			content, err := aead.Open(nil, nonce, data[header_len + aead.NonceSize():], data[:header_len])
+			if nil != err {
+				return nil, errors.New("the encrypted file was changed or the key is wrong")
+			}
+
+			return content, nil
+		default:
+			return nil, errors.New("unknown encrypted file version: " + strconv.Itoa(int(data[header_len - 1])))
+	}
+}
+
+/*
+IsEncrypted checks if a file was written by WriteEncrypted() (only by its header - it's not decrypted), for example to
+migrate plain text files to encrypted ones.
+
+-----------------------------------------------------------
+
+– Returns:
+  - true if the file starts with the header of encrypted files, false otherwise (including if it can't be read)
+*/
+func (gPath GPath) IsEncrypted() bool {
+	file, err := gPath.Open()
+	if nil != err {
+		return false
+	}
+	defer file.Close()
+
+	// A single Read may return less than asked for even if the file has more.
+	var magic []byte = make([]byte, len(ENCRYPTED_FILE_MAGIC))
+	if _, err = io.ReadFull(file, magic); nil != err {
+		return false
+	}
+
+	return ENCRYPTED_FILE_MAGIC == string(magic)
+}
+
+/*
+SetEncryptionKeyFILESDIRS sets the key used by GPath.WriteEncrypted() and GPath.ReadEncrypted() instead of the profile
+one.
+
+-----------------------------------------------------------
+
+– Params:
+  - key – the key (random bytes, at least 32 of them), or nil to go back to the profile one
+
+– Returns:
+  - nil if the key was set, an error if it's too short
+*/
+func SetEncryptionKeyFILESDIRS(key []byte) error {
+	if nil != key && len(key) < _ENCRYPTION_KEY_MIN_LEN {
+		return errors.New("the encryption key must have at least " + strconv.Itoa(_ENCRYPTION_KEY_MIN_LEN) + " bytes")
+	}
+
+	encryption_key_mutex_GL.Lock()
+	defer encryption_key_mutex_GL.Unlock()
+
+	encryption_key_GL = nil
+	if nil != key {
+		encryption_key_GL = append([]byte(nil), key...)
+	}
+
+	return nil
+}
+
+/*
+DecodeEncryptionKeyFILESDIRS decodes an encryption key written in base64 (as in the profile or in a key file).
+
+-----------------------------------------------------------
+
+– Params:
+  - key_base64 – the key in base64 (spaces and line breaks around it are ignored)
+
+– Returns:
+  - the key or nil if an error occurred
+  - nil if the key was decoded successfully, an error otherwise (including if it's too short)
+*/
+func DecodeEncryptionKeyFILESDIRS(key_base64 string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(key_base64))
+	if nil != err {
+		return nil, err
+	}
+	if len(key) < _ENCRYPTION_KEY_MIN_LEN {
+		return nil, errors.New("the encryption key must have at least " + strconv.Itoa(_ENCRYPTION_KEY_MIN_LEN) + " bytes")
+	}
+
+	return key, nil
+}
+
+/*
+getFileAeadFILESDIRS gets the cipher to encrypt and decrypt files with, from the current key.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the cipher or nil if an error occurred
+  - nil if the cipher was created successfully, an error otherwise (including if there's no key)
+*/
+func getFileAeadFILESDIRS() (cipher.AEAD, error) {
+	encryption_key_mutex_GL.Lock()
+	var key []byte = encryption_key_GL
+	encryption_key_mutex_GL.Unlock()
+	if nil == key {
+		key = PersonalConsts_GL._ENCRYPTION_KEY
+	}
+	if nil == key {
+		return nil, errors.New("no encryption key set in the profile")
+	}
+
+	// The key given is turned into an AES-256 key specific to this use.
+	var key_hash [sha512.Size]byte = sha512.Sum512(append([]byte(_ENCRYPTION_KEY_CONTEXT), key...))
+	block, err := aes.NewCipher(key_hash[:32])
+	if nil != err {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
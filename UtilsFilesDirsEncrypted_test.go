@@ -0,0 +1,184 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package Utils
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+)
+
+// setTestEncryptionKeyFILESDIRS sets the encryption key (nil for none at all, not even the profile one) until the end
+// of the test.
+func setTestEncryptionKeyFILESDIRS(t *testing.T, key []byte) {
+	var profile_key []byte = PersonalConsts_GL._ENCRYPTION_KEY
+	PersonalConsts_GL._ENCRYPTION_KEY = nil
+	if err := SetEncryptionKeyFILESDIRS(key); nil != err {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		PersonalConsts_GL._ENCRYPTION_KEY = profile_key
+		_ = SetEncryptionKeyFILESDIRS(nil)
+	})
+}
+
+func TestEncryptedRoundTrip(t *testing.T) {
+	setTestEncryptionKeyFILESDIRS(t, bytes.Repeat([]byte{1}, 32))
+	var path GPath = PathFILESDIRS(false, "", "/dir/file.enc").WithFS(NewMemFileSystemFILESDIRS())
+
+	var content []byte = []byte("secret contents")
+	if err := path.WriteEncrypted(content); nil != err {
+		t.Fatal(err)
+	}
+	if data := path.ReadFile(); bytes.Contains(data, content) {
+		t.Error("the contents were written in plain text")
+	}
+	if !path.IsEncrypted() {
+		t.Error("the file is not seen as encrypted")
+	}
+
+	got, err := path.ReadEncrypted()
+	if nil != err {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(content, got) {
+		t.Errorf("read %q, want %q", got, content)
+	}
+}
+
+func TestEncryptedRejected(t *testing.T) {
+	var key []byte = bytes.Repeat([]byte{1}, 32)
+	setTestEncryptionKeyFILESDIRS(t, key)
+	var path GPath = PathFILESDIRS(false, "", "/file.enc").WithFS(NewMemFileSystemFILESDIRS())
+	if err := path.WriteEncrypted([]byte("secret contents")); nil != err {
+		t.Fatal(err)
+	}
+	var data []byte = path.ReadFile()
+	var header_len int = len(ENCRYPTED_FILE_MAGIC) + 1
+
+	var tests = []struct {
+		name   string
+		change func(data []byte) []byte
+		key    []byte
+	}{
+		{"ciphertext changed", func(data []byte) []byte {
+			data[len(data) - 1] ^= 1
+
+			return data
+		}, key},
+		{"nonce changed", func(data []byte) []byte {
+			data[header_len] ^= 1
+
+			return data
+		}, key},
+		{"version changed", func(data []byte) []byte {
+			data[header_len - 1] = 2
+
+			return data
+		}, key},
+		{"magic changed", func(data []byte) []byte {
+			data[0] ^= 1
+
+			return data
+		}, key},
+		{"truncated", func(data []byte) []byte {
+			return data[:header_len + 4]
+		}, key},
+		{"wrong key", nil, bytes.Repeat([]byte{2}, 32)},
+		{"no key", nil, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var changed []byte = append([]byte(nil), data...)
+			if nil != test.change {
+				changed = test.change(changed)
+			}
+			if err := path.WriteFile(changed); nil != err {
+				t.Fatal(err)
+			}
+			setTestEncryptionKeyFILESDIRS(t, test.key)
+
+			if content, err := path.ReadEncrypted(); nil == err || nil != content {
+				t.Errorf("read %q (error %v), want an error", content, err)
+			}
+		})
+	}
+}
+
+func TestIsEncrypted(t *testing.T) {
+	var fs FileSystem = NewMemFileSystemFILESDIRS()
+	var tests = []struct {
+		name     string
+		contents string
+		want     bool
+	}{
+		{"encrypted header", ENCRYPTED_FILE_MAGIC + "\x01...", true},
+		{"only the magic", ENCRYPTED_FILE_MAGIC, true},
+		{"plain text", "some plain text", false},
+		{"shorter than the magic", ENCRYPTED_FILE_MAGIC[:3], false},
+		{"empty", "", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var path GPath = PathFILESDIRS(false, "", "/file").WithFS(fs)
+			if err := path.WriteTextFile(test.contents); nil != err {
+				t.Fatal(err)
+			}
+			if got := path.IsEncrypted(); test.want != got {
+				t.Errorf("IsEncrypted() = %v, want %v", got, test.want)
+			}
+		})
+	}
+
+	if PathFILESDIRS(false, "", "/missing").WithFS(fs).IsEncrypted() {
+		t.Error("a missing file is seen as encrypted")
+	}
+}
+
+func TestDecodeEncryptionKey(t *testing.T) {
+	var key []byte = bytes.Repeat([]byte{7}, 32)
+	var tests = []struct {
+		name       string
+		key_base64 string
+		want       []byte
+	}{
+		{"valid", base64.StdEncoding.EncodeToString(key), key},
+		{"valid with spaces around", " \n" + base64.StdEncoding.EncodeToString(key) + "\n", key},
+		{"too short", base64.StdEncoding.EncodeToString(key[:31]), nil},
+		{"empty", "", nil},
+		{"invalid base64", "not base64!", nil},
+		{"bad padding", base64.StdEncoding.EncodeToString(key)[:43], nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := DecodeEncryptionKeyFILESDIRS(test.key_base64)
+			if (nil == err) != (nil != test.want) || !bytes.Equal(test.want, got) {
+				t.Errorf("got %v (error %v), want %v", got, err, test.want)
+			}
+		})
+	}
+
+	if err := SetEncryptionKeyFILESDIRS(key[:31]); nil == err {
+		_ = SetEncryptionKeyFILESDIRS(nil)
+		t.Error("a short key was set")
+	}
+}
@@ -0,0 +1,691 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package Utils
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrReadOnlyFS is the error returned by the writing operations of a read-only file system.
+var ErrReadOnlyFS error = errors.New("the file system is read-only")
+
+/*
+FileSystem is the file system where the GPath operations are done. The default one is the OS (OsFileSystem), but it can
+be replaced globally with SetFileSystemFILESDIRS() or for a specific path (and all paths added to it, like the VISOR
+directory of a profile, which is chosen with FILE_SYSTEM in PersonalConsts_EOG.json) with GPath.WithFS().
+
+The implementations are OsFileSystem, MemFileSystem (all in memory), ReadOnlyFileSystem (refuses all changes) and
+OverlayFileSystem (keeps all changes in memory, on top of another file system).
+
+The methods work like the os package functions of the same names (filepath.EvalSymlinks() for EvalSymlinks()).
+*/
+type FileSystem interface {
+	Stat(name string) (os.FileInfo, error)
+	Lstat(name string) (os.FileInfo, error)
+	ReadDir(name string) ([]os.DirEntry, error)
+	OpenFile(name string, flag int, perm os.FileMode) (FSFile, error)
+	CreateTemp(dir string, pattern string) (FSFile, error)
+	Mkdir(name string, perm os.FileMode) error
+	Remove(name string) error
+	Rename(old_name string, new_name string) error
+	Chmod(name string, mode os.FileMode) error
+	Chtimes(name string, atime time.Time, mtime time.Time) error
+	Readlink(name string) (string, error)
+	Symlink(old_name string, new_name string) error
+	EvalSymlinks(name string) (string, error)
+}
+
+// FSFile is an opened file of a FileSystem. The methods work like the ones of os.File.
+type FSFile interface {
+	io.Reader
+	io.Writer
+	io.Seeker
+	io.Closer
+	Name() string
+	Stat() (os.FileInfo, error)
+	Sync() error
+}
+
+// OsFileSystem is the FileSystem of the OS, which just calls the os package functions.
+type OsFileSystem struct{}
+
+// ReadOnlyFileSystem is a FileSystem that reads from another one but refuses any change, returning ErrReadOnlyFS. To
+// allow changes without them reaching the other file system, use an OverlayFileSystem instead.
+type ReadOnlyFileSystem struct {
+	// FS is the file system to read from.
+	FS FileSystem
+}
+
+// fs_default_GL is the file system of the paths without one.
+var fs_default_GL FileSystem = OsFileSystem{}
+
+/*
+SetFileSystemFILESDIRS sets the file system of all the paths without one (check GPath.WithFS()).
+
+Module code doesn't need any change to use another file system - like a MemFileSystem for tests.
+
+-----------------------------------------------------------
+
+– Params:
+  - fileSystem – the new default file system
+*/
+func SetFileSystemFILESDIRS(fileSystem FileSystem) {
+	fs_default_GL = fileSystem
+}
+
+/*
+WithFS gets a copy of the path that uses the given file system, which is passed on to the paths added to it.
+
+-----------------------------------------------------------
+
+– Params:
+  - fileSystem – the file system
+
+– Returns:
+  - the path with the file system
+*/
+func (gPath GPath) WithFS(fileSystem FileSystem) GPath {
+	gPath.fs = fileSystem
+
+	return gPath
+}
+
+/*
+getFS gets the file system to use for the path.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the file system of the path or the default one if it has none, refusing the changes out of the sandbox if the path
+    has one
+*/
+func (gPath GPath) getFS() FileSystem {
+	var fileSystem FileSystem = gPath.getBaseFS()
+	if "" != gPath.sandbox {
+		return _SandboxFS{FileSystem: fileSystem, sandbox: gPath.sandbox}
+	}
+
+	return fileSystem
+}
+
+/*
+getBaseFS gets the file system of the path, without the sandbox checks of getFS().
+
+-----------------------------------------------------------
+
+– Returns:
+  - the file system of the path or the default one if it has none
+*/
+func (gPath GPath) getBaseFS() FileSystem {
+	if nil != gPath.fs {
+		return gPath.fs
+	}
+
+	return fs_default_GL
+}
+
+/*
+isOsFS checks if the path uses the OS file system, for the operations that only the OS supports (like native locking
+and watching).
+
+-----------------------------------------------------------
+
+– Returns:
+  - true if the path uses the OS file system, false otherwise
+*/
+func (gPath GPath) isOsFS() bool {
+	_, ok := gPath.getBaseFS().(OsFileSystem)
+
+	return ok
+}
+
+/*
+readFileFS reads all the contents of a file of a file system.
+
+-----------------------------------------------------------
+
+– Params:
+  - fileSystem – the file system
+  - name – the path of the file
+
+– Returns:
+  - the contents of the file
+  - nil if the file was read successfully, an error otherwise
+*/
+func readFileFS(fileSystem FileSystem, name string) ([]byte, error) {
+	file, err := fileSystem.OpenFile(name, os.O_RDONLY, 0)
+	if nil != err {
+		return nil, err
+	}
+	defer file.Close()
+
+	return io.ReadAll(file)
+}
+
+/*
+writeFileFS writes all the contents of a file of a file system, creating it if necessary.
+
+-----------------------------------------------------------
+
+– Params:
+  - fileSystem – the file system
+  - name – the path of the file
+  - data – the contents to write
+  - perm – the permissions of the file if it's created
+
+– Returns:
+  - nil if the file was written successfully, an error otherwise
+*/
+func writeFileFS(fileSystem FileSystem, name string, data []byte, perm os.FileMode) error {
+	file, err := fileSystem.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if nil != err {
+		return err
+	}
+
+	_, err = file.Write(data)
+	if err_close := file.Close(); nil == err {
+		err = err_close
+	}
+
+	return err
+}
+
+/*
+mkdirAllFS creates a directory and all its missing parents on a file system.
+
+-----------------------------------------------------------
+
+– Params:
+  - fileSystem – the file system
+  - name – the path of the directory
+  - perm – the permissions of the directories created
+
+– Returns:
+  - nil if the directory exists or was created successfully, an error otherwise
+*/
+func mkdirAllFS(fileSystem FileSystem, name string, perm os.FileMode) error {
+	if file_info, err := fileSystem.Stat(name); nil == err {
+		if file_info.IsDir() {
+			return nil
+		}
+
+		return errors.New("the path exists and is not a directory: " + name)
+	}
+
+	var parent string = filepath.Dir(filepath.Clean(name))
+	if parent != filepath.Clean(name) {
+		if err := mkdirAllFS(fileSystem, parent, perm); nil != err {
+			return err
+		}
+	}
+
+	var err error = fileSystem.Mkdir(name, perm)
+	if nil != err {
+		// Maybe created in the meantime.
+		if file_info, err_stat := fileSystem.Lstat(name); nil == err_stat && file_info.IsDir() {
+			return nil
+		}
+	}
+
+	return err
+}
+
+/*
+Stat gets information about a path with os.Stat(), following symbolic links.
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the path
+
+– Returns:
+  - the information about the path
+  - nil if the information was got successfully, an error otherwise
+*/
+func (OsFileSystem) Stat(name string) (os.FileInfo, error) {
+	return os.Stat(name)
+}
+
+/*
+Lstat gets information about a path with os.Lstat(), without following symbolic links.
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the path
+
+– Returns:
+  - the information about the path
+  - nil if the information was got successfully, an error otherwise
+*/
+func (OsFileSystem) Lstat(name string) (os.FileInfo, error) {
+	return os.Lstat(name)
+}
+
+/*
+ReadDir reads the contents of a directory with os.ReadDir().
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the path of the directory
+
+– Returns:
+  - the entries of the directory, sorted by name
+  - nil if the directory was read successfully, an error otherwise
+*/
+func (OsFileSystem) ReadDir(name string) ([]os.DirEntry, error) {
+	return os.ReadDir(name)
+}
+
+/*
+OpenFile opens a file with os.OpenFile().
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the path of the file
+  - flag – the os.O_* flags to open the file with
+  - perm – the permissions of the file if it's created
+
+– Returns:
+  - the opened file
+  - nil if the file was opened successfully, an error otherwise
+*/
+func (OsFileSystem) OpenFile(name string, flag int, perm os.FileMode) (FSFile, error) {
+	file, err := os.OpenFile(name, flag, perm)
+	if nil != err {
+		// Not returning the nil *os.File, which would be a non-nil FSFile.
+		return nil, err
+	}
+
+	return file, nil
+}
+
+/*
+CreateTemp creates a new temporary file in a directory with os.CreateTemp().
+
+-----------------------------------------------------------
+
+– Params:
+  - dir – the directory to create the file in
+  - pattern – the name of the file, with the last "*" replaced by a random string (or with it appended if there's none)
+
+– Returns:
+  - the opened file
+  - nil if the file was created successfully, an error otherwise
+*/
+func (OsFileSystem) CreateTemp(dir string, pattern string) (FSFile, error) {
+	file, err := os.CreateTemp(dir, pattern)
+	if nil != err {
+		return nil, err
+	}
+
+	return file, nil
+}
+
+/*
+Mkdir creates a directory with os.Mkdir().
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the path of the directory
+  - perm – the permissions of the directory
+
+– Returns:
+  - nil if the directory was created successfully, an error otherwise
+*/
+func (OsFileSystem) Mkdir(name string, perm os.FileMode) error {
+	return os.Mkdir(name, perm)
+}
+
+/*
+Remove removes a file or an empty directory with os.Remove().
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the path to remove
+
+– Returns:
+  - nil if the path was removed successfully, an error otherwise
+*/
+func (OsFileSystem) Remove(name string) error {
+	return os.Remove(name)
+}
+
+/*
+Rename renames (moves) a path with os.Rename().
+
+-----------------------------------------------------------
+
+– Params:
+  - old_name – the path to rename
+  - new_name – the new path
+
+– Returns:
+  - nil if the path was renamed successfully, an error otherwise
+*/
+func (OsFileSystem) Rename(old_name string, new_name string) error {
+	return os.Rename(old_name, new_name)
+}
+
+/*
+Chmod changes the permissions of a path with os.Chmod().
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the path
+  - mode – the new permissions
+
+– Returns:
+  - nil if the permissions were changed successfully, an error otherwise
+*/
+func (OsFileSystem) Chmod(name string, mode os.FileMode) error {
+	return os.Chmod(name, mode)
+}
+
+/*
+Chtimes changes the access and modification times of a path with os.Chtimes().
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the path
+  - atime – the new access time
+  - mtime – the new modification time
+
+– Returns:
+  - nil if the times were changed successfully, an error otherwise
+*/
+func (OsFileSystem) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	return os.Chtimes(name, atime, mtime)
+}
+
+/*
+Readlink gets the target of a symbolic link with os.Readlink().
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the path of the link
+
+– Returns:
+  - the target of the link
+  - nil if the target was got successfully, an error otherwise
+*/
+func (OsFileSystem) Readlink(name string) (string, error) {
+	return os.Readlink(name)
+}
+
+/*
+Symlink creates a symbolic link with os.Symlink().
+
+-----------------------------------------------------------
+
+– Params:
+  - old_name – the target of the link
+  - new_name – the path of the link
+
+– Returns:
+  - nil if the link was created successfully, an error otherwise
+*/
+func (OsFileSystem) Symlink(old_name string, new_name string) error {
+	return os.Symlink(old_name, new_name)
+}
+
+/*
+EvalSymlinks gets a path with its symbolic links resolved, with filepath.EvalSymlinks().
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the path
+
+– Returns:
+  - the resolved path
+  - nil if the path was resolved successfully, an error otherwise
+*/
+func (OsFileSystem) EvalSymlinks(name string) (string, error) {
+	return filepath.EvalSymlinks(name)
+}
+
+/*
+Stat gets information about a path of the wrapped file system, following symbolic links.
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the path
+
+– Returns:
+  - the information about the path
+  - nil if the information was got successfully, an error otherwise
+*/
+func (readOnlyFS ReadOnlyFileSystem) Stat(name string) (os.FileInfo, error) {
+	return readOnlyFS.FS.Stat(name)
+}
+
+/*
+Lstat gets information about a path of the wrapped file system, without following symbolic links.
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the path
+
+– Returns:
+  - the information about the path
+  - nil if the information was got successfully, an error otherwise
+*/
+func (readOnlyFS ReadOnlyFileSystem) Lstat(name string) (os.FileInfo, error) {
+	return readOnlyFS.FS.Lstat(name)
+}
+
+/*
+ReadDir reads the contents of a directory of the wrapped file system.
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the path of the directory
+
+– Returns:
+  - the entries of the directory, sorted by name
+  - nil if the directory was read successfully, an error otherwise
+*/
+func (readOnlyFS ReadOnlyFileSystem) ReadDir(name string) ([]os.DirEntry, error) {
+	return readOnlyFS.FS.ReadDir(name)
+}
+
+/*
+OpenFile opens a file of the wrapped file system, but only for reading.
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the path of the file
+  - flag – the os.O_* flags to open the file with
+  - perm – the permissions of the file if it's created
+
+– Returns:
+  - the opened file
+  - nil if the file was opened successfully, ErrReadOnlyFS if any writing flag was given, another error otherwise
+*/
+func (readOnlyFS ReadOnlyFileSystem) OpenFile(name string, flag int, perm os.FileMode) (FSFile, error) {
+	if 0 != flag & (os.O_WRONLY | os.O_RDWR | os.O_CREATE | os.O_TRUNC | os.O_APPEND) {
+		return nil, ErrReadOnlyFS
+	}
+
+	return readOnlyFS.FS.OpenFile(name, flag, perm)
+}
+
+/*
+CreateTemp refuses to create a temporary file.
+
+-----------------------------------------------------------
+
+– Params:
+  - dir – the directory to create the file in
+  - pattern – the name of the file, with the last "*" replaced by a random string (or with it appended if there's none)
+
+– Returns:
+  - always nil
+  - always ErrReadOnlyFS
+*/
+func (readOnlyFS ReadOnlyFileSystem) CreateTemp(dir string, pattern string) (FSFile, error) {
+	return nil, ErrReadOnlyFS
+}
+
+/*
+Mkdir refuses to create a directory.
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the path of the directory
+  - perm – the permissions of the directory
+
+– Returns:
+  - always ErrReadOnlyFS
+*/
+func (readOnlyFS ReadOnlyFileSystem) Mkdir(name string, perm os.FileMode) error {
+	return ErrReadOnlyFS
+}
+
+/*
+Remove refuses to remove a path.
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the path to remove
+
+– Returns:
+  - always ErrReadOnlyFS
+*/
+func (readOnlyFS ReadOnlyFileSystem) Remove(name string) error {
+	return ErrReadOnlyFS
+}
+
+/*
+Rename refuses to rename a path.
+
+-----------------------------------------------------------
+
+– Params:
+  - old_name – the path to rename
+  - new_name – the new path
+
+– Returns:
+  - always ErrReadOnlyFS
+*/
+func (readOnlyFS ReadOnlyFileSystem) Rename(old_name string, new_name string) error {
+	return ErrReadOnlyFS
+}
+
+/*
+Chmod refuses to change the permissions of a path.
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the path
+  - mode – the new permissions
+
+– Returns:
+  - always ErrReadOnlyFS
+*/
+func (readOnlyFS ReadOnlyFileSystem) Chmod(name string, mode os.FileMode) error {
+	return ErrReadOnlyFS
+}
+
+/*
+Chtimes refuses to change the times of a path.
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the path
+  - atime – the new access time
+  - mtime – the new modification time
+
+– Returns:
+  - always ErrReadOnlyFS
+*/
+func (readOnlyFS ReadOnlyFileSystem) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	return ErrReadOnlyFS
+}
+
+/*
+Readlink gets the target of a symbolic link of the wrapped file system.
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the path of the link
+
+– Returns:
+  - the target of the link
+  - nil if the target was got successfully, an error otherwise
+*/
+func (readOnlyFS ReadOnlyFileSystem) Readlink(name string) (string, error) {
+	return readOnlyFS.FS.Readlink(name)
+}
+
+/*
+Symlink refuses to create a symbolic link.
+
+-----------------------------------------------------------
+
+– Params:
+  - old_name – the target of the link
+  - new_name – the path of the link
+
+– Returns:
+  - always ErrReadOnlyFS
+*/
+func (readOnlyFS ReadOnlyFileSystem) Symlink(old_name string, new_name string) error {
+	return ErrReadOnlyFS
+}
+
+/*
+EvalSymlinks gets a path of the wrapped file system with its symbolic links resolved.
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the path
+
+– Returns:
+  - the resolved path
+  - nil if the path was resolved successfully, an error otherwise
+*/
+func (readOnlyFS ReadOnlyFileSystem) EvalSymlinks(name string) (string, error) {
+	return readOnlyFS.FS.EvalSymlinks(name)
+}
@@ -0,0 +1,191 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package Utils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestMemFileSystemRenameDir(t *testing.T) {
+	var memFS *MemFileSystem = NewMemFileSystemFILESDIRS()
+	var dir GPath = PathFILESDIRS(true, "", "/old").WithFS(memFS)
+	// Enough entries for the map to be reorganized while they're moved.
+	for i := 0; i < 200; i++ {
+		if err := dir.Add2(false, "sub", strconv.Itoa(i) + ".txt").WriteTextFile(strconv.Itoa(i)); nil != err {
+			t.Fatal(err)
+		}
+	}
+
+	if err := memFS.Rename("/old", "/new"); nil != err {
+		t.Fatal(err)
+	}
+	for i := 0; i < 200; i++ {
+		var p_contents *string = PathFILESDIRS(false, "", "/new/sub", strconv.Itoa(i) + ".txt").WithFS(memFS).
+			ReadTextFile()
+		if nil == p_contents || strconv.Itoa(i) != *p_contents {
+			t.Fatalf("file %d was not moved", i)
+		}
+	}
+	if _, err := memFS.Stat("/old"); !errors.Is(err, os.ErrNotExist) {
+		t.Error("the old directory still exists")
+	}
+	if err := memFS.Rename("/new", "/new/sub/inside"); nil == err {
+		t.Error("renaming a directory into itself didn't fail")
+	}
+}
+
+func TestMemFileSystemErrors(t *testing.T) {
+	var memFS *MemFileSystem = NewMemFileSystemFILESDIRS()
+	if err := mkdirAllFS(memFS, "/dir/sub", 0o777); nil != err {
+		t.Fatal(err)
+	}
+	if err := writeFileFS(memFS, "/dir/file", []byte("x"), 0o666); nil != err {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		name    string
+		do      func() error
+		wantErr error
+	}{
+		{"create without parent", func() error {
+			_, err := memFS.OpenFile("/none/file", os.O_WRONLY|os.O_CREATE, 0o666)
+			return err
+		}, os.ErrNotExist},
+		{"exclusive create of existing file", func() error {
+			_, err := memFS.OpenFile("/dir/file", os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o666)
+			return err
+		}, os.ErrExist},
+		{"mkdir existing", func() error { return memFS.Mkdir("/dir/sub", 0o777) }, os.ErrExist},
+		{"remove missing", func() error { return memFS.Remove("/dir/none") }, os.ErrNotExist},
+		{"remove non-empty directory", func() error { return memFS.Remove("/dir") }, nil},
+		{"rename onto directory", func() error { return memFS.Rename("/dir/file", "/dir/sub") }, os.ErrExist},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var err error = test.do()
+			if nil == err {
+				t.Fatal("no error")
+			}
+			if nil != test.wantErr && !errors.Is(err, test.wantErr) {
+				t.Errorf("got %v, want %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestReadOnlyFileSystem(t *testing.T) {
+	var memFS *MemFileSystem = NewMemFileSystemFILESDIRS()
+	if err := writeFileFS(memFS, "/file", []byte("x"), 0o666); nil != err {
+		t.Fatal(err)
+	}
+	var readOnlyFS ReadOnlyFileSystem = ReadOnlyFileSystem{FS: memFS}
+
+	if data, err := readFileFS(readOnlyFS, "/file"); nil != err || "x" != string(data) {
+		t.Errorf("read got %q, %v", data, err)
+	}
+	if err := writeFileFS(readOnlyFS, "/file", []byte("y"), 0o666); !errors.Is(err, ErrReadOnlyFS) {
+		t.Errorf("write got %v", err)
+	}
+	if err := readOnlyFS.Remove("/file"); !errors.Is(err, ErrReadOnlyFS) {
+		t.Errorf("remove got %v", err)
+	}
+}
+
+func TestOverlayFileSystem(t *testing.T) {
+	var base_dir string = t.TempDir()
+	if err := os.MkdirAll(filepath.Join(base_dir, "dir", "sub"), 0o777); nil != err {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.txt", "dir/b.txt", "dir/sub/c.txt"} {
+		if err := os.WriteFile(filepath.Join(base_dir, name), []byte("base " + name), 0o666); nil != err {
+			t.Fatal(err)
+		}
+	}
+	var overlayFS *OverlayFileSystem = NewOverlayFileSystemFILESDIRS(OsFileSystem{})
+	var root GPath = PathFILESDIRS(true, "", base_dir).WithFS(overlayFS)
+
+	if err := root.Add2(false, "a.txt").WriteTextFile("changed"); nil != err {
+		t.Fatal(err)
+	}
+	if err := root.Add2(false, "new.txt").WriteTextFile("new"); nil != err {
+		t.Fatal(err)
+	}
+	if err := overlayFS.Remove(filepath.Join(base_dir, "dir", "b.txt")); nil != err {
+		t.Fatal(err)
+	}
+	if err := overlayFS.Rename(filepath.Join(base_dir, "dir", "sub"), filepath.Join(base_dir, "moved")); nil != err {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		name      string
+		path      string
+		want      string
+		wantExist bool
+	}{
+		{"changed file", "a.txt", "changed", true},
+		{"created file", "new.txt", "new", true},
+		{"removed file", "dir/b.txt", "", false},
+		{"renamed directory contents", "moved/c.txt", "base dir/sub/c.txt", true},
+		{"renamed directory old contents", "dir/sub/c.txt", "", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := readFileFS(overlayFS, filepath.Join(base_dir, test.path))
+			if test.wantExist {
+				if nil != err || test.want != string(data) {
+					t.Errorf("got %q, %v, want %q", data, err, test.want)
+				}
+			} else if !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("got %v, want it to not exist", err)
+			}
+		})
+	}
+
+	entries, err := overlayFS.ReadDir(base_dir)
+	if nil != err {
+		t.Fatal(err)
+	}
+	var names []string = nil
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if want := []string{"a.txt", "dir", "moved", "new.txt"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("ReadDir() got %v, want %v", names, want)
+	}
+
+	// The base must not have been changed.
+	for _, name := range []string{"a.txt", "dir/b.txt", "dir/sub/c.txt"} {
+		if data, err := os.ReadFile(filepath.Join(base_dir, name)); nil != err || "base " + name != string(data) {
+			t.Errorf("base file %s got %q, %v", name, data, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(base_dir, "new.txt")); !errors.Is(err, os.ErrNotExist) {
+		t.Error("the created file reached the base")
+	}
+}
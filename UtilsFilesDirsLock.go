@@ -0,0 +1,282 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package Utils
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// LOCK_SHARED is the lock mode for readers - many processes can hold it at the same time.
+	LOCK_SHARED int = iota
+	// LOCK_EXCLUSIVE is the lock mode for writers - only one process can hold it, and only if nobody holds a shared one.
+	LOCK_EXCLUSIVE
+)
+
+// LOCK_FILE_EXT is the extension of the files used to lock the paths.
+const LOCK_FILE_EXT string = ".lock"
+
+// _LOCK_RETRY_INTERVAL is the time between attempts to get a lock when waiting with a timeout.
+const _LOCK_RETRY_INTERVAL time.Duration = 20 * time.Millisecond
+
+// ErrFileLocked is the error returned when a lock could not be got because another process (or another FileLock in the
+// same process) holds it.
+var ErrFileLocked error = errors.New("the path is locked by someone else")
+
+/*
+FileLock is an advisory cross-process lock on a path, got through GPath.Lock() or GPath.TryLock().
+
+The lock is held on a separate file with the same name as the path plus LOCK_FILE_EXT (so it's not lost when the path is
+replaced, as in GPath.WriteFileAtomic()). It's advisory, so it only protects the path from those who also lock it.
+
+It's backed by flock() on Unix-like systems and by LockFileEx() on Windows. On file systems other than the OS one and on
+the other OSes (like Solaris or AIX), there are no lock files and the lock only works inside the same process - it
+doesn't protect from other processes there.
+*/
+type FileLock struct {
+	// file is the opened lock file, or nil if the lock was already released or is not a lock file one.
+	file *os.File
+	// processLock is the in-process lock, or nil if the lock was already released or is not an in-process one.
+	processLock *_ProcessLock
+	// process_path is the path of the in-process lock, to remove it from process_locks_GL when nobody holds it anymore.
+	process_path string
+	// exclusive is true if the lock is exclusive, false if it's shared.
+	exclusive bool
+}
+
+// _ProcessLock is the state of an in-process lock (used for file systems other than the OS one).
+type _ProcessLock struct {
+	// readers is the number of shared locks held.
+	readers int
+	// writer is true if the exclusive lock is held.
+	writer bool
+}
+
+var (
+	// process_locks_mutex_GL protects process_locks_GL.
+	process_locks_mutex_GL sync.Mutex
+	// process_locks_GL is the map of the paths to their in-process locks, with only the ones being held.
+	process_locks_GL map[string]*_ProcessLock = map[string]*_ProcessLock{}
+)
+
+/*
+Lock locks the path, waiting for it to be available.
+
+-----------------------------------------------------------
+
+– Params:
+  - mode – LOCK_SHARED or LOCK_EXCLUSIVE
+  - timeout – the maximum time to wait for the lock or a negative value to wait forever
+
+– Returns:
+  - the lock, to be released with FileLock.Unlock(), or nil if an error occurred
+  - nil if the lock was got, ErrFileLocked if the timeout passed, another error otherwise
+*/
+func (gPath GPath) Lock(mode int, timeout time.Duration) (*FileLock, error) {
+	var exclusive bool = LOCK_EXCLUSIVE == mode
+	if !gPath.isOsFS() || !_FILE_LOCKS_SUPPORTED {
+		return lockInProcessFILESDIRS(gPath.p, exclusive, timeout)
+	}
+
+	file, err := gPath.openLockFile()
+	if nil != err {
+		return nil, err
+	}
+
+	if timeout < 0 {
+		err = lockFileFILESDIRS(file, exclusive, true)
+	} else {
+		var deadline time.Time = time.Now().Add(timeout)
+		for {
+			err = lockFileFILESDIRS(file, exclusive, false)
+			if ErrFileLocked != err || time.Now().After(deadline) {
+				break
+			}
+
+			time.Sleep(_LOCK_RETRY_INTERVAL)
+		}
+	}
+	if nil != err {
+		_ = file.Close()
+
+		return nil, err
+	}
+
+	return &FileLock{
+		file:      file,
+		exclusive: exclusive,
+	}, nil
+}
+
+/*
+TryLock locks the path only if it's available right away.
+
+-----------------------------------------------------------
+
+– Params:
+  - mode – LOCK_SHARED or LOCK_EXCLUSIVE
+
+– Returns:
+  - the lock, to be released with FileLock.Unlock(), or nil if an error occurred
+  - nil if the lock was got, ErrFileLocked if someone else holds it, another error otherwise
+*/
+func (gPath GPath) TryLock(mode int) (*FileLock, error) {
+	return gPath.Lock(mode, 0)
+}
+
+/*
+WithLock runs a function while holding a lock on the path, releasing it when the function returns (or panics).
+
+-----------------------------------------------------------
+
+– Params:
+  - mode – LOCK_SHARED or LOCK_EXCLUSIVE
+  - timeout – the maximum time to wait for the lock or a negative value to wait forever
+  - f – the function to run
+
+– Returns:
+  - the error returned by the function, or the error of GPath.Lock() if the lock was not got (and the function not run)
+*/
+func (gPath GPath) WithLock(mode int, timeout time.Duration, f func() error) error {
+	fileLock, err := gPath.Lock(mode, timeout)
+	if nil != err {
+		return err
+	}
+	defer fileLock.Unlock()
+
+	return f()
+}
+
+/*
+Unlock releases the lock. Calling it more than once does nothing.
+
+-----------------------------------------------------------
+
+– Returns:
+  - nil if the lock was released successfully, an error otherwise
+*/
+func (fileLock *FileLock) Unlock() error {
+	if nil != fileLock.processLock {
+		process_locks_mutex_GL.Lock()
+		if fileLock.exclusive {
+			fileLock.processLock.writer = false
+		} else {
+			fileLock.processLock.readers--
+		}
+		if !fileLock.processLock.writer && 0 == fileLock.processLock.readers {
+			delete(process_locks_GL, fileLock.process_path)
+		}
+		process_locks_mutex_GL.Unlock()
+		fileLock.processLock = nil
+
+		return nil
+	}
+	if nil == fileLock.file {
+		return nil
+	}
+
+	var err error = unlockFileFILESDIRS(fileLock.file)
+	if err_close := fileLock.file.Close(); nil == err {
+		err = err_close
+	}
+	fileLock.file = nil
+
+	return err
+}
+
+/*
+openLockFile opens (creating if necessary) the lock file of the path.
+
+The lock file is never removed, as removing it could let 2 processes lock different files for the same path.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the opened lock file
+  - nil if the file was opened successfully, an error otherwise
+*/
+func (gPath GPath) openLockFile() (*os.File, error) {
+	if err := gPath.IsSupported(); nil != err {
+		return nil, err
+	}
+
+	var lock_path GPath = gPath.Dir().Add2(false, gPath.Name() + LOCK_FILE_EXT)
+	// The lock file of a sandbox is next to it, out of it.
+	lock_path.sandbox = ""
+	if err := lock_path.Create(false); nil != err {
+		return nil, err
+	}
+
+	return os.OpenFile(lock_path.p, os.O_RDWR|os.O_CREATE, lock_path.getPerms().Files)
+}
+
+/*
+lockInProcessFILESDIRS locks a path inside the current process only, for file systems other than the OS one and OSes
+without file locks.
+
+-----------------------------------------------------------
+
+– Params:
+  - path – the path to lock
+  - exclusive – true for an exclusive lock, false for a shared one
+  - timeout – same as in GPath.Lock()
+
+– Returns:
+  - same as GPath.Lock()
+*/
+func lockInProcessFILESDIRS(path string, exclusive bool, timeout time.Duration) (*FileLock, error) {
+	var deadline time.Time = time.Now().Add(timeout)
+	for {
+		process_locks_mutex_GL.Lock()
+		processLock, ok := process_locks_GL[path]
+		if !ok {
+			processLock = &_ProcessLock{}
+			process_locks_GL[path] = processLock
+		}
+
+		var available bool = !processLock.writer && (!exclusive || 0 == processLock.readers)
+		if available {
+			if exclusive {
+				processLock.writer = true
+			} else {
+				processLock.readers++
+			}
+		}
+		process_locks_mutex_GL.Unlock()
+
+		if available {
+			return &FileLock{
+				processLock:  processLock,
+				process_path: path,
+				exclusive:    exclusive,
+			}, nil
+		}
+		if timeout >= 0 && time.Now().After(deadline) {
+			return nil, ErrFileLocked
+		}
+
+		time.Sleep(_LOCK_RETRY_INTERVAL)
+	}
+}
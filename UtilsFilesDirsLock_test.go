@@ -0,0 +1,193 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package Utils
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// lockTestPaths gets a path to lock with file locks (on the OS file system) and another with in-process locks.
+func lockTestPaths(t *testing.T) map[string]GPath {
+	return map[string]GPath{
+		"OS": PathFILESDIRS(true, "", t.TempDir()).Add2(false, "file.txt"),
+		"in-process": PathFILESDIRS(true, "", "/dir/file.txt").WithFS(NewMemFileSystemFILESDIRS()),
+	}
+}
+
+func TestLockModes(t *testing.T) {
+	var tests = []struct {
+		name       string
+		first      int
+		second     int
+		wantLocked bool
+	}{
+		{"shared and shared", LOCK_SHARED, LOCK_SHARED, false},
+		{"shared and exclusive", LOCK_SHARED, LOCK_EXCLUSIVE, true},
+		{"exclusive and shared", LOCK_EXCLUSIVE, LOCK_SHARED, true},
+		{"exclusive and exclusive", LOCK_EXCLUSIVE, LOCK_EXCLUSIVE, true},
+	}
+	for fs_name, gPath := range lockTestPaths(t) {
+		for _, test := range tests {
+			t.Run(fs_name + "/" + test.name, func(t *testing.T) {
+				first, err := gPath.TryLock(test.first)
+				if nil != err {
+					t.Fatal(err)
+				}
+
+				second, err := gPath.TryLock(test.second)
+				if test.wantLocked {
+					if !errors.Is(err, ErrFileLocked) {
+						t.Fatalf("got error %v, want ErrFileLocked", err)
+					}
+				} else {
+					if nil != err {
+						t.Fatal(err)
+					}
+					if err = second.Unlock(); nil != err {
+						t.Fatal(err)
+					}
+				}
+
+				if err = first.Unlock(); nil != err {
+					t.Fatal(err)
+				}
+				// Released, so available again - and releasing twice does nothing.
+				if err = first.Unlock(); nil != err {
+					t.Fatal(err)
+				}
+				again, err := gPath.TryLock(LOCK_EXCLUSIVE)
+				if nil != err {
+					t.Fatalf("not available after being released: %v", err)
+				}
+				_ = again.Unlock()
+			})
+		}
+	}
+}
+
+func TestLockTimeout(t *testing.T) {
+	for fs_name, gPath := range lockTestPaths(t) {
+		t.Run(fs_name, func(t *testing.T) {
+			held, err := gPath.TryLock(LOCK_EXCLUSIVE)
+			if nil != err {
+				t.Fatal(err)
+			}
+
+			var start time.Time = time.Now()
+			if _, err = gPath.Lock(LOCK_SHARED, 100 * time.Millisecond); !errors.Is(err, ErrFileLocked) {
+				t.Fatalf("got error %v, want ErrFileLocked", err)
+			}
+			if elapsed := time.Since(start); elapsed < 100 * time.Millisecond {
+				t.Errorf("gave up after %v, before the timeout", elapsed)
+			}
+
+			// Waiting forever gets it once released.
+			var locked chan error = make(chan error, 1)
+			go func() {
+				fileLock, err := gPath.Lock(LOCK_EXCLUSIVE, -1)
+				if nil == err {
+					err = fileLock.Unlock()
+				}
+				locked <- err
+			}()
+			time.Sleep(50 * time.Millisecond)
+			select {
+				case err = <-locked:
+					t.Fatalf("got the lock while held (error %v)", err)
+				default:
+			}
+			_ = held.Unlock()
+			select {
+				case err = <-locked:
+					if nil != err {
+						t.Fatal(err)
+					}
+				case <-time.After(5 * time.Second):
+					t.Fatal("didn't get the lock after it was released")
+			}
+		})
+	}
+}
+
+func TestWithLock(t *testing.T) {
+	for fs_name, gPath := range lockTestPaths(t) {
+		t.Run(fs_name, func(t *testing.T) {
+			var errTest error = errors.New("test")
+			var ran bool = false
+			if err := gPath.WithLock(LOCK_EXCLUSIVE, 0, func() error {
+				ran = true
+				if _, err := gPath.TryLock(LOCK_SHARED); !errors.Is(err, ErrFileLocked) {
+					t.Errorf("got error %v while held, want ErrFileLocked", err)
+				}
+
+				return errTest
+			}); errTest != err || !ran {
+				t.Fatalf("got error %v and ran %v, want the function's error", err, ran)
+			}
+
+			held, err := gPath.TryLock(LOCK_EXCLUSIVE)
+			if nil != err {
+				t.Fatalf("not released: %v", err)
+			}
+			ran = false
+			if err = gPath.WithLock(LOCK_SHARED, 0, func() error {
+				ran = true
+
+				return nil
+			}); !errors.Is(err, ErrFileLocked) || ran {
+				t.Errorf("got error %v and ran %v, want ErrFileLocked without running", err, ran)
+			}
+			_ = held.Unlock()
+		})
+	}
+}
+
+func TestLockInProcessPruned(t *testing.T) {
+	var gPath GPath = PathFILESDIRS(true, "", "/pruned/file.txt").WithFS(NewMemFileSystemFILESDIRS())
+
+	first, err := gPath.TryLock(LOCK_SHARED)
+	if nil != err {
+		t.Fatal(err)
+	}
+	second, err := gPath.TryLock(LOCK_SHARED)
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	var isKept = func() bool {
+		process_locks_mutex_GL.Lock()
+		defer process_locks_mutex_GL.Unlock()
+		_, ok := process_locks_GL[gPath.p]
+
+		return ok
+	}
+	_ = first.Unlock()
+	if !isKept() {
+		t.Error("removed while still held")
+	}
+	_ = second.Unlock()
+	if isKept() {
+		t.Error("kept after being released by all")
+	}
+}
@@ -0,0 +1,82 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+//go:build linux || darwin || freebsd || openbsd || netbsd || dragonfly
+
+package Utils
+
+import (
+	"os"
+	"syscall"
+)
+
+// _FILE_LOCKS_SUPPORTED is true if the OS supports locking files across processes.
+const _FILE_LOCKS_SUPPORTED bool = true
+
+/*
+lockFileFILESDIRS locks an opened file with flock().
+
+-----------------------------------------------------------
+
+– Params:
+  - file – the file to lock
+  - exclusive – true for an exclusive lock, false for a shared one
+  - wait – true to wait until the lock is available, false to return right away
+
+– Returns:
+  - nil if the file was locked, ErrFileLocked if it's locked by someone else and wait is false, another error otherwise
+*/
+func lockFileFILESDIRS(file *os.File, exclusive bool, wait bool) error {
+	var how int = syscall.LOCK_SH
+	if exclusive {
+		how = syscall.LOCK_EX
+	}
+	if !wait {
+		how |= syscall.LOCK_NB
+	}
+
+	for {
+		var err error = syscall.Flock(int(file.Fd()), how)
+		if syscall.EINTR == err {
+			continue
+		}
+		if syscall.EWOULDBLOCK == err {
+			return ErrFileLocked
+		}
+
+		return err
+	}
+}
+
+/*
+unlockFileFILESDIRS unlocks a file locked with lockFileFILESDIRS().
+
+-----------------------------------------------------------
+
+– Params:
+  - file – the file to unlock
+
+– Returns:
+  - nil if the file was unlocked, an error otherwise
+*/
+func unlockFileFILESDIRS(file *os.File) error {
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+}
@@ -0,0 +1,102 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+//go:build windows
+
+package Utils
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// _FILE_LOCKS_SUPPORTED is true if the OS supports locking files across processes.
+const _FILE_LOCKS_SUPPORTED bool = true
+
+const (
+	_LOCKFILE_FAIL_IMMEDIATELY uintptr = 0x00000001
+	_LOCKFILE_EXCLUSIVE_LOCK   uintptr = 0x00000002
+
+	_ERROR_LOCK_VIOLATION syscall.Errno = 33
+)
+
+var (
+	modkernel32      = syscall.NewLazyDLL("kernel32.dll")
+	procLockFileEx   = modkernel32.NewProc("LockFileEx")
+	procUnlockFileEx = modkernel32.NewProc("UnlockFileEx")
+)
+
+/*
+lockFileFILESDIRS locks an opened file with LockFileEx() (the first byte only, which is enough, as all lockers lock the
+same byte).
+
+-----------------------------------------------------------
+
+– Params:
+  - file – the file to lock
+  - exclusive – true for an exclusive lock, false for a shared one
+  - wait – true to wait until the lock is available, false to return right away
+
+– Returns:
+  - nil if the file was locked, ErrFileLocked if it's locked by someone else and wait is false, another error otherwise
+*/
+func lockFileFILESDIRS(file *os.File, exclusive bool, wait bool) error {
+	var flags uintptr = 0
+	if exclusive {
+		flags |= _LOCKFILE_EXCLUSIVE_LOCK
+	}
+	if !wait {
+		flags |= _LOCKFILE_FAIL_IMMEDIATELY
+	}
+
+	var overlapped syscall.Overlapped
+	ret, _, err := procLockFileEx.Call(file.Fd(), flags, 0, 1, 0, uintptr(unsafe.Pointer(&overlapped)))
+	if 0 != ret {
+		return nil
+	}
+	if _ERROR_LOCK_VIOLATION == err {
+		return ErrFileLocked
+	}
+
+	return err
+}
+
+/*
+unlockFileFILESDIRS unlocks a file locked with lockFileFILESDIRS().
+
+-----------------------------------------------------------
+
+– Params:
+  - file – the file to unlock
+
+– Returns:
+  - nil if the file was unlocked, an error otherwise
+*/
+func unlockFileFILESDIRS(file *os.File) error {
+	var overlapped syscall.Overlapped
+	ret, _, err := procUnlockFileEx.Call(file.Fd(), 0, 1, 0, uintptr(unsafe.Pointer(&overlapped)))
+	if 0 != ret {
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,322 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package Utils
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"sort"
+	"strings"
+)
+
+// MANIFEST_FILE_EXT is the usual extension of manifest files.
+const MANIFEST_FILE_EXT string = ".sha512"
+
+// ManifestOptions is the options for GPath.CreateManifest(), GPath.WriteManifest() and GPath.VerifyManifest().
+type ManifestOptions struct {
+	// Exclude is the patterns (as in GPath.Glob(), relative to the directory) of the paths to leave out. A directory
+	// that matches leaves out all its contents.
+	Exclude []string
+
+	// manifest_rel_path is the relative path of the manifest file if it's inside the directory, to leave it out.
+	manifest_rel_path string
+}
+
+// ManifestDiff is the differences between a directory and its manifest, as relative paths with "/" as the separator.
+type ManifestDiff struct {
+	// Added is the files in the directory but not in the manifest.
+	Added []string
+	// Removed is the files in the manifest but not in the directory.
+	Removed []string
+	// Modified is the files whose contents don't match the manifest.
+	Modified []string
+}
+
+/*
+GetSha512 gets the SHA-512 hash of a file, reading it in chunks so that large files don't need to fit in memory.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the hash as a lowercase hexadecimal string
+  - nil if the hash was got successfully, an error otherwise
+*/
+func (gPath GPath) GetSha512() (string, error) {
+	file, err := gPath.Open()
+	if nil != err {
+		return "", err
+	}
+	defer file.Close()
+
+	var hasher hash.Hash = sha512.New()
+	if _, err = io.Copy(hasher, file); nil != err {
+		return "", err
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+/*
+CreateManifest hashes all the files inside the directory (recursively) with SHA-512.
+
+-----------------------------------------------------------
+
+– Params:
+  - options – the options for the manifest or nil for the default ones
+
+– Returns:
+  - the manifest: the hashes of the files by their relative paths (with "/" as the separator)
+  - nil if the manifest was created successfully, an error otherwise
+*/
+func (gPath GPath) CreateManifest(options *ManifestOptions) (map[string]string, error) {
+	var manifestOptions ManifestOptions = ManifestOptions{}
+	if nil != options {
+		manifestOptions = *options
+	}
+
+	var manifest map[string]string = map[string]string{}
+	var err_hash error = nil
+	var err error = gPath.Walk(nil, func(found GPath, rel_path string) int {
+		if !isPathWantedFILESDIRS(rel_path, nil, manifestOptions.Exclude) {
+			return WALK_SKIP_DIR
+		}
+		if rel_path == manifestOptions.manifest_rel_path {
+			return WALK_CONTINUE
+		}
+		if found.dir {
+			return WALK_CONTINUE
+		}
+		if strings.ContainsAny(rel_path, "\r\n") {
+			err_hash = errors.New("file name with a line break not supported: \"" + rel_path + "\"")
+
+			return WALK_STOP
+		}
+
+		var hash_str string
+		if hash_str, err_hash = found.GetSha512(); nil != err_hash {
+			return WALK_STOP
+		}
+		manifest[rel_path] = hash_str
+
+		return WALK_CONTINUE
+	})
+	if nil != err_hash {
+		return nil, err_hash
+	}
+	if nil != err {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+/*
+WriteManifest creates the manifest of the directory (as in CreateManifest()) and writes it to a file (atomically), in
+the format of the "sha512sum" command, sorted by path.
+
+If the manifest file is inside the directory, it's left out of the manifest.
+
+-----------------------------------------------------------
+
+– Params:
+  - manifest_path – the path of the manifest file
+  - options – the options for the manifest or nil for the default ones
+
+– Returns:
+  - nil if the manifest was written successfully, an error otherwise
+*/
+func (gPath GPath) WriteManifest(manifest_path GPath, options *ManifestOptions) error {
+	manifest, err := gPath.CreateManifest(gPath.getManifestOptions(manifest_path, options))
+	if nil != err {
+		return err
+	}
+
+	var rel_paths []string = make([]string, 0, len(manifest))
+	for rel_path := range manifest {
+		rel_paths = append(rel_paths, rel_path)
+	}
+	sort.Strings(rel_paths)
+
+	var builder strings.Builder
+	for _, rel_path := range rel_paths {
+		builder.WriteString(manifest[rel_path] + "  " + rel_path + "\n")
+	}
+
+	// Written with "\n" on all OSes, as "sha512sum" does.
+	return manifest_path.WriteFileAtomic([]byte(builder.String()), nil)
+}
+
+/*
+VerifyManifest checks the files inside the directory against a manifest file written by WriteManifest() (or by the
+"sha512sum" command, with the paths relative to the directory).
+
+-----------------------------------------------------------
+
+– Params:
+  - manifest_path – the path of the manifest file
+  - options – the options used to write the manifest or nil for the default ones
+
+– Returns:
+  - the differences found
+  - nil if the verification was done, an error otherwise (differences are not errors)
+*/
+func (gPath GPath) VerifyManifest(manifest_path GPath, options *ManifestOptions) (ManifestDiff, error) {
+	var manifestDiff ManifestDiff = ManifestDiff{}
+
+	expected, err := ReadManifestFILESDIRS(manifest_path)
+	if nil != err {
+		return manifestDiff, err
+	}
+	actual, err := gPath.CreateManifest(gPath.getManifestOptions(manifest_path, options))
+	if nil != err {
+		return manifestDiff, err
+	}
+
+	for rel_path, hash_str := range actual {
+		expected_hash, ok := expected[rel_path]
+		if !ok {
+			manifestDiff.Added = append(manifestDiff.Added, rel_path)
+		} else if !strings.EqualFold(hash_str, expected_hash) {
+			manifestDiff.Modified = append(manifestDiff.Modified, rel_path)
+		}
+	}
+	for rel_path := range expected {
+		if _, ok := actual[rel_path]; !ok {
+			manifestDiff.Removed = append(manifestDiff.Removed, rel_path)
+		}
+	}
+	sort.Strings(manifestDiff.Added)
+	sort.Strings(manifestDiff.Removed)
+	sort.Strings(manifestDiff.Modified)
+
+	return manifestDiff, nil
+}
+
+/*
+ReadManifestFILESDIRS reads a manifest file in the format of the "sha512sum" command.
+
+-----------------------------------------------------------
+
+– Params:
+  - manifest_path – the path of the manifest file
+
+– Returns:
+  - the manifest: the hashes of the files by their relative paths (with "/" as the separator)
+  - nil if the manifest was read successfully, an error otherwise (including a malformed line)
+*/
+func ReadManifestFILESDIRS(manifest_path GPath) (map[string]string, error) {
+	lineIterator, err := manifest_path.Lines(0)
+	if nil != err {
+		return nil, err
+	}
+	defer lineIterator.Close()
+
+	var manifest map[string]string = map[string]string{}
+	for lineIterator.Next() {
+		var line string = lineIterator.Line()
+		if "" == line {
+			continue
+		}
+
+		// "sha512sum" separates with 2 spaces, or with " *" in binary mode.
+		var idx int = strings.Index(line, " ")
+		if idx <= 0 || len(line) < idx + 2 || (' ' != line[idx + 1] && '*' != line[idx + 1]) {
+			return nil, errors.New("malformed manifest line: \"" + line + "\"")
+		}
+		if hash_bytes, err := hex.DecodeString(line[:idx]); nil != err || sha512.Size != len(hash_bytes) {
+			return nil, errors.New("invalid SHA-512 hash in the manifest line: \"" + line + "\"")
+		}
+		if len(line) == idx + 2 {
+			return nil, errors.New("missing file name in the manifest line: \"" + line + "\"")
+		}
+		manifest[strings.ReplaceAll(line[idx + 2:], "\\", "/")] = line[:idx]
+	}
+
+	return manifest, lineIterator.Err()
+}
+
+/*
+IsEmpty checks if there are no differences.
+
+-----------------------------------------------------------
+
+– Returns:
+  - true if the directory matches the manifest, false otherwise
+*/
+func (manifestDiff ManifestDiff) IsEmpty() bool {
+	return 0 == len(manifestDiff.Added) && 0 == len(manifestDiff.Removed) && 0 == len(manifestDiff.Modified)
+}
+
+/*
+String gets a description of the differences, one file per line.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the description, or "" if there are no differences
+*/
+func (manifestDiff ManifestDiff) String() string {
+	var builder strings.Builder
+	for _, rel_path := range manifestDiff.Added {
+		builder.WriteString("added: " + rel_path + "\n")
+	}
+	for _, rel_path := range manifestDiff.Removed {
+		builder.WriteString("removed: " + rel_path + "\n")
+	}
+	for _, rel_path := range manifestDiff.Modified {
+		builder.WriteString("modified: " + rel_path + "\n")
+	}
+
+	return builder.String()
+}
+
+/*
+getManifestOptions gets the options for the manifest of the directory, leaving the manifest file out if it's inside it.
+
+-----------------------------------------------------------
+
+– Params:
+  - manifest_path – the path of the manifest file
+  - options – the options given or nil
+
+– Returns:
+  - the options to use
+*/
+func (gPath GPath) getManifestOptions(manifest_path GPath, options *ManifestOptions) *ManifestOptions {
+	var manifestOptions ManifestOptions = ManifestOptions{}
+	if nil != options {
+		manifestOptions = *options
+	}
+
+	var dir_path string = gPath.p
+	if !strings.HasSuffix(dir_path, gPath.s) {
+		dir_path += gPath.s
+	}
+	if strings.HasPrefix(manifest_path.p, dir_path) {
+		manifestOptions.manifest_rel_path = strings.ReplaceAll(strings.TrimPrefix(manifest_path.p, dir_path), gPath.s, "/")
+	}
+
+	return &manifestOptions
+}
@@ -0,0 +1,129 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package Utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// _SHA512_ABC is the SHA-512 hash of "abc".
+const _SHA512_ABC string = "ddaf35a193617abacc417349ae20413112e6fa4e89a97ea20a9eeee64b55d39a" +
+	"2192992a274fc1a836ba3c23a3feebbd454d4423643ce80e2a9ac94fa54ca49f"
+
+func TestReadManifest(t *testing.T) {
+	var tests = []struct {
+		name     string
+		contents string
+		want     map[string]string
+		wantErr  bool
+	}{
+		{"text mode", _SHA512_ABC + "  dir/file.txt\n", map[string]string{"dir/file.txt": _SHA512_ABC}, false},
+		{"binary mode", _SHA512_ABC + " *file.bin\n", map[string]string{"file.bin": _SHA512_ABC}, false},
+		{"backslashes", _SHA512_ABC + "  dir\\file.txt\n", map[string]string{"dir/file.txt": _SHA512_ABC}, false},
+		{"spaces in name", _SHA512_ABC + "  a  b.txt\n", map[string]string{"a  b.txt": _SHA512_ABC}, false},
+		{"CRLF and empty lines", "\r\n" + _SHA512_ABC + "  a.txt\r\n\r\n",
+			map[string]string{"a.txt": _SHA512_ABC}, false},
+		{"empty", "", map[string]string{}, false},
+		{"one space", _SHA512_ABC + " a.txt\n", nil, true},
+		{"no separator", _SHA512_ABC + "\n", nil, true},
+		{"no name", _SHA512_ABC + "  \n", nil, true},
+		{"short hash", "abc123  a.txt\n", nil, true},
+		{"not hex", strings.Repeat("z", 128) + "  a.txt\n", nil, true},
+		{"leading space", " " + _SHA512_ABC + "  a.txt\n", nil, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var manifest_path GPath = PathFILESDIRS(false, "", "/m.sha512").WithFS(NewMemFileSystemFILESDIRS())
+			if err := manifest_path.WriteFile([]byte(test.contents)); nil != err {
+				t.Fatal(err)
+			}
+
+			manifest, err := ReadManifestFILESDIRS(manifest_path)
+			if (nil != err) != test.wantErr {
+				t.Fatalf("got error %v, want error %v", err, test.wantErr)
+			}
+			if !test.wantErr && !reflect.DeepEqual(manifest, test.want) {
+				t.Errorf("got %v, want %v", manifest, test.want)
+			}
+		})
+	}
+}
+
+func TestVerifyManifest(t *testing.T) {
+	var tests = []struct {
+		name   string
+		change func(dir GPath) error
+		want   ManifestDiff
+	}{
+		{"unchanged", func(dir GPath) error { return nil }, ManifestDiff{}},
+		{"added", func(dir GPath) error {
+			return dir.Add2(false, "new.txt").WriteTextFile("new")
+		}, ManifestDiff{Added: []string{"new.txt"}}},
+		{"removed", func(dir GPath) error {
+			_, err := dir.Add2(false, "sub", "b.txt").RemoveAll(nil)
+			return err
+		}, ManifestDiff{Removed: []string{"sub/b.txt"}}},
+		{"modified", func(dir GPath) error {
+			return dir.Add2(false, "a.txt").WriteTextFile("changed")
+		}, ManifestDiff{Modified: []string{"a.txt"}}},
+		{"excluded", func(dir GPath) error {
+			return dir.Add2(false, "skip", "c.txt").WriteTextFile("changed")
+		}, ManifestDiff{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var dir GPath = PathFILESDIRS(true, "", "/dir").WithFS(NewMemFileSystemFILESDIRS())
+			for name, contents := range map[string]string{"a.txt": "abc", "sub/b.txt": "b", "skip/c.txt": "c"} {
+				if err := dir.Add2(false, name).WriteTextFile(contents); nil != err {
+					t.Fatal(err)
+				}
+			}
+			var options *ManifestOptions = &ManifestOptions{Exclude: []string{"skip"}}
+			// Inside the directory, so it must leave itself out.
+			var manifest_path GPath = dir.Add2(false, "manifest" + MANIFEST_FILE_EXT)
+			if err := dir.WriteManifest(manifest_path, options); nil != err {
+				t.Fatal(err)
+			}
+
+			manifest, err := ReadManifestFILESDIRS(manifest_path)
+			if nil != err {
+				t.Fatal(err)
+			}
+			if _SHA512_ABC != manifest["a.txt"] || 2 != len(manifest) {
+				t.Fatalf("wrong manifest: %v", manifest)
+			}
+
+			if err = test.change(dir); nil != err {
+				t.Fatal(err)
+			}
+			manifestDiff, err := dir.VerifyManifest(manifest_path, options)
+			if nil != err {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(manifestDiff, test.want) {
+				t.Errorf("got %+v, want %+v", manifestDiff, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package Utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+)
+
+// _GPathJSON is the JSON format of a GPath.
+type _GPathJSON struct {
+	// Path is the path string.
+	Path string
+	// Separator is the path separator of the path.
+	Separator string
+	// Dir is true if the path describes a directory, false if it describes a file.
+	Dir bool
+}
+
+/*
+MarshalJSON implements json.Marshaler, writing the path as an object with the path string, the separator and the
+directory flag.
+
+Note: the file system, permissions policy and sandbox of the path are not kept.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the JSON of the path
+  - always nil
+*/
+func (gPath GPath) MarshalJSON() ([]byte, error) {
+	return json.Marshal(_GPathJSON{
+		Path:      gPath.p,
+		Separator: gPath.s,
+		Dir:       gPath.dir,
+	})
+}
+
+/*
+UnmarshalJSON implements json.Unmarshaler, reading a path written by MarshalJSON() or a plain JSON string (handled as in
+UnmarshalText()). The path is validated and corrected as in PathFILESDIRS().
+
+-----------------------------------------------------------
+
+– Params:
+  - data – the JSON of the path
+
+– Returns:
+  - nil if the path was read successfully, an error otherwise (including if the path is invalid)
+*/
+func (gPath *GPath) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("\"")) {
+		var path string
+		if err := json.Unmarshal(data, &path); nil != err {
+			return err
+		}
+
+		return gPath.UnmarshalText([]byte(path))
+	}
+
+	var gPathJSON _GPathJSON
+	if err := json.Unmarshal(data, &gPathJSON); nil != err {
+		return err
+	}
+
+	loaded, err := loadGPathFILESDIRS(gPathJSON)
+	if nil != err {
+		return err
+	}
+	*gPath = loaded
+
+	return nil
+}
+
+/*
+MarshalText implements encoding.TextMarshaler, writing the path string. Directories end in the separator, as in the
+project convention, so the directory flag is kept.
+
+Note: the file system, permissions policy and sandbox of the path are not kept.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the path string
+  - always nil
+*/
+func (gPath GPath) MarshalText() ([]byte, error) {
+	return []byte(gPath.p), nil
+}
+
+/*
+UnmarshalText implements encoding.TextUnmarshaler, reading a path string. The separator is the one used in the string
+(or the OS one if there's none) and the path describes a directory if it ends in it. The path is validated and
+corrected as in PathFILESDIRS().
+
+-----------------------------------------------------------
+
+– Params:
+  - text – the path string
+
+– Returns:
+  - nil if the path was read successfully, an error otherwise (including if the path is invalid)
+*/
+func (gPath *GPath) UnmarshalText(text []byte) error {
+	var gPathJSON _GPathJSON = _GPathJSON{
+		Path:      string(text),
+		Separator: string(os.PathSeparator),
+	}
+	if strings.Contains(gPathJSON.Path, "\\") && !strings.Contains(gPathJSON.Path, "/") {
+		gPathJSON.Separator = "\\"
+	} else if strings.Contains(gPathJSON.Path, "/") {
+		gPathJSON.Separator = "/"
+	}
+	gPathJSON.Dir = strings.HasSuffix(gPathJSON.Path, gPathJSON.Separator)
+
+	loaded, err := loadGPathFILESDIRS(gPathJSON)
+	if nil != err {
+		return err
+	}
+	*gPath = loaded
+
+	return nil
+}
+
+/*
+loadGPathFILESDIRS creates a GPath from its serialized parts, validating them.
+
+-----------------------------------------------------------
+
+– Params:
+  - gPathJSON – the parts of the path
+
+– Returns:
+  - the path (the zero GPath if the path string is empty)
+  - nil if the path is valid, an error otherwise
+*/
+func loadGPathFILESDIRS(gPathJSON _GPathJSON) (GPath, error) {
+	if "" == gPathJSON.Path {
+		return GPath{}, nil
+	}
+
+	if "/" != gPathJSON.Separator && "\\" != gPathJSON.Separator {
+		return GPath{}, errors.New("invalid path separator: \"" + gPathJSON.Separator + "\"")
+	}
+	if strings.ContainsRune(gPathJSON.Path, 0) {
+		return GPath{}, errors.New("invalid path: it contains a NUL character")
+	}
+
+	var gPath GPath = PathFILESDIRS(gPathJSON.Dir, gPathJSON.Separator, gPathJSON.Path)
+	if err := gPath.IsSupported(); nil != err {
+		return GPath{}, err
+	}
+
+	// The saved flag wins over what's on the disk now.
+	gPath.dir = gPathJSON.Dir
+	if gPath.dir && !strings.HasSuffix(gPath.p, gPath.s) {
+		gPath.p += gPath.s
+	} else if !gPath.dir && len(gPath.p) > len(gPath.s) {
+		gPath.p = strings.TrimSuffix(gPath.p, gPath.s)
+	}
+
+	return gPath, nil
+}
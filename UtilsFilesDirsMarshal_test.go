@@ -0,0 +1,163 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package Utils
+
+import (
+	"encoding/json"
+	"runtime"
+	"testing"
+)
+
+// checkTestGPath checks the path string, separator and directory flag of a path.
+func checkTestGPath(t *testing.T, gPath GPath, want_p string, want_s string, want_dir bool) {
+	t.Helper()
+	if want_p != gPath.p || want_s != gPath.s || want_dir != gPath.dir {
+		t.Errorf("got %q, %q, %v, want %q, %q, %v", gPath.p, gPath.s, gPath.dir, want_p, want_s, want_dir)
+	}
+}
+
+func TestMarshalJSONRoundTrip(t *testing.T) {
+	var tests = []struct {
+		name     string
+		gPath    GPath
+		wantJSON string
+	}{
+		{"directory", PathFILESDIRS(true, "/", "/a/b"), `{"Path":"/a/b/","Separator":"/","Dir":true}`},
+		{"file", PathFILESDIRS(false, "/", "/a/b.txt"), `{"Path":"/a/b.txt","Separator":"/","Dir":false}`},
+		{"relative with backslashes", PathFILESDIRS(false, "\\", "a\\b.txt"),
+			`{"Path":"a\\b.txt","Separator":"\\","Dir":false}`},
+		{"empty", GPath{}, `{"Path":"","Separator":"","Dir":false}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := json.Marshal(test.gPath)
+			if nil != err {
+				t.Fatal(err)
+			}
+			if test.wantJSON != string(data) {
+				t.Errorf("marshalled %s, want %s", data, test.wantJSON)
+			}
+
+			var gPath GPath
+			if err = json.Unmarshal(data, &gPath); nil != err {
+				t.Fatal(err)
+			}
+			checkTestGPath(t, gPath, test.gPath.p, test.gPath.s, test.gPath.dir)
+		})
+	}
+}
+
+func TestUnmarshalJSONString(t *testing.T) {
+	var tests = []struct {
+		name    string
+		json    string
+		want_p  string
+		want_s  string
+		wantDir bool
+	}{
+		{"file", `"/a/b.txt"`, "/a/b.txt", "/", false},
+		{"directory", `"/a/b/"`, "/a/b/", "/", true},
+		{"backslashes", `"a\\b\\"`, "a\\b\\", "\\", true},
+		{"in a struct", `{"Dir": "rel/dir/"}`, "rel/dir/", "/", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var gPath GPath
+			if '{' == test.json[0] {
+				var wrapper struct {
+					Dir GPath
+				}
+				if err := json.Unmarshal([]byte(test.json), &wrapper); nil != err {
+					t.Fatal(err)
+				}
+				gPath = wrapper.Dir
+			} else if err := json.Unmarshal([]byte(test.json), &gPath); nil != err {
+				t.Fatal(err)
+			}
+			checkTestGPath(t, gPath, test.want_p, test.want_s, test.wantDir)
+		})
+	}
+}
+
+func TestMarshalTextRoundTrip(t *testing.T) {
+	var tests = []struct {
+		name     string
+		gPath    GPath
+		wantText string
+	}{
+		{"directory", PathFILESDIRS(true, "/", "/a/b"), "/a/b/"},
+		{"file", PathFILESDIRS(false, "/", "/a/b.txt"), "/a/b.txt"},
+		{"backslashes", PathFILESDIRS(true, "\\", "a\\b"), "a\\b\\"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			text, err := test.gPath.MarshalText()
+			if nil != err {
+				t.Fatal(err)
+			}
+			if test.wantText != string(text) {
+				t.Errorf("marshalled %q, want %q", text, test.wantText)
+			}
+
+			var gPath GPath
+			if err = gPath.UnmarshalText(text); nil != err {
+				t.Fatal(err)
+			}
+			checkTestGPath(t, gPath, test.gPath.p, test.gPath.s, test.gPath.dir)
+		})
+	}
+
+	// As map keys, the text form is used.
+	data, err := json.Marshal(map[GPath]int{PathFILESDIRS(false, "/", "/a/b.txt"): 1})
+	if nil != err || `{"/a/b.txt":1}` != string(data) {
+		t.Errorf("map marshalled %s (error %v)", data, err)
+	}
+}
+
+func TestUnmarshalRejected(t *testing.T) {
+	var unsupported string = "C:\\\\dir\\\\file"
+	if "windows" == runtime.GOOS {
+		unsupported = "/dir/file"
+	}
+
+	var tests = []struct {
+		name string
+		json string
+	}{
+		{"bad separator", `{"Path":"/a/b","Separator":":","Dir":false}`},
+		{"empty separator", `{"Path":"/a/b","Separator":"","Dir":false}`},
+		{"embedded NUL", `{"Path":"/a/b\u0000c","Separator":"/","Dir":false}`},
+		{"embedded NUL in a string", `"/a/b\u0000c"`},
+		{"unsupported path", `{"Path":"` + unsupported + `","Separator":"` + `\\` + `","Dir":false}`},
+		{"unsupported path in a string", `"` + unsupported + `"`},
+		{"not a path", `42`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var gPath GPath = PathFILESDIRS(false, "/", "/previous")
+			if err := json.Unmarshal([]byte(test.json), &gPath); nil == err {
+				t.Errorf("unmarshalled to %q, want an error", gPath.p)
+			}
+			checkTestGPath(t, gPath, "/previous", "/", false)
+		})
+	}
+}
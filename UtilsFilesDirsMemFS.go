@@ -0,0 +1,830 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package Utils
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+MemFileSystem is a FileSystem kept entirely in memory, for tests. Get one with NewMemFileSystemFILESDIRS().
+
+Symbolic links are not supported. Locks on its paths only work inside the same process.
+*/
+type MemFileSystem struct {
+	// mutex protects all the nodes.
+	mutex sync.Mutex
+	// nodes is the map of the cleaned paths to their nodes (without the root ones, which always exist).
+	nodes map[string]*_MemNode
+}
+
+// _MemNode is a file or directory of a MemFileSystem.
+type _MemNode struct {
+	// is_dir is true if the node is a directory, false if it's a file.
+	is_dir bool
+	// data is the contents of the file.
+	data []byte
+	// mode is the permissions of the node.
+	mode os.FileMode
+	// mod_time is the modification time of the node.
+	mod_time time.Time
+}
+
+// _MemFileInfo is the os.FileInfo of a _MemNode.
+type _MemFileInfo struct {
+	name     string
+	size     int64
+	mode     os.FileMode
+	mod_time time.Time
+}
+
+// _MemFile is an opened file of a MemFileSystem.
+type _MemFile struct {
+	memFS  *MemFileSystem
+	name   string
+	node   *_MemNode
+	flag   int
+	offset int64
+	closed bool
+}
+
+/*
+NewMemFileSystemFILESDIRS creates a new empty in-memory file system.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the new file system
+*/
+func NewMemFileSystemFILESDIRS() *MemFileSystem {
+	return &MemFileSystem{
+		nodes: map[string]*_MemNode{},
+	}
+}
+
+/*
+Stat gets information about a path (the same as Lstat(), as there are no symbolic links).
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the path
+
+– Returns:
+  - the information about the path
+  - nil if the information was got successfully, an error otherwise
+*/
+func (memFS *MemFileSystem) Stat(name string) (os.FileInfo, error) {
+	return memFS.Lstat(name)
+}
+
+/*
+Lstat gets information about a path.
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the path
+
+– Returns:
+  - the information about the path
+  - nil if the information was got successfully, an error otherwise
+*/
+func (memFS *MemFileSystem) Lstat(name string) (os.FileInfo, error) {
+	memFS.mutex.Lock()
+	defer memFS.mutex.Unlock()
+
+	node, err := memFS.getNode("stat", name)
+	if nil != err {
+		return nil, err
+	}
+
+	return newMemFileInfoFILESDIRS(name, node), nil
+}
+
+/*
+ReadDir reads the contents of a directory.
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the path of the directory
+
+– Returns:
+  - the entries of the directory, sorted by name
+  - nil if the directory was read successfully, an error otherwise
+*/
+func (memFS *MemFileSystem) ReadDir(name string) ([]os.DirEntry, error) {
+	memFS.mutex.Lock()
+	defer memFS.mutex.Unlock()
+
+	node, err := memFS.getNode("readdirent", name)
+	if nil != err {
+		return nil, err
+	}
+	if !node.is_dir {
+		return nil, &os.PathError{Op: "readdirent", Path: name, Err: errors.New("not a directory")}
+	}
+
+	var dir string = cleanMemPathFILESDIRS(name)
+	var entries []os.DirEntry = nil
+	for path, child := range memFS.nodes {
+		if path != dir && filepath.Dir(path) == dir {
+			entries = append(entries, fs.FileInfoToDirEntry(newMemFileInfoFILESDIRS(path, child)))
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+
+	return entries, nil
+}
+
+/*
+OpenFile opens a file, creating it if asked and its parent directory exists.
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the path of the file
+  - flag – the os.O_* flags to open the file with
+  - perm – the permissions of the file if it's created
+
+– Returns:
+  - the opened file
+  - nil if the file was opened successfully, an error otherwise
+*/
+func (memFS *MemFileSystem) OpenFile(name string, flag int, perm os.FileMode) (FSFile, error) {
+	memFS.mutex.Lock()
+	defer memFS.mutex.Unlock()
+
+	var path string = cleanMemPathFILESDIRS(name)
+	node, err := memFS.getNode("open", name)
+	if nil == err {
+		if 0 != flag & os.O_CREATE && 0 != flag & os.O_EXCL {
+			return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrExist}
+		}
+		if node.is_dir && 0 != flag & (os.O_WRONLY | os.O_RDWR) {
+			return nil, &os.PathError{Op: "open", Path: name, Err: errors.New("is a directory")}
+		}
+		if 0 != flag & os.O_TRUNC {
+			node.data = nil
+			node.mod_time = time.Now()
+		}
+	} else {
+		if 0 == flag & os.O_CREATE {
+			return nil, err
+		}
+		if err = memFS.checkParent("open", name); nil != err {
+			return nil, err
+		}
+
+		node = &_MemNode{
+			is_dir:   false,
+			data:     nil,
+			mode:     perm.Perm(),
+			mod_time: time.Now(),
+		}
+		memFS.nodes[path] = node
+	}
+
+	return &_MemFile{
+		memFS:  memFS,
+		name:   name,
+		node:   node,
+		flag:   flag,
+		offset: 0,
+		closed: false,
+	}, nil
+}
+
+/*
+CreateTemp creates a new temporary file in a directory.
+
+-----------------------------------------------------------
+
+– Params:
+  - dir – the directory to create the file in
+  - pattern – the name of the file, with the last "*" replaced by a random string (or with it appended if there's none)
+
+– Returns:
+  - the opened file
+  - nil if the file was created successfully, an error otherwise
+*/
+func (memFS *MemFileSystem) CreateTemp(dir string, pattern string) (FSFile, error) {
+	var prefix string = pattern
+	var suffix string = ""
+	if idx := strings.LastIndex(pattern, "*"); -1 != idx {
+		prefix = pattern[:idx]
+		suffix = pattern[idx+1:]
+	}
+
+	var err error = nil
+	for i := 0; i < _TEMP_NAME_MAX_TRIES; i++ {
+		var file FSFile = nil
+		file, err = memFS.OpenFile(filepath.Join(dir, prefix + RandStringGENERAL(RAND_STR_LEN) + suffix),
+			os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o600)
+		if !errors.Is(err, os.ErrExist) {
+			return file, err
+		}
+	}
+
+	return nil, err
+}
+
+/*
+Mkdir creates a directory, if its parent directory exists.
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the path of the directory
+  - perm – the permissions of the directory
+
+– Returns:
+  - nil if the directory was created successfully, an error otherwise
+*/
+func (memFS *MemFileSystem) Mkdir(name string, perm os.FileMode) error {
+	memFS.mutex.Lock()
+	defer memFS.mutex.Unlock()
+
+	if _, err := memFS.getNode("mkdir", name); nil == err {
+		return &os.PathError{Op: "mkdir", Path: name, Err: os.ErrExist}
+	}
+	if err := memFS.checkParent("mkdir", name); nil != err {
+		return err
+	}
+
+	memFS.nodes[cleanMemPathFILESDIRS(name)] = &_MemNode{
+		is_dir:   true,
+		data:     nil,
+		mode:     perm.Perm(),
+		mod_time: time.Now(),
+	}
+
+	return nil
+}
+
+/*
+Remove removes a file or an empty directory.
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the path to remove
+
+– Returns:
+  - nil if the path was removed successfully, an error otherwise
+*/
+func (memFS *MemFileSystem) Remove(name string) error {
+	memFS.mutex.Lock()
+	defer memFS.mutex.Unlock()
+
+	var path string = cleanMemPathFILESDIRS(name)
+	node, ok := memFS.nodes[path]
+	if !ok {
+		return &os.PathError{Op: "remove", Path: name, Err: os.ErrNotExist}
+	}
+	if node.is_dir {
+		for child_path := range memFS.nodes {
+			if child_path != path && filepath.Dir(child_path) == path {
+				return &os.PathError{Op: "remove", Path: name, Err: errors.New("directory not empty")}
+			}
+		}
+	}
+
+	delete(memFS.nodes, path)
+
+	return nil
+}
+
+/*
+Rename renames (moves) a path, with all its contents if it's a directory. An existing file is replaced, but not
+an existing directory.
+
+-----------------------------------------------------------
+
+– Params:
+  - old_name – the path to rename
+  - new_name – the new path
+
+– Returns:
+  - nil if the path was renamed successfully, an error otherwise
+*/
+func (memFS *MemFileSystem) Rename(old_name string, new_name string) error {
+	memFS.mutex.Lock()
+	defer memFS.mutex.Unlock()
+
+	var old_path string = cleanMemPathFILESDIRS(old_name)
+	var new_path string = cleanMemPathFILESDIRS(new_name)
+	node, ok := memFS.nodes[old_path]
+	if !ok {
+		return &os.LinkError{Op: "rename", Old: old_name, New: new_name, Err: os.ErrNotExist}
+	}
+	if err := memFS.checkParent("rename", new_name); nil != err {
+		return err
+	}
+	if old_path == new_path {
+		return nil
+	}
+	if strings.HasPrefix(new_path, old_path + string(os.PathSeparator)) {
+		return &os.LinkError{Op: "rename", Old: old_name, New: new_name, Err: errors.New("invalid argument")}
+	}
+	if existing, ok := memFS.nodes[new_path]; ok && (existing.is_dir || node.is_dir) {
+		return &os.LinkError{Op: "rename", Old: old_name, New: new_name, Err: os.ErrExist}
+	}
+
+	// Move the node and, if it's a directory, all its contents. The paths are collected first, as changing the map
+	// while ranging over it may skip or repeat entries.
+	var old_prefix string = old_path + string(os.PathSeparator)
+	var child_paths []string = nil
+	for path := range memFS.nodes {
+		if strings.HasPrefix(path, old_prefix) {
+			child_paths = append(child_paths, path)
+		}
+	}
+	for _, path := range child_paths {
+		var child *_MemNode = memFS.nodes[path]
+		delete(memFS.nodes, path)
+		memFS.nodes[new_path + path[len(old_path):]] = child
+	}
+	delete(memFS.nodes, old_path)
+	memFS.nodes[new_path] = node
+
+	return nil
+}
+
+/*
+Chmod changes the permissions of a path.
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the path
+  - mode – the new permissions
+
+– Returns:
+  - nil if the permissions were changed successfully, an error otherwise
+*/
+func (memFS *MemFileSystem) Chmod(name string, mode os.FileMode) error {
+	memFS.mutex.Lock()
+	defer memFS.mutex.Unlock()
+
+	node, err := memFS.getNode("chmod", name)
+	if nil != err {
+		return err
+	}
+	node.mode = mode.Perm()
+
+	return nil
+}
+
+/*
+Chtimes changes the modification time of a path (the access time is not kept).
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the path
+  - atime – the new access time
+  - mtime – the new modification time
+
+– Returns:
+  - nil if the times were changed successfully, an error otherwise
+*/
+func (memFS *MemFileSystem) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	memFS.mutex.Lock()
+	defer memFS.mutex.Unlock()
+
+	node, err := memFS.getNode("chtimes", name)
+	if nil != err {
+		return err
+	}
+	node.mod_time = mtime
+
+	return nil
+}
+
+/*
+Readlink would get the target of a symbolic link, but they're not supported.
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the path of the link
+
+– Returns:
+  - always ""
+  - always an error
+*/
+func (memFS *MemFileSystem) Readlink(name string) (string, error) {
+	return "", &os.PathError{Op: "readlink", Path: name, Err: errors.New("symbolic links are not supported")}
+}
+
+/*
+Symlink would create a symbolic link, but they're not supported.
+
+-----------------------------------------------------------
+
+– Params:
+  - old_name – the target of the link
+  - new_name – the path of the link
+
+– Returns:
+  - always an error
+*/
+func (memFS *MemFileSystem) Symlink(old_name string, new_name string) error {
+	return &os.LinkError{Op: "symlink", Old: old_name, New: new_name,
+		Err: errors.New("symbolic links are not supported")}
+}
+
+/*
+EvalSymlinks gets a path cleaned, if it exists (there are no symbolic links to resolve).
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the path
+
+– Returns:
+  - the resolved path
+  - nil if the path was resolved successfully, an error otherwise
+*/
+func (memFS *MemFileSystem) EvalSymlinks(name string) (string, error) {
+	if _, err := memFS.Lstat(name); nil != err {
+		return "", err
+	}
+
+	return cleanMemPathFILESDIRS(name), nil
+}
+
+/*
+getNode gets the node of a path. Must be called with the mutex locked.
+
+-----------------------------------------------------------
+
+– Params:
+  - op – the name of the operation, for the error
+  - name – the path of the node
+
+– Returns:
+  - the node of the path
+  - nil if the node exists, an error otherwise
+*/
+func (memFS *MemFileSystem) getNode(op string, name string) (*_MemNode, error) {
+	var path string = cleanMemPathFILESDIRS(name)
+	if isMemRootFILESDIRS(path) {
+		return &_MemNode{is_dir: true, mode: 0o777}, nil
+	}
+
+	node, ok := memFS.nodes[path]
+	if !ok {
+		return nil, &os.PathError{Op: op, Path: name, Err: os.ErrNotExist}
+	}
+
+	return node, nil
+}
+
+/*
+checkParent checks if the parent of a path exists and is a directory. Must be called with the mutex locked.
+
+-----------------------------------------------------------
+
+– Params:
+  - op – the name of the operation, for the error
+  - name – the path whose parent is checked
+
+– Returns:
+  - nil if the parent is a directory, an error otherwise
+*/
+func (memFS *MemFileSystem) checkParent(op string, name string) error {
+	var parent string = filepath.Dir(cleanMemPathFILESDIRS(name))
+	node, err := memFS.getNode(op, parent)
+	if nil != err {
+		return &os.PathError{Op: op, Path: name, Err: os.ErrNotExist}
+	}
+	if !node.is_dir {
+		return &os.PathError{Op: op, Path: name, Err: errors.New("not a directory")}
+	}
+
+	return nil
+}
+
+/*
+Read reads from the current offset of the file, like os.File.Read().
+
+-----------------------------------------------------------
+
+– Params:
+  - p – the buffer to read into
+
+– Returns:
+  - the number of bytes read
+  - nil if bytes were read, io.EOF at the end of the file, another error otherwise (like if the file is closed or was
+    opened only for writing)
+*/
+func (memFile *_MemFile) Read(p []byte) (int, error) {
+	memFile.memFS.mutex.Lock()
+	defer memFile.memFS.mutex.Unlock()
+
+	if memFile.closed {
+		return 0, os.ErrClosed
+	}
+	if 0 != memFile.flag & os.O_WRONLY {
+		return 0, &os.PathError{Op: "read", Path: memFile.name, Err: errors.New("bad file descriptor")}
+	}
+	if memFile.offset >= int64(len(memFile.node.data)) {
+		return 0, io.EOF
+	}
+
+	var n int = copy(p, memFile.node.data[memFile.offset:])
+	memFile.offset += int64(n)
+
+	return n, nil
+}
+
+/*
+Write writes at the current offset of the file (or at its end if opened with os.O_APPEND), like os.File.Write().
+
+-----------------------------------------------------------
+
+– Params:
+  - p – the bytes to write
+
+– Returns:
+  - the number of bytes written
+  - nil if the bytes were written, an error otherwise (like if the file is closed or was opened only for reading)
+*/
+func (memFile *_MemFile) Write(p []byte) (int, error) {
+	memFile.memFS.mutex.Lock()
+	defer memFile.memFS.mutex.Unlock()
+
+	if memFile.closed {
+		return 0, os.ErrClosed
+	}
+	if 0 == memFile.flag & (os.O_WRONLY | os.O_RDWR) {
+		return 0, &os.PathError{Op: "write", Path: memFile.name, Err: errors.New("bad file descriptor")}
+	}
+
+	if 0 != memFile.flag & os.O_APPEND {
+		memFile.offset = int64(len(memFile.node.data))
+	}
+	var end int64 = memFile.offset + int64(len(p))
+	if end > int64(len(memFile.node.data)) {
+		var new_data []byte = make([]byte, end)
+		copy(new_data, memFile.node.data)
+		memFile.node.data = new_data
+	}
+	copy(memFile.node.data[memFile.offset:], p)
+	memFile.offset = end
+	memFile.node.mod_time = time.Now()
+
+	return len(p), nil
+}
+
+/*
+Seek sets the offset of the next Read() or Write(), like os.File.Seek().
+
+-----------------------------------------------------------
+
+– Params:
+  - offset – the offset
+  - whence – what the offset is relative to: io.SeekStart, io.SeekCurrent or io.SeekEnd
+
+– Returns:
+  - the new offset, relative to the start of the file
+  - nil if the offset was set, an error otherwise (if it would be negative)
+*/
+func (memFile *_MemFile) Seek(offset int64, whence int) (int64, error) {
+	memFile.memFS.mutex.Lock()
+	defer memFile.memFS.mutex.Unlock()
+
+	var new_offset int64 = offset
+	switch whence {
+		case io.SeekCurrent:
+			new_offset += memFile.offset
+		case io.SeekEnd:
+			new_offset += int64(len(memFile.node.data))
+	}
+	if new_offset < 0 {
+		return 0, &os.PathError{Op: "seek", Path: memFile.name, Err: errors.New("invalid argument")}
+	}
+	memFile.offset = new_offset
+
+	return new_offset, nil
+}
+
+/*
+Close closes the file. The contents stay in the file system.
+
+-----------------------------------------------------------
+
+– Returns:
+  - nil if the file was closed, os.ErrClosed if it was already closed
+*/
+func (memFile *_MemFile) Close() error {
+	memFile.memFS.mutex.Lock()
+	defer memFile.memFS.mutex.Unlock()
+
+	if memFile.closed {
+		return os.ErrClosed
+	}
+	memFile.closed = true
+
+	return nil
+}
+
+/*
+Name gets the name the file was opened with.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the name of the file
+*/
+func (memFile *_MemFile) Name() string {
+	return memFile.name
+}
+
+/*
+Stat gets the information about the file, with its current size and modification time.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the information about the file
+  - always nil
+*/
+func (memFile *_MemFile) Stat() (os.FileInfo, error) {
+	memFile.memFS.mutex.Lock()
+	defer memFile.memFS.mutex.Unlock()
+
+	return newMemFileInfoFILESDIRS(memFile.name, memFile.node), nil
+}
+
+/*
+Sync does nothing, as there's nothing to write to a disk.
+
+-----------------------------------------------------------
+
+– Returns:
+  - always nil
+*/
+func (memFile *_MemFile) Sync() error {
+	return nil
+}
+
+/*
+Name gets the base name of the path.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the base name
+*/
+func (memFileInfo _MemFileInfo) Name() string {
+	return memFileInfo.name
+}
+
+/*
+Size gets the size of the file.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the size in bytes, or 0 for a directory
+*/
+func (memFileInfo _MemFileInfo) Size() int64 {
+	return memFileInfo.size
+}
+
+/*
+Mode gets the mode of the path (type and permissions).
+
+-----------------------------------------------------------
+
+– Returns:
+  - the mode
+*/
+func (memFileInfo _MemFileInfo) Mode() os.FileMode {
+	return memFileInfo.mode
+}
+
+/*
+ModTime gets the modification time of the path.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the modification time
+*/
+func (memFileInfo _MemFileInfo) ModTime() time.Time {
+	return memFileInfo.mod_time
+}
+
+/*
+IsDir checks if the path is a directory.
+
+-----------------------------------------------------------
+
+– Returns:
+  - true if the path is a directory, false otherwise
+*/
+func (memFileInfo _MemFileInfo) IsDir() bool {
+	return memFileInfo.mode.IsDir()
+}
+
+/*
+Sys gets the underlying data source, which there isn't in memory.
+
+-----------------------------------------------------------
+
+– Returns:
+  - always nil
+*/
+func (memFileInfo _MemFileInfo) Sys() any {
+	return nil
+}
+
+/*
+newMemFileInfoFILESDIRS creates the os.FileInfo of a node, with the node's current state.
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the path of the node
+  - node – the node
+
+– Returns:
+  - the information about the node
+*/
+func newMemFileInfoFILESDIRS(name string, node *_MemNode) _MemFileInfo {
+	var mode os.FileMode = node.mode
+	if node.is_dir {
+		mode |= os.ModeDir
+	}
+
+	return _MemFileInfo{
+		name:     filepath.Base(cleanMemPathFILESDIRS(name)),
+		size:     int64(len(node.data)),
+		mode:     mode,
+		mod_time: node.mod_time,
+	}
+}
+
+/*
+cleanMemPathFILESDIRS cleans a path to be used as a key of the nodes of a MemFileSystem.
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the path
+
+– Returns:
+  - the cleaned path
+*/
+func cleanMemPathFILESDIRS(name string) string {
+	return filepath.Clean(filepath.FromSlash(name))
+}
+
+/*
+isMemRootFILESDIRS checks if a cleaned path is a root directory (which always exists on a MemFileSystem).
+
+-----------------------------------------------------------
+
+– Params:
+  - path – the cleaned path
+
+– Returns:
+  - true if the path is a root directory, false otherwise
+*/
+func isMemRootFILESDIRS(path string) bool {
+	return filepath.Dir(path) == path || "." == path
+}
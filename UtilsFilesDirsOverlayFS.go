@@ -0,0 +1,622 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package Utils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+/*
+OverlayFileSystem is a FileSystem that reads from another one (the base) but never changes it: all changes go to an
+in-memory layer on top of it, which is what's read from for the changed paths. Removed paths are hidden from the base.
+Get one with NewOverlayFileSystemFILESDIRS().
+
+This is useful to run modules without changing any files (like for dry runs), while still letting them see their own
+changes. Symbolic links can't be created and the ones on the base can be read but not changed.
+*/
+type OverlayFileSystem struct {
+	// base is the file system read from, which is never changed.
+	base FileSystem
+	// upper is the layer with the changed paths.
+	upper *MemFileSystem
+	// mutex protects whiteouts and keeps the copies from the base to the upper layer consistent.
+	mutex sync.Mutex
+	// whiteouts is the set of the cleaned paths removed (or renamed away), which hides them and their contents on the
+	// base.
+	whiteouts map[string]bool
+}
+
+/*
+NewOverlayFileSystemFILESDIRS creates a new overlay file system on top of another one, with no changes yet.
+
+-----------------------------------------------------------
+
+– Params:
+  - base – the file system to read from
+
+– Returns:
+  - the new file system
+*/
+func NewOverlayFileSystemFILESDIRS(base FileSystem) *OverlayFileSystem {
+	return &OverlayFileSystem{
+		base:      base,
+		upper:     NewMemFileSystemFILESDIRS(),
+		whiteouts: map[string]bool{},
+	}
+}
+
+/*
+Stat gets information about a path, following symbolic links.
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the path
+
+– Returns:
+  - the information about the path
+  - nil if the information was got successfully, an error otherwise
+*/
+func (overlayFS *OverlayFileSystem) Stat(name string) (os.FileInfo, error) {
+	overlayFS.mutex.Lock()
+	defer overlayFS.mutex.Unlock()
+
+	if overlayFS.inUpper(name) {
+		return overlayFS.upper.Stat(name)
+	}
+	if overlayFS.isHidden(name) {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+	}
+
+	return overlayFS.base.Stat(name)
+}
+
+/*
+Lstat gets information about a path, without following symbolic links.
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the path
+
+– Returns:
+  - the information about the path
+  - nil if the information was got successfully, an error otherwise
+*/
+func (overlayFS *OverlayFileSystem) Lstat(name string) (os.FileInfo, error) {
+	overlayFS.mutex.Lock()
+	defer overlayFS.mutex.Unlock()
+
+	return overlayFS.lstat(name)
+}
+
+/*
+ReadDir reads the contents of a directory, merging the ones of the base with the ones of the upper layer.
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the path of the directory
+
+– Returns:
+  - the entries of the directory, sorted by name
+  - nil if the directory was read successfully, an error otherwise
+*/
+func (overlayFS *OverlayFileSystem) ReadDir(name string) ([]os.DirEntry, error) {
+	overlayFS.mutex.Lock()
+	defer overlayFS.mutex.Unlock()
+
+	return overlayFS.readDir(name)
+}
+
+/*
+OpenFile opens a file. If it's opened for writing, it's copied to the upper layer first (unless it's being created).
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the path of the file
+  - flag – the os.O_* flags to open the file with
+  - perm – the permissions of the file if it's created
+
+– Returns:
+  - the opened file
+  - nil if the file was opened successfully, an error otherwise
+*/
+func (overlayFS *OverlayFileSystem) OpenFile(name string, flag int, perm os.FileMode) (FSFile, error) {
+	overlayFS.mutex.Lock()
+	defer overlayFS.mutex.Unlock()
+
+	if 0 == flag & (os.O_WRONLY | os.O_RDWR | os.O_CREATE | os.O_TRUNC | os.O_APPEND) {
+		if overlayFS.inUpper(name) {
+			return overlayFS.upper.OpenFile(name, flag, perm)
+		}
+		if overlayFS.isHidden(name) {
+			return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+		}
+
+		return overlayFS.base.OpenFile(name, flag, perm)
+	}
+
+	file_info, err := overlayFS.lstat(name)
+	if nil == err {
+		if 0 != flag & os.O_CREATE && 0 != flag & os.O_EXCL {
+			return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrExist}
+		}
+		if file_info.IsDir() {
+			return nil, &os.PathError{Op: "open", Path: name, Err: errors.New("is a directory")}
+		}
+		if err = overlayFS.copyUp(name, false); nil != err {
+			return nil, err
+		}
+	} else {
+		if 0 == flag & os.O_CREATE {
+			return nil, err
+		}
+		if err = overlayFS.copyUp(filepath.Dir(cleanMemPathFILESDIRS(name)), false); nil != err {
+			return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+		}
+	}
+
+	return overlayFS.upper.OpenFile(name, flag, perm)
+}
+
+/*
+CreateTemp creates a new temporary file in a directory, in the upper layer.
+
+-----------------------------------------------------------
+
+– Params:
+  - dir – the directory to create the file in
+  - pattern – the name of the file, with the last "*" replaced by a random string (or with it appended if there's none)
+
+– Returns:
+  - the opened file
+  - nil if the file was created successfully, an error otherwise
+*/
+func (overlayFS *OverlayFileSystem) CreateTemp(dir string, pattern string) (FSFile, error) {
+	overlayFS.mutex.Lock()
+	defer overlayFS.mutex.Unlock()
+
+	if err := overlayFS.copyUp(dir, false); nil != err {
+		return nil, err
+	}
+
+	return overlayFS.upper.CreateTemp(dir, pattern)
+}
+
+/*
+Mkdir creates a directory in the upper layer.
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the path of the directory
+  - perm – the permissions of the directory
+
+– Returns:
+  - nil if the directory was created successfully, an error otherwise
+*/
+func (overlayFS *OverlayFileSystem) Mkdir(name string, perm os.FileMode) error {
+	overlayFS.mutex.Lock()
+	defer overlayFS.mutex.Unlock()
+
+	if _, err := overlayFS.lstat(name); nil == err {
+		return &os.PathError{Op: "mkdir", Path: name, Err: os.ErrExist}
+	}
+	if err := overlayFS.copyUp(filepath.Dir(cleanMemPathFILESDIRS(name)), false); nil != err {
+		return &os.PathError{Op: "mkdir", Path: name, Err: os.ErrNotExist}
+	}
+
+	return overlayFS.upper.Mkdir(name, perm)
+}
+
+/*
+Remove removes a file or an empty directory, from the upper layer if it's there and by hiding it from the base.
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the path to remove
+
+– Returns:
+  - nil if the path was removed successfully, an error otherwise
+*/
+func (overlayFS *OverlayFileSystem) Remove(name string) error {
+	overlayFS.mutex.Lock()
+	defer overlayFS.mutex.Unlock()
+
+	file_info, err := overlayFS.lstat(name)
+	if nil != err {
+		return &os.PathError{Op: "remove", Path: name, Err: os.ErrNotExist}
+	}
+	if file_info.IsDir() {
+		entries, err := overlayFS.readDir(name)
+		if nil != err {
+			return err
+		}
+		if 0 != len(entries) {
+			return &os.PathError{Op: "remove", Path: name, Err: errors.New("directory not empty")}
+		}
+	}
+
+	if overlayFS.inUpper(name) {
+		if err = overlayFS.upper.Remove(name); nil != err {
+			return err
+		}
+	}
+	overlayFS.whiteouts[cleanMemPathFILESDIRS(name)] = true
+
+	return nil
+}
+
+/*
+Rename renames (moves) a path. The path (with all its contents, if it's a directory) is copied to the upper layer and
+renamed there, and the old path is hidden from the base.
+
+-----------------------------------------------------------
+
+– Params:
+  - old_name – the path to rename
+  - new_name – the new path
+
+– Returns:
+  - nil if the path was renamed successfully, an error otherwise
+*/
+func (overlayFS *OverlayFileSystem) Rename(old_name string, new_name string) error {
+	overlayFS.mutex.Lock()
+	defer overlayFS.mutex.Unlock()
+
+	var old_path string = cleanMemPathFILESDIRS(old_name)
+	var new_path string = cleanMemPathFILESDIRS(new_name)
+	old_info, err := overlayFS.lstat(old_path)
+	if nil != err {
+		return &os.LinkError{Op: "rename", Old: old_name, New: new_name, Err: os.ErrNotExist}
+	}
+	if old_path == new_path {
+		return nil
+	}
+	if err = overlayFS.copyUp(filepath.Dir(new_path), false); nil != err {
+		return &os.LinkError{Op: "rename", Old: old_name, New: new_name, Err: os.ErrNotExist}
+	}
+	if new_info, err := overlayFS.lstat(new_path); nil == err && (new_info.IsDir() || old_info.IsDir()) {
+		return &os.LinkError{Op: "rename", Old: old_name, New: new_name, Err: os.ErrExist}
+	}
+	if err = overlayFS.copyUp(old_path, true); nil != err {
+		return err
+	}
+
+	if err = overlayFS.upper.Rename(old_path, new_path); nil != err {
+		return err
+	}
+	overlayFS.whiteouts[old_path] = true
+
+	return nil
+}
+
+/*
+Chmod changes the permissions of a path, after copying it to the upper layer.
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the path
+  - mode – the new permissions
+
+– Returns:
+  - nil if the permissions were changed successfully, an error otherwise
+*/
+func (overlayFS *OverlayFileSystem) Chmod(name string, mode os.FileMode) error {
+	overlayFS.mutex.Lock()
+	defer overlayFS.mutex.Unlock()
+
+	if err := overlayFS.copyUp(name, false); nil != err {
+		return err
+	}
+
+	return overlayFS.upper.Chmod(name, mode)
+}
+
+/*
+Chtimes changes the access and modification times of a path, after copying it to the upper layer.
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the path
+  - atime – the new access time
+  - mtime – the new modification time
+
+– Returns:
+  - nil if the times were changed successfully, an error otherwise
+*/
+func (overlayFS *OverlayFileSystem) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	overlayFS.mutex.Lock()
+	defer overlayFS.mutex.Unlock()
+
+	if err := overlayFS.copyUp(name, false); nil != err {
+		return err
+	}
+
+	return overlayFS.upper.Chtimes(name, atime, mtime)
+}
+
+/*
+Readlink gets the target of a symbolic link of the base.
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the path of the link
+
+– Returns:
+  - the target of the link
+  - nil if the target was got successfully, an error otherwise
+*/
+func (overlayFS *OverlayFileSystem) Readlink(name string) (string, error) {
+	overlayFS.mutex.Lock()
+	defer overlayFS.mutex.Unlock()
+
+	if overlayFS.inUpper(name) {
+		return overlayFS.upper.Readlink(name)
+	}
+	if overlayFS.isHidden(name) {
+		return "", &os.PathError{Op: "readlink", Path: name, Err: os.ErrNotExist}
+	}
+
+	return overlayFS.base.Readlink(name)
+}
+
+/*
+Symlink would create a symbolic link, but they can't be created on an overlay file system.
+
+-----------------------------------------------------------
+
+– Params:
+  - old_name – the target of the link
+  - new_name – the path of the link
+
+– Returns:
+  - always an error
+*/
+func (overlayFS *OverlayFileSystem) Symlink(old_name string, new_name string) error {
+	return &os.LinkError{Op: "symlink", Old: old_name, New: new_name,
+		Err: errors.New("symbolic links are not supported")}
+}
+
+/*
+EvalSymlinks gets a path with its symbolic links (the ones of the base) resolved.
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the path
+
+– Returns:
+  - the resolved path
+  - nil if the path was resolved successfully, an error otherwise
+*/
+func (overlayFS *OverlayFileSystem) EvalSymlinks(name string) (string, error) {
+	overlayFS.mutex.Lock()
+	defer overlayFS.mutex.Unlock()
+
+	if overlayFS.inUpper(name) {
+		return overlayFS.upper.EvalSymlinks(name)
+	}
+	if overlayFS.isHidden(name) {
+		return "", &os.PathError{Op: "lstat", Path: name, Err: os.ErrNotExist}
+	}
+
+	return overlayFS.base.EvalSymlinks(name)
+}
+
+/*
+lstat gets information about a path, without following symbolic links. Must be called with the mutex locked.
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the path
+
+– Returns:
+  - the information about the path, from the upper layer if it's there, from the base otherwise
+  - nil if the information was got successfully, an error otherwise
+*/
+func (overlayFS *OverlayFileSystem) lstat(name string) (os.FileInfo, error) {
+	if overlayFS.inUpper(name) {
+		return overlayFS.upper.Lstat(name)
+	}
+	if overlayFS.isHidden(name) {
+		return nil, &os.PathError{Op: "lstat", Path: name, Err: os.ErrNotExist}
+	}
+
+	return overlayFS.base.Lstat(name)
+}
+
+/*
+readDir reads the contents of a directory, merging the ones of the base with the ones of the upper layer. Must be
+called with the mutex locked.
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the path of the directory
+
+– Returns:
+  - the entries of the directory, sorted by name
+  - nil if the directory was read successfully, an error otherwise
+*/
+func (overlayFS *OverlayFileSystem) readDir(name string) ([]os.DirEntry, error) {
+	file_info, err := overlayFS.lstat(name)
+	if nil != err {
+		return nil, err
+	}
+	if !file_info.IsDir() {
+		return nil, &os.PathError{Op: "readdirent", Path: name, Err: errors.New("not a directory")}
+	}
+
+	var path string = cleanMemPathFILESDIRS(name)
+	var entries_map map[string]os.DirEntry = map[string]os.DirEntry{}
+	if !overlayFS.isHidden(path) {
+		if base_entries, err := overlayFS.base.ReadDir(name); nil == err {
+			for _, entry := range base_entries {
+				if !overlayFS.whiteouts[filepath.Join(path, entry.Name())] {
+					entries_map[entry.Name()] = entry
+				}
+			}
+		}
+	}
+	if overlayFS.inUpper(path) || isMemRootFILESDIRS(path) {
+		upper_entries, err := overlayFS.upper.ReadDir(name)
+		if nil != err {
+			return nil, err
+		}
+		for _, entry := range upper_entries {
+			entries_map[entry.Name()] = entry
+		}
+	}
+
+	var entries []os.DirEntry = make([]os.DirEntry, 0, len(entries_map))
+	for _, entry := range entries_map {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+
+	return entries, nil
+}
+
+/*
+copyUp copies a path of the base to the upper layer (with its missing parents), so that it can be changed there. Must be
+called with the mutex locked.
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the path
+  - recursive – true to also copy all the contents if it's a directory, false to copy only the path itself
+
+– Returns:
+  - nil if the path is on the upper layer, an error otherwise
+*/
+func (overlayFS *OverlayFileSystem) copyUp(name string, recursive bool) error {
+	var path string = cleanMemPathFILESDIRS(name)
+	if isMemRootFILESDIRS(path) && !recursive {
+		return nil
+	}
+
+	file_info, err := overlayFS.lstat(path)
+	if nil != err {
+		return err
+	}
+	if !overlayFS.inUpper(path) && !isMemRootFILESDIRS(path) {
+		if 0 != file_info.Mode() & os.ModeSymlink {
+			return &os.PathError{Op: "copy", Path: name, Err: errors.New("symbolic links are not supported")}
+		}
+		if err = overlayFS.copyUp(filepath.Dir(path), false); nil != err {
+			return err
+		}
+
+		if file_info.IsDir() {
+			err = overlayFS.upper.Mkdir(path, file_info.Mode().Perm())
+		} else {
+			var data []byte = nil
+			data, err = readFileFS(overlayFS.base, path)
+			if nil == err {
+				err = writeFileFS(overlayFS.upper, path, data, file_info.Mode().Perm())
+			}
+		}
+		if nil != err {
+			return err
+		}
+		_ = overlayFS.upper.Chtimes(path, file_info.ModTime(), file_info.ModTime())
+	}
+
+	if recursive && file_info.IsDir() {
+		entries, err := overlayFS.readDir(path)
+		if nil != err {
+			return err
+		}
+		for _, entry := range entries {
+			if err = overlayFS.copyUp(filepath.Join(path, entry.Name()), true); nil != err {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+/*
+inUpper checks if a path is on the upper layer (the roots never are, as they're the base ones). Must be called with the
+mutex locked.
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the path
+
+– Returns:
+  - true if the path is on the upper layer, false otherwise
+*/
+func (overlayFS *OverlayFileSystem) inUpper(name string) bool {
+	var path string = cleanMemPathFILESDIRS(name)
+	if isMemRootFILESDIRS(path) {
+		return false
+	}
+	_, err := overlayFS.upper.Lstat(path)
+
+	return nil == err
+}
+
+/*
+isHidden checks if a path of the base is hidden because it or one of its parents was removed. Must be called with the
+mutex locked.
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the path
+
+– Returns:
+  - true if the path is hidden, false otherwise
+*/
+func (overlayFS *OverlayFileSystem) isHidden(name string) bool {
+	var path string = cleanMemPathFILESDIRS(name)
+	for {
+		if overlayFS.whiteouts[path] {
+			return true
+		}
+
+		var parent string = filepath.Dir(path)
+		if parent == path || "." == parent {
+			return false
+		}
+		path = parent
+	}
+}
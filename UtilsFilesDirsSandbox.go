@@ -0,0 +1,406 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package Utils
+
+import (
+	"errors"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// _INVALID_NAME_CHARS is the characters not allowed in names of files or directories on some OS.
+const _INVALID_NAME_CHARS string = "/\\:*?\"<>|"
+
+// ErrOutOfSandbox is the error returned by the changes to paths out of the sandbox of the GPath they're done through.
+var ErrOutOfSandbox error = errors.New("the path is out of its sandbox")
+
+// _SandboxFS is the FileSystem of the sandboxed paths: it reads from another one, but refuses the changes to paths out
+// of the sandbox with ErrOutOfSandbox (only by the path strings - the symbolic links are checked by GPath.Child()).
+type _SandboxFS struct {
+	// FileSystem is the file system of the path.
+	FileSystem
+	// sandbox is the sandbox of the path.
+	sandbox string
+}
+
+/*
+Sandbox gets a copy of the path that is the sandbox of itself and of all the paths added to it: they can't go out of it.
+
+GPath.Child() returns an error if the final path would go out of the sandbox, also checking symbolic links that point
+outside of it. GPath.Add() (or PathFILESDIRS()) and GPath.Dir() still give paths out of the sandbox (with ".." for
+example), but they keep the sandbox, so all the changes through them (writing, creating, removing, moving to...) are
+refused with ErrOutOfSandbox. Only reading is allowed.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the path, now a sandbox
+*/
+func (gPath GPath) Sandbox() GPath {
+	gPath.sandbox = gPath.p
+	if !strings.HasSuffix(gPath.sandbox, gPath.s) {
+		gPath.sandbox += gPath.s
+	}
+
+	return gPath
+}
+
+/*
+Child safely adds names to a directory path. Use it instead of Add() for names that can't be trusted (user input,
+email addresses, names from the Internet...).
+
+Each name must be a single file or directory name - it's sanitized with SanitizeNameFILESDIRS() and rejected if it's
+empty, "." or "..". The final path is also rejected if it's out of the sandbox of the directory (or of the directory
+itself if it has no sandbox), including through symbolic links.
+
+-----------------------------------------------------------
+
+– Params:
+  - describes_dir – true if the final path describes a directory, false if it describes a file
+  - names – the names to add
+
+– Returns:
+  - the final path, with the same sandbox as the directory (or the directory as the sandbox if it has none)
+  - nil if the path is safe, an error otherwise
+*/
+func (gPath GPath) Child(describes_dir bool, names ...string) (GPath, error) {
+	if 0 == len(names) {
+		return GPath{}, errors.New("no names given")
+	}
+
+	var base_gPath GPath = gPath
+	if "" == base_gPath.sandbox {
+		base_gPath = base_gPath.Sandbox()
+	}
+
+	var sub_paths []any = []any{base_gPath}
+	for _, name := range names {
+		var sanitized string = SanitizeNameFILESDIRS(name)
+		if "" == sanitized || "." == sanitized || ".." == sanitized {
+			return GPath{}, errors.New("invalid name: \"" + name + "\"")
+		}
+		sub_paths = append(sub_paths, sanitized)
+	}
+	var child GPath = PathFILESDIRS(describes_dir, gPath.s, sub_paths...)
+	if !child.isInSandbox() {
+		return GPath{}, errors.New("the path \"" + child.p + "\" goes out of its sandbox")
+	}
+
+	if err := child.checkSymlinksInSandbox(); nil != err {
+		return GPath{}, err
+	}
+
+	return child, nil
+}
+
+/*
+SanitizeNameFILESDIRS makes a string safe to use as the name of a file or directory on any OS.
+
+The path separators, the characters not allowed on Windows and the control characters are replaced with "_", and the
+spaces and dots at the ends (which Windows drops) are removed.
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the name to sanitize
+
+– Returns:
+  - the sanitized name (which can still be "", "." or "..", which GPath.Child() rejects)
+*/
+func SanitizeNameFILESDIRS(name string) string {
+	var builder strings.Builder
+	for _, c := range name {
+		if c < 0x20 || 0x7F == c || strings.ContainsRune(_INVALID_NAME_CHARS, c) {
+			builder.WriteRune('_')
+		} else {
+			builder.WriteRune(c)
+		}
+	}
+
+	var sanitized string = strings.TrimLeft(builder.String(), " ")
+	if "." != sanitized && ".." != sanitized {
+		sanitized = strings.TrimRight(sanitized, " .")
+	}
+
+	return sanitized
+}
+
+/*
+isInSandbox checks if the path is inside its sandbox, only by the path string (without checking symbolic links).
+
+-----------------------------------------------------------
+
+– Returns:
+  - true if the path is inside its sandbox or has none, false otherwise
+*/
+func (gPath GPath) isInSandbox() bool {
+	if "" == gPath.sandbox {
+		return true
+	}
+
+	// Compare with the same separator on both, since the path may use a different one from the sandbox.
+	var path string = strings.Replace(gPath.p, "\\", "/", -1)
+	var sandbox string = strings.Replace(gPath.sandbox, "\\", "/", -1)
+	if !strings.HasSuffix(path, "/") {
+		path += "/"
+	}
+	if "windows" == runtime.GOOS {
+		return strings.HasPrefix(strings.ToLower(path), strings.ToLower(sandbox))
+	}
+
+	return strings.HasPrefix(path, sandbox)
+}
+
+/*
+checkSymlinksInSandbox checks if the path is still inside its sandbox after resolving all the symbolic links on the way
+(up to the deepest part of the path that exists).
+
+-----------------------------------------------------------
+
+– Returns:
+  - nil if the path is inside its sandbox or has none, an error otherwise
+*/
+func (gPath GPath) checkSymlinksInSandbox() error {
+	if "" == gPath.sandbox {
+		return nil
+	}
+
+	var fileSystem FileSystem = gPath.getFS()
+	real_sandbox, err := fileSystem.EvalSymlinks(gPath.sandbox)
+	if nil != err {
+		// If the sandbox doesn't exist, nothing inside it can be a link.
+		return nil
+	}
+
+	// Find the deepest existing part of the path and resolve it.
+	var existing string = strings.TrimSuffix(gPath.p, gPath.s)
+	var real_path string
+	for {
+		if real_path, err = fileSystem.EvalSymlinks(existing); nil == err {
+			break
+		}
+
+		var idx int = strings.LastIndex(existing, gPath.s)
+		if idx <= 0 {
+			return nil
+		}
+		existing = existing[:idx]
+	}
+
+	var sandbox_gPath GPath = GPath{p: real_sandbox, s: gPath.s}.Sandbox()
+	var resolved_gPath GPath = GPath{p: real_path, s: gPath.s, sandbox: sandbox_gPath.sandbox}
+	if !resolved_gPath.isInSandbox() {
+		return errors.New("the path \"" + gPath.p + "\" goes out of its sandbox through a symbolic link")
+	}
+
+	return nil
+}
+
+/*
+checkInSandbox checks if a path given to the file system is inside the sandbox.
+
+-----------------------------------------------------------
+
+– Params:
+  - op – the operation, for the error
+  - name – the path
+
+– Returns:
+  - nil if the path is inside the sandbox, an error with ErrOutOfSandbox otherwise
+*/
+func (sandboxFS _SandboxFS) checkInSandbox(op string, name string) error {
+	if !(GPath{p: name, sandbox: sandboxFS.sandbox}).isInSandbox() {
+		return &os.PathError{Op: op, Path: name, Err: ErrOutOfSandbox}
+	}
+
+	return nil
+}
+
+/*
+OpenFile opens a file, refusing to open it for writing if it's out of the sandbox.
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the path of the file
+  - flag – the flags to open the file with
+  - perm – the permissions to create the file with
+
+– Returns:
+  - the opened file, or nil if an error occurred
+  - nil if the file was opened successfully, an error otherwise
+*/
+func (sandboxFS _SandboxFS) OpenFile(name string, flag int, perm os.FileMode) (FSFile, error) {
+	if 0 != flag & (os.O_WRONLY | os.O_RDWR | os.O_CREATE | os.O_TRUNC | os.O_APPEND) {
+		if err := sandboxFS.checkInSandbox("open", name); nil != err {
+			return nil, err
+		}
+	}
+
+	return sandboxFS.FileSystem.OpenFile(name, flag, perm)
+}
+
+/*
+CreateTemp creates a new temporary file in a directory inside the sandbox.
+
+-----------------------------------------------------------
+
+– Params:
+  - dir – the directory
+  - pattern – the name of the file, where the last "*" is replaced by a random string
+
+– Returns:
+  - the created file, or nil if an error occurred
+  - nil if the file was created successfully, an error otherwise
+*/
+func (sandboxFS _SandboxFS) CreateTemp(dir string, pattern string) (FSFile, error) {
+	if err := sandboxFS.checkInSandbox("createtemp", dir); nil != err {
+		return nil, err
+	}
+
+	return sandboxFS.FileSystem.CreateTemp(dir, pattern)
+}
+
+/*
+Mkdir creates a directory inside the sandbox.
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the path of the directory
+  - perm – the permissions of the directory
+
+– Returns:
+  - nil if the directory was created successfully, an error otherwise
+*/
+func (sandboxFS _SandboxFS) Mkdir(name string, perm os.FileMode) error {
+	if err := sandboxFS.checkInSandbox("mkdir", name); nil != err {
+		return err
+	}
+
+	return sandboxFS.FileSystem.Mkdir(name, perm)
+}
+
+/*
+Remove removes a file or an empty directory inside the sandbox.
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the path to remove
+
+– Returns:
+  - nil if the path was removed successfully, an error otherwise
+*/
+func (sandboxFS _SandboxFS) Remove(name string) error {
+	if err := sandboxFS.checkInSandbox("remove", name); nil != err {
+		return err
+	}
+
+	return sandboxFS.FileSystem.Remove(name)
+}
+
+/*
+Rename renames (moves) a path, with both the old and the new paths inside the sandbox.
+
+-----------------------------------------------------------
+
+– Params:
+  - old_name – the current path
+  - new_name – the new path
+
+– Returns:
+  - nil if the path was renamed successfully, an error otherwise
+*/
+func (sandboxFS _SandboxFS) Rename(old_name string, new_name string) error {
+	if err := sandboxFS.checkInSandbox("rename", old_name); nil != err {
+		return err
+	}
+	if err := sandboxFS.checkInSandbox("rename", new_name); nil != err {
+		return err
+	}
+
+	return sandboxFS.FileSystem.Rename(old_name, new_name)
+}
+
+/*
+Chmod changes the permissions of a path inside the sandbox.
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the path
+  - mode – the new permissions
+
+– Returns:
+  - nil if the permissions were changed successfully, an error otherwise
+*/
+func (sandboxFS _SandboxFS) Chmod(name string, mode os.FileMode) error {
+	if err := sandboxFS.checkInSandbox("chmod", name); nil != err {
+		return err
+	}
+
+	return sandboxFS.FileSystem.Chmod(name, mode)
+}
+
+/*
+Chtimes changes the access and modification times of a path inside the sandbox.
+
+-----------------------------------------------------------
+
+– Params:
+  - name – the path
+  - atime – the new access time
+  - mtime – the new modification time
+
+– Returns:
+  - nil if the times were changed successfully, an error otherwise
+*/
+func (sandboxFS _SandboxFS) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	if err := sandboxFS.checkInSandbox("chtimes", name); nil != err {
+		return err
+	}
+
+	return sandboxFS.FileSystem.Chtimes(name, atime, mtime)
+}
+
+/*
+Symlink creates a symbolic link inside the sandbox.
+
+-----------------------------------------------------------
+
+– Params:
+  - old_name – the path the link points to
+  - new_name – the path of the link
+
+– Returns:
+  - nil if the link was created successfully, an error otherwise
+*/
+func (sandboxFS _SandboxFS) Symlink(old_name string, new_name string) error {
+	if err := sandboxFS.checkInSandbox("symlink", new_name); nil != err {
+		return err
+	}
+
+	return sandboxFS.FileSystem.Symlink(old_name, new_name)
+}
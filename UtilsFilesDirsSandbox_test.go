@@ -0,0 +1,156 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package Utils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestSandboxAddOutside(t *testing.T) {
+	var dir GPath = PathFILESDIRS(true, "", t.TempDir())
+	var sandbox GPath = dir.Add2(true, "box").Sandbox()
+	if err := sandbox.Create(true); nil != err {
+		t.Fatal(err)
+	}
+	var outside_file GPath = dir.Add2(false, "outside.txt")
+	if err := outside_file.WriteTextFile("x"); nil != err {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		name      string
+		change    func() error
+		wantErr   bool
+		unchanged GPath
+	}{
+		{"write inside", func() error {
+			return sandbox.Add2(false, "file.txt").WriteTextFile("x")
+		}, false, GPath{}},
+		{"write inside through ..", func() error {
+			return sandbox.Add2(false, "dir", "..", "file2.txt").WriteTextFile("x")
+		}, false, GPath{}},
+		{"write in a new sandbox with new parents", func() error {
+			return dir.Add2(true, "a", "b").Sandbox().Add2(false, "file.txt").WriteTextFile("x")
+		}, false, GPath{}},
+		{"write outside", func() error {
+			return sandbox.Add2(false, "..", "new.txt").WriteTextFile("x")
+		}, true, dir.Add2(false, "new.txt")},
+		{"write far outside", func() error {
+			return sandbox.Add2(false, "..", "..", "new.txt").WriteTextFile("x")
+		}, true, dir.Dir().Add2(false, "new.txt")},
+		{"create directory outside", func() error {
+			return sandbox.Add2(true, "..", "new_dir").Create(true)
+		}, true, dir.Add2(true, "new_dir")},
+		{"create through Dir", func() error {
+			return sandbox.Dir().Add2(false, "new.txt").Create(true)
+		}, true, dir.Add2(false, "new.txt")},
+		{"overwrite outside", func() error {
+			return sandbox.Add2(false, "..", "outside.txt").WriteTextFile("y")
+		}, true, GPath{}},
+		{"remove outside", func() error {
+			return sandbox.Add2(false, "..", "outside.txt").Remove()
+		}, true, GPath{}},
+		{"move outside", func() error {
+			if err := sandbox.Add2(false, "moved.txt").WriteTextFile("x"); nil != err {
+				return err
+			}
+			_, err := sandbox.Add2(false, "moved.txt").MoveTo(sandbox.Add2(false, "..", "moved.txt"), nil)
+
+			return err
+		}, true, dir.Add2(false, "moved.txt")},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var err error = test.change()
+			if test.wantErr {
+				if !errors.Is(err, ErrOutOfSandbox) {
+					t.Errorf("got error %v, want ErrOutOfSandbox", err)
+				}
+			} else if nil != err {
+				t.Error(err)
+			}
+			if (GPath{}) != test.unchanged && test.unchanged.Exists() {
+				t.Errorf("%q was created out of the sandbox", test.unchanged.p)
+			}
+		})
+	}
+
+	// Reading is still allowed, and nothing changed the file.
+	var p_contents *string = sandbox.Add2(false, "..", "outside.txt").ReadTextFile()
+	if nil == p_contents || "x" != *p_contents {
+		t.Errorf("outside file read as %v, want \"x\"", p_contents)
+	}
+
+	// The lock file of the sandbox itself is next to it, out of it.
+	if err := sandbox.WithLock(LOCK_EXCLUSIVE, 0, func() error {
+		return nil
+	}); nil != err {
+		t.Errorf("locking the sandbox: %v", err)
+	}
+}
+
+func TestSandboxChild(t *testing.T) {
+	var dir string = t.TempDir()
+	var sandbox GPath = PathFILESDIRS(true, "", dir, "box").Sandbox()
+	if err := sandbox.Create(true); nil != err {
+		t.Fatal(err)
+	}
+	var symlinks bool = "windows" != runtime.GOOS
+	if symlinks {
+		if err := os.Symlink(dir, filepath.Join(dir, "box", "link")); nil != err {
+			t.Fatal(err)
+		}
+	}
+
+	var tests = []struct {
+		name         string
+		names        []string
+		needsSymlink bool
+		wantErr      bool
+	}{
+		{"plain name", []string{"file.txt"}, false, false},
+		{"nested names", []string{"dir", "file.txt"}, false, false},
+		{"dot dot", []string{".."}, false, true},
+		{"dot", []string{"."}, false, true},
+		{"empty", []string{""}, false, true},
+		{"separator sanitized", []string{"../file.txt"}, false, false},
+		{"symlink out", []string{"link", "file.txt"}, true, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.needsSymlink && !symlinks {
+				t.Skip("symbolic links need special permissions on Windows")
+			}
+			child, err := sandbox.Child(false, test.names...)
+			if (nil != err) != test.wantErr {
+				t.Fatalf("got error %v, want error %v", err, test.wantErr)
+			}
+			if nil == err && !child.isInSandbox() {
+				t.Errorf("%q is out of the sandbox", child.p)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package Utils
+
+import (
+	"os"
+	"time"
+)
+
+// PermsPolicy is the permissions given to the files and directories created through a GPath.
+type PermsPolicy struct {
+	// Dirs is the permissions of the directories.
+	Dirs os.FileMode
+	// Files is the permissions of the files.
+	Files os.FileMode
+}
+
+// perms_default_GL is the permissions policy of the paths without one.
+var perms_default_GL PermsPolicy = PermsOpenFILESDIRS()
+
+/*
+PermsOpenFILESDIRS gets the policy for paths anyone can read and write. It's the initial default one.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the policy
+*/
+func PermsOpenFILESDIRS() PermsPolicy {
+	return PermsPolicy{Dirs: 0o777, Files: 0o777}
+}
+
+/*
+PermsPrivateFILESDIRS gets the policy for paths only the owner can use, like ones with credentials.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the policy
+*/
+func PermsPrivateFILESDIRS() PermsPolicy {
+	return PermsPolicy{Dirs: 0o700, Files: 0o600}
+}
+
+/*
+PermsProgramsFILESDIRS gets the policy for programs, which anyone can run but only the owner can change.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the policy
+*/
+func PermsProgramsFILESDIRS() PermsPolicy {
+	return PermsPolicy{Dirs: 0o755, Files: 0o755}
+}
+
+// GPathInfo is the information about a path, got through GPath.Stat().
+type GPathInfo struct {
+	// Size is the size of the file in bytes (for directories, it's system-dependent).
+	Size int64
+	// Mod_time is the last modification time.
+	Mod_time time.Time
+	// Perms is the permissions of the path.
+	Perms os.FileMode
+	// Is_dir is true if the path is a directory (or a symbolic link to one).
+	Is_dir bool
+	// Is_symlink is true if the path is a symbolic link.
+	Is_symlink bool
+	// Link_target is the path the symbolic link points to, or "" if it's not a symbolic link.
+	Link_target string
+	// Uid is the ID of the user owning the path, or -1 if not available (like on Windows).
+	Uid int
+	// Gid is the ID of the group owning the path, or -1 if not available (like on Windows).
+	Gid int
+}
+
+/*
+SetDefaultPermsFILESDIRS sets the permissions policy of all the paths without one (check GPath.WithPerms()).
+
+-----------------------------------------------------------
+
+– Params:
+  - perms – the new default permissions policy
+*/
+func SetDefaultPermsFILESDIRS(perms PermsPolicy) {
+	perms_default_GL = perms
+}
+
+/*
+WithPerms gets a copy of the path with the given permissions policy, which is used to create it and is passed on to the
+paths added to it.
+
+An empty policy (PermsPolicy{}) removes the policy of the path, so that the default one is used.
+
+-----------------------------------------------------------
+
+– Params:
+  - perms – the permissions policy
+
+– Returns:
+  - the path with the permissions policy
+*/
+func (gPath GPath) WithPerms(perms PermsPolicy) GPath {
+	gPath.perms = perms
+
+	return gPath
+}
+
+/*
+Stat gets information about the path. If the path is a symbolic link, the information is about the link itself, except
+for GPathInfo.Is_dir.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the information about the path
+  - nil if the information was got successfully, an error otherwise
+*/
+func (gPath GPath) Stat() (GPathInfo, error) {
+	if err := gPath.IsSupported(); nil != err {
+		return GPathInfo{}, err
+	}
+
+	file_info, err := gPath.getFS().Lstat(gPath.p)
+	if nil != err {
+		return GPathInfo{}, err
+	}
+
+	uid, gid := getOwnerFILESDIRS(file_info)
+	var gPathInfo GPathInfo = GPathInfo{
+		Size:        file_info.Size(),
+		Mod_time:    file_info.ModTime(),
+		Perms:       file_info.Mode().Perm(),
+		Is_dir:      file_info.IsDir(),
+		Is_symlink:  0 != file_info.Mode() & os.ModeSymlink,
+		Link_target: "",
+		Uid:         uid,
+		Gid:         gid,
+	}
+	if gPathInfo.Is_symlink {
+		gPathInfo.Link_target, _ = gPath.getFS().Readlink(gPath.p)
+		if target_info, err := gPath.getFS().Stat(gPath.p); nil == err {
+			gPathInfo.Is_dir = target_info.IsDir()
+		}
+	}
+
+	return gPathInfo, nil
+}
+
+/*
+Chmod changes the permissions of the path.
+
+-----------------------------------------------------------
+
+– Params:
+  - perms – the new permissions
+
+– Returns:
+  - nil if the permissions were changed successfully, an error otherwise
+*/
+func (gPath GPath) Chmod(perms os.FileMode) error {
+	if err := gPath.IsSupported(); nil != err {
+		return err
+	}
+
+	return gPath.getFS().Chmod(gPath.p, perms)
+}
+
+/*
+getPerms gets the permissions policy to use to create the path.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the policy of the path or the default one if it has none
+*/
+func (gPath GPath) getPerms() PermsPolicy {
+	if (PermsPolicy{}) != gPath.perms {
+		return gPath.perms
+	}
+
+	return perms_default_GL
+}
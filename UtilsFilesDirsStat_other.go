@@ -0,0 +1,35 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+//go:build !(linux || darwin || freebsd || openbsd || netbsd || dragonfly)
+
+package Utils
+
+import (
+	"os"
+)
+
+/*
+getOwnerFILESDIRS is not supported on this OS. Check the Unix implementation for more information.
+*/
+func getOwnerFILESDIRS(file_info os.FileInfo) (int, int) {
+	return -1, -1
+}
@@ -0,0 +1,50 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+//go:build linux || darwin || freebsd || openbsd || netbsd || dragonfly
+
+package Utils
+
+import (
+	"os"
+	"syscall"
+)
+
+/*
+getOwnerFILESDIRS gets the owner of a path from its information.
+
+-----------------------------------------------------------
+
+– Params:
+  - file_info – the information about the path
+
+– Returns:
+  - the ID of the user owning the path, or -1 if not available
+  - the ID of the group owning the path, or -1 if not available
+*/
+func getOwnerFILESDIRS(file_info os.FileInfo) (int, int) {
+	stat, ok := file_info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return -1, -1
+	}
+
+	return int(stat.Uid), int(stat.Gid)
+}
@@ -0,0 +1,619 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package Utils
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// LINES_DEF_MAX_LEN is the default maximum length of a line in bytes for GPath.Lines() and GPath.Tail().
+	LINES_DEF_MAX_LEN int = 1024 * 1024
+	// _TAIL_DEF_POLL_INTERVAL is the default TailOptions.Poll_interval.
+	_TAIL_DEF_POLL_INTERVAL time.Duration = 500 * time.Millisecond
+	// _TAIL_CHUNK_SIZE is the size of the chunks read from the end of a file to find its last lines.
+	_TAIL_CHUNK_SIZE int64 = 4096
+)
+
+// ErrLineTooLong is returned by LineIterator.Err() when a line is longer than the maximum length.
+var ErrLineTooLong error = errors.New("line too long")
+
+/*
+LineIterator reads a file line by line without loading it all into memory, got through GPath.Lines().
+
+It's used like bufio.Scanner: call Next() until it returns false, get each line with Line() and in the end check Err().
+*/
+type LineIterator struct {
+	// file is the file being read.
+	file FSFile
+	// scanner is the scanner of the file.
+	scanner *bufio.Scanner
+	// err is the error that stopped the iteration, if any.
+	err error
+}
+
+// TailOptions is the options for GPath.Tail().
+type TailOptions struct {
+	// From_start is true to give all the lines already in the file first, false to give only the last Last_lines.
+	From_start bool
+	// Last_lines is the number of lines already in the file to give first (like "tail -n"), if From_start is false.
+	Last_lines int
+	// Poll_interval is the time between checks for new lines, or 0 for the default (500 ms).
+	Poll_interval time.Duration
+	// Max_line_len is the maximum length of a line in bytes, or 0 for the default (LINES_DEF_MAX_LEN). Longer lines
+	// are given in pieces of this length.
+	Max_line_len int
+}
+
+/*
+Tailer follows the lines added to a file (like "tail -F"), got through GPath.Tail().
+
+The file may not exist yet, be truncated or be replaced by another one (rotated) - the Tailer starts again from the
+beginning of the new contents.
+*/
+type Tailer struct {
+	// lines is the channel of the lines given to the user.
+	lines chan string
+	// stop is closed to stop the following.
+	stop chan struct{}
+	// stop_once makes sure stop is closed only once.
+	stop_once sync.Once
+	// done is closed when the goroutine of the tailer has finished.
+	done chan struct{}
+	// err is the error that stopped the tailer, if any - only to be read after done is closed.
+	err error
+}
+
+/*
+Open opens a file for reading.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the file, which must be closed when no longer needed, or nil if an error occurred
+  - nil if the file was opened successfully, an error otherwise (including if the path describes a directory)
+*/
+func (gPath GPath) Open() (FSFile, error) {
+	if gPath.dir {
+		return nil, errors.New("the path describes a directory")
+	}
+
+	return gPath.getFS().OpenFile(gPath.p, os.O_RDONLY, 0)
+}
+
+/*
+OpenCreate opens a file for writing, emptying it or creating it and any directories if necessary.
+
+It's the streaming version of WriteFile() (Create() only creates the path).
+
+-----------------------------------------------------------
+
+– Returns:
+  - the file, which must be closed when no longer needed, or nil if an error occurred
+  - nil if the file was opened successfully, an error otherwise (including if the path describes a directory)
+*/
+func (gPath GPath) OpenCreate() (FSFile, error) {
+	return gPath.openWrite(os.O_TRUNC)
+}
+
+/*
+OpenAppend opens a file for writing at its end, creating it and any directories if necessary.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the file, which must be closed when no longer needed, or nil if an error occurred
+  - nil if the file was opened successfully, an error otherwise (including if the path describes a directory)
+*/
+func (gPath GPath) OpenAppend() (FSFile, error) {
+	return gPath.openWrite(os.O_APPEND)
+}
+
+/*
+openWrite opens a file for writing, creating it and any directories if necessary, with the permissions policy of the
+path.
+
+-----------------------------------------------------------
+
+– Params:
+  - flag – the flag to add to os.O_WRONLY|os.O_CREATE (os.O_TRUNC or os.O_APPEND)
+
+– Returns:
+  - the file or nil if an error occurred
+  - nil if the file was opened successfully, an error otherwise
+*/
+func (gPath GPath) openWrite(flag int) (FSFile, error) {
+	if gPath.dir {
+		return nil, errors.New("the path describes a directory")
+	}
+
+	var existed bool = gPath.Exists()
+	if !existed {
+		if err := gPath.Dir().Create(false); nil != err {
+			return nil, err
+		}
+	}
+
+	var perms PermsPolicy = gPath.getPerms()
+	file, err := gPath.getFS().OpenFile(gPath.p, os.O_WRONLY|os.O_CREATE|flag, perms.Files)
+	if nil != err {
+		return nil, err
+	}
+	if !existed {
+		_ = gPath.getFS().Chmod(gPath.p, perms.Files)
+	}
+
+	return file, nil
+}
+
+/*
+Lines opens a text file to be read line by line.
+
+The line breaks can be "\n", "\r\n" or "\r" (just like with ReadTextFile()) and are not included in the lines.
+
+-----------------------------------------------------------
+
+– Params:
+  - max_line_len – the maximum length of a line in bytes, or 0 for the default (LINES_DEF_MAX_LEN) - reading stops with
+	ErrLineTooLong on a longer line
+
+– Returns:
+  - the iterator, which must be closed with LineIterator.Close() when no longer needed, or nil if an error occurred
+  - nil if the file was opened successfully, an error otherwise (including if the path describes a directory)
+*/
+func (gPath GPath) Lines(max_line_len int) (*LineIterator, error) {
+	if max_line_len <= 0 {
+		max_line_len = LINES_DEF_MAX_LEN
+	}
+
+	file, err := gPath.Open()
+	if nil != err {
+		return nil, err
+	}
+
+	var buf_len int = 4096
+	if max_line_len < buf_len {
+		buf_len = max_line_len
+	}
+	var scanner *bufio.Scanner = bufio.NewScanner(file)
+	// +2 for the line break, which is in the buffer too.
+	scanner.Buffer(make([]byte, 0, buf_len), max_line_len + 2)
+	scanner.Split(splitLinesFILESDIRS)
+
+	return &LineIterator{
+		file:    file,
+		scanner: scanner,
+	}, nil
+}
+
+/*
+Next advances to the next line.
+
+-----------------------------------------------------------
+
+– Returns:
+  - true if there's a line to get with Line(), false if the end of the file was reached or an error occurred
+*/
+func (lineIterator *LineIterator) Next() bool {
+	if nil != lineIterator.err {
+		return false
+	}
+
+	if lineIterator.scanner.Scan() {
+		return true
+	}
+
+	lineIterator.err = lineIterator.scanner.Err()
+	if errors.Is(lineIterator.err, bufio.ErrTooLong) {
+		lineIterator.err = ErrLineTooLong
+	}
+
+	return false
+}
+
+/*
+Line gets the current line.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the line, without the line break
+*/
+func (lineIterator *LineIterator) Line() string {
+	return lineIterator.scanner.Text()
+}
+
+/*
+Err gets the error that stopped the iteration.
+
+-----------------------------------------------------------
+
+– Returns:
+  - nil if the end of the file was reached (or the iteration hasn't stopped), the error otherwise
+*/
+func (lineIterator *LineIterator) Err() error {
+	return lineIterator.err
+}
+
+/*
+Close closes the file of the iterator.
+
+-----------------------------------------------------------
+
+– Returns:
+  - nil if the file was closed successfully, an error otherwise
+*/
+func (lineIterator *LineIterator) Close() error {
+	return lineIterator.file.Close()
+}
+
+/*
+Tail starts following the lines added to a file.
+
+-----------------------------------------------------------
+
+– Params:
+  - options – the options for the following or nil for the default ones (only new lines)
+
+– Returns:
+  - the tailer, which must be closed with Tailer.Close() when no longer needed, or nil if an error occurred
+  - nil if the file is being followed, an error otherwise (including if the path describes a directory)
+*/
+func (gPath GPath) Tail(options *TailOptions) (*Tailer, error) {
+	if gPath.dir {
+		return nil, errors.New("the path describes a directory")
+	}
+
+	var tailOptions TailOptions = TailOptions{}
+	if nil != options {
+		tailOptions = *options
+	}
+	if 0 == tailOptions.Poll_interval {
+		tailOptions.Poll_interval = _TAIL_DEF_POLL_INTERVAL
+	}
+	if tailOptions.Max_line_len <= 0 {
+		tailOptions.Max_line_len = LINES_DEF_MAX_LEN
+	}
+
+	// The starting point is got right away so that any lines added after this function returns are given.
+	var offset int64 = 0
+	file_info, err := gPath.getFS().Stat(gPath.p)
+	if nil == err {
+		if !tailOptions.From_start {
+			offset, err = gPath.getLastLinesOffset(file_info.Size(), tailOptions.Last_lines)
+			if nil != err {
+				return nil, err
+			}
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	} else {
+		file_info = nil
+	}
+
+	var tailer *Tailer = &Tailer{
+		lines: make(chan string, 64),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+
+	go tailer.follow(gPath, tailOptions, offset, file_info)
+
+	return tailer, nil
+}
+
+/*
+Lines gets the channel of the lines of the tailer (without the line breaks), which is closed after Tailer.Close() is
+called or if an error occurs.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the channel of the lines
+*/
+func (tailer *Tailer) Lines() <-chan string {
+	return tailer.lines
+}
+
+/*
+Err gets the error that stopped the tailer, after the lines channel is closed.
+
+-----------------------------------------------------------
+
+– Returns:
+  - nil if the tailer was closed (or is still running), the error otherwise
+*/
+func (tailer *Tailer) Err() error {
+	select {
+		case <-tailer.done:
+			return tailer.err
+		default:
+			return nil
+	}
+}
+
+/*
+Close stops the tailer and waits for it to finish. Calling it more than once does nothing.
+
+-----------------------------------------------------------
+
+– Returns:
+  - always nil (the return is for compatibility with io.Closer)
+*/
+func (tailer *Tailer) Close() error {
+	tailer.stop_once.Do(func() {
+		close(tailer.stop)
+	})
+
+	// Discard lines nobody reads so that the tailer can finish.
+	for {
+		select {
+			case <-tailer.done:
+				return nil
+			case <-tailer.lines:
+		}
+	}
+}
+
+/*
+follow checks the file for new lines periodically and gives them to the user, until stop is closed or an error occurs.
+
+-----------------------------------------------------------
+
+– Params:
+  - gPath – the path of the file
+  - tailOptions – the options for the following
+  - offset – the offset in the file where to start reading
+  - old_info – the information of the file when the following started, or nil if it didn't exist
+*/
+func (tailer *Tailer) follow(gPath GPath, tailOptions TailOptions, offset int64, old_info os.FileInfo) {
+	defer close(tailer.done)
+	defer close(tailer.lines)
+
+	var fileSystem FileSystem = gPath.getFS()
+	var partial []byte = nil
+
+	var ticker *time.Ticker = time.NewTicker(tailOptions.Poll_interval)
+	defer ticker.Stop()
+
+	for {
+		file_info, err := fileSystem.Stat(gPath.p)
+		if nil != err {
+			if !errors.Is(err, os.ErrNotExist) {
+				tailer.err = err
+
+				return
+			}
+
+			// Removed (maybe being rotated) - wait for it to come back.
+			file_info = nil
+		}
+
+		if nil != file_info {
+			// A file that was missing is a new one. Replaced files can only be recognized on the OS file system - on
+			// others only truncation is detected.
+			var replaced bool = nil == old_info || (gPath.isOsFS() && !os.SameFile(old_info, file_info))
+			if replaced || file_info.Size() < offset {
+				offset = 0
+				partial = nil
+			}
+
+			if file_info.Size() > offset {
+				var stopped bool = false
+				offset, partial, stopped, err = tailer.sendNewLines(fileSystem, gPath.p, offset, partial,
+					tailOptions.Max_line_len)
+				if nil != err {
+					tailer.err = err
+
+					return
+				}
+				if stopped {
+					return
+				}
+			}
+		}
+		old_info = file_info
+
+		select {
+			case <-tailer.stop:
+				return
+			case <-ticker.C:
+		}
+	}
+}
+
+/*
+getLastLinesOffset gets the offset in the file where its last lines start, reading it from the end.
+
+Only "\n" and "\r\n" are considered line breaks here.
+
+-----------------------------------------------------------
+
+– Params:
+  - size – the size of the file
+  - num_lines – the number of last lines
+
+– Returns:
+  - the offset where the last lines start (the size of the file if num_lines is 0 or less)
+  - nil if the offset was found successfully, an error otherwise
+*/
+func (gPath GPath) getLastLinesOffset(size int64, num_lines int) (int64, error) {
+	if num_lines <= 0 || 0 == size {
+		return size, nil
+	}
+
+	file, err := gPath.Open()
+	if nil != err {
+		return 0, err
+	}
+	defer file.Close()
+
+	var buf []byte = make([]byte, _TAIL_CHUNK_SIZE)
+	var end int64 = size
+	var found int = 0
+	for end > 0 {
+		var start int64 = end - _TAIL_CHUNK_SIZE
+		if start < 0 {
+			start = 0
+		}
+		var chunk []byte = buf[:end - start]
+		if _, err = file.Seek(start, io.SeekStart); nil != err {
+			return 0, err
+		}
+		if _, err = io.ReadFull(file, chunk); nil != err {
+			return 0, err
+		}
+
+		for i := len(chunk) - 1; i >= 0; i-- {
+			// The line break at the very end of the file ends the last line and doesn't start a new one.
+			if '\n' != chunk[i] || start + int64(i) == size - 1 {
+				continue
+			}
+
+			found++
+			if found == num_lines {
+				return start + int64(i) + 1, nil
+			}
+		}
+		end = start
+	}
+
+	return 0, nil
+}
+
+/*
+sendNewLines reads a file from the given offset to its end in chunks and gives each complete line to the user as soon as
+it's read, so that only one chunk and the unfinished line are in memory at a time.
+
+-----------------------------------------------------------
+
+– Params:
+  - fileSystem – the file system
+  - name – the path of the file
+  - offset – the offset where to start reading
+  - partial – the unfinished line read before
+  - max_line_len – the maximum length of a line in bytes (longer lines are given in pieces of this length)
+
+– Returns:
+  - the offset after the contents read
+  - the unfinished line at the end of the contents read
+  - true if stop was closed meanwhile, false otherwise
+  - nil if the file was read successfully, an error otherwise
+*/
+func (tailer *Tailer) sendNewLines(fileSystem FileSystem, name string, offset int64, partial []byte,
+			max_line_len int) (int64, []byte, bool, error) {
+	file, err := fileSystem.OpenFile(name, os.O_RDONLY, 0)
+	if nil != err {
+		return offset, partial, false, err
+	}
+	defer file.Close()
+
+	if _, err = file.Seek(offset, io.SeekStart); nil != err {
+		return offset, partial, false, err
+	}
+
+	var reader *bufio.Reader = bufio.NewReaderSize(file, int(_TAIL_CHUNK_SIZE))
+	var chunk []byte = make([]byte, _TAIL_CHUNK_SIZE)
+	for {
+		n, err := reader.Read(chunk)
+		offset += int64(n)
+		partial = append(partial, chunk[:n]...)
+
+		for {
+			var line []byte = nil
+			advance, token, _ := splitLinesFILESDIRS(partial, false)
+			if advance > 0 && len(token) <= max_line_len {
+				line = token
+			} else if len(partial) > max_line_len {
+				advance = max_line_len
+				line = partial[:advance]
+			} else {
+				break
+			}
+
+			select {
+				case tailer.lines <- string(line):
+				case <-tailer.stop:
+					return offset, partial, true, nil
+			}
+			partial = partial[advance:]
+		}
+
+		if io.EOF == err {
+			return offset, partial, false, nil
+		}
+		if nil != err {
+			return offset, partial, false, err
+		}
+	}
+}
+
+/*
+splitLinesFILESDIRS is a bufio.SplitFunc that splits lines ended by "\n", "\r\n" or "\r", without the line breaks.
+
+-----------------------------------------------------------
+
+– Params:
+  - data – the data not yet split
+  - at_eof – true if there's no more data after this
+
+– Returns:
+  - the number of bytes to advance, or 0 to ask for more data
+  - the line, or nil if there's none yet
+  - always nil
+*/
+func splitLinesFILESDIRS(data []byte, at_eof bool) (int, []byte, error) {
+	if at_eof && 0 == len(data) {
+		return 0, nil, nil
+	}
+
+	if idx := bytes.IndexAny(data, "\r\n"); idx >= 0 {
+		if '\r' == data[idx] {
+			if idx + 1 < len(data) {
+				if '\n' == data[idx + 1] {
+					return idx + 2, data[:idx], nil
+				}
+
+				return idx + 1, data[:idx], nil
+			}
+
+			if !at_eof {
+				// It may be a "\r\n" split in 2 - wait for more data.
+				return 0, nil, nil
+			}
+		}
+
+		return idx + 1, data[:idx], nil
+	}
+
+	if at_eof {
+		return len(data), data, nil
+	}
+
+	return 0, nil, nil
+}
@@ -0,0 +1,103 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package Utils
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTailFromStart(t *testing.T) {
+	var long_line string = strings.Repeat("x", 10000)
+
+	var tests = []struct {
+		name         string
+		contents     string
+		max_line_len int
+		want         []string
+	}{
+		{"empty", "", 0, nil},
+		{"mixed line breaks", "a\nb\r\nc\rd\n", 0, []string{"a", "b", "c", "d"}},
+		// Lines split between chunks must come out whole.
+		{"long line", long_line + "\nend\n", 0, []string{long_line, "end"}},
+		{"line too long", "abcdefghij\n", 4, []string{"abcd", "efgh", "ij"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var file GPath = PathFILESDIRS(false, "", "/file.txt").WithFS(NewMemFileSystemFILESDIRS())
+			if err := file.WriteTextFile(test.contents); nil != err {
+				t.Fatal(err)
+			}
+
+			tailer, err := file.Tail(&TailOptions{
+				From_start:    true,
+				Poll_interval: 10 * time.Millisecond,
+				Max_line_len:  test.max_line_len,
+			})
+			if nil != err {
+				t.Fatal(err)
+			}
+			defer tailer.Close()
+
+			for i, want := range test.want {
+				select {
+					case line := <-tailer.Lines():
+						if want != line {
+							t.Fatalf("line %d is %q, want %q", i, line, want)
+						}
+					case <-time.After(time.Second):
+						t.Fatalf("line %d never came", i)
+				}
+			}
+		})
+	}
+}
+
+func TestTailManyLines(t *testing.T) {
+	var builder strings.Builder
+	for i := 0; i < 5000; i++ {
+		builder.WriteString(strconv.Itoa(i) + "\n")
+	}
+	var file GPath = PathFILESDIRS(false, "", "/file.txt").WithFS(NewMemFileSystemFILESDIRS())
+	if err := file.WriteTextFile(builder.String()); nil != err {
+		t.Fatal(err)
+	}
+
+	tailer, err := file.Tail(&TailOptions{From_start: true, Poll_interval: 10 * time.Millisecond})
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer tailer.Close()
+
+	for i := 0; i < 5000; i++ {
+		select {
+			case line := <-tailer.Lines():
+				if strconv.Itoa(i) != line {
+					t.Fatalf("line %d is %q", i, line)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("line %d never came", i)
+		}
+	}
+}
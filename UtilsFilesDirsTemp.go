@@ -0,0 +1,153 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package Utils
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"time"
+)
+
+// _TEMP_NAME_MAX_TRIES is the number of random names tried for a temporary file or directory before giving up (the
+// same as os.MkdirTemp()).
+const _TEMP_NAME_MAX_TRIES int = 10000
+
+/*
+CreateTemp creates a new temporary file with a unique name inside the directory, creating the directory if necessary.
+The file gets the permissions of the permissions policy of the path.
+
+-----------------------------------------------------------
+
+– Params:
+  - pattern – the name of the file, where the last "*" is replaced by a random string (or with it added at the end if
+	there's no "*")
+
+– Returns:
+  - the path of the file
+  - the file, opened for reading and writing, which must be closed when no longer needed, or nil if an error occurred
+  - nil if the file was created successfully, an error otherwise (including if the path doesn't describe a directory)
+*/
+func (gPath GPath) CreateTemp(pattern string) (GPath, FSFile, error) {
+	if !gPath.dir {
+		return GPath{}, nil, errors.New("the path does not describe a directory")
+	}
+	if strings.ContainsAny(pattern, "/\\") {
+		return GPath{}, nil, errors.New("the pattern can't contain path separators")
+	}
+	if err := gPath.Create(false); nil != err {
+		return GPath{}, nil, err
+	}
+
+	file, err := gPath.getFS().CreateTemp(gPath.p, pattern)
+	if nil != err {
+		return GPath{}, nil, err
+	}
+	var temp_gPath GPath = gPath.Add2(false, PathFILESDIRS(false, gPath.s, file.Name()).Name())
+	// The file system always creates it with 0o600.
+	_ = gPath.getFS().Chmod(temp_gPath.p, gPath.getPerms().Files)
+
+	return temp_gPath, file, nil
+}
+
+/*
+MkdirTemp creates a new temporary directory with a unique name inside the directory, creating the directory if
+necessary. The directory gets the permissions of the permissions policy of the path.
+
+-----------------------------------------------------------
+
+– Params:
+  - pattern – the name of the directory, as in CreateTemp()
+
+– Returns:
+  - the path of the directory
+  - nil if the directory was created successfully, an error otherwise (including if the path doesn't describe a
+	directory or if no unused name was found)
+*/
+func (gPath GPath) MkdirTemp(pattern string) (GPath, error) {
+	if !gPath.dir {
+		return GPath{}, errors.New("the path does not describe a directory")
+	}
+	if strings.ContainsAny(pattern, "/\\") {
+		return GPath{}, errors.New("the pattern can't contain path separators")
+	}
+	if err := gPath.Create(false); nil != err {
+		return GPath{}, err
+	}
+
+	var prefix string = pattern
+	var suffix string = ""
+	if idx := strings.LastIndex(pattern, "*"); -1 != idx {
+		prefix = pattern[:idx]
+		suffix = pattern[idx + 1:]
+	}
+
+	var err error = nil
+	for i := 0; i < _TEMP_NAME_MAX_TRIES; i++ {
+		var dir GPath = gPath.Add2(true, prefix + RandStringGENERAL(RAND_STR_LEN) + suffix)
+		err = gPath.getFS().Mkdir(dir.p, gPath.getPerms().Dirs)
+		if nil == err {
+			_ = gPath.getFS().Chmod(dir.p, gPath.getPerms().Dirs)
+
+			return dir, nil
+		} else if !errors.Is(err, os.ErrExist) {
+			return GPath{}, err
+		}
+	}
+
+	return GPath{}, err
+}
+
+/*
+RemoveOlderThan removes the direct contents of the directory (with all their contents) that were last modified before
+the given age.
+
+-----------------------------------------------------------
+
+– Params:
+  - age – the age of the contents to remove, or 0 to remove all of them
+
+– Returns:
+  - the paths removed
+  - nil if the contents were removed successfully, an error otherwise
+*/
+func (gPath GPath) RemoveOlderThan(age time.Duration) ([]GPath, error) {
+	var filter *FileFilter = nil
+	if age > 0 {
+		filter = &FileFilter{Modified_before: time.Now().Add(-age)}
+	}
+
+	contents, err := gPath.List(filter)
+	if nil != err {
+		return nil, err
+	}
+
+	var removed []GPath = nil
+	for _, content := range contents {
+		if _, err = content.RemoveAll(nil); nil != err {
+			return removed, err
+		}
+		removed = append(removed, content)
+	}
+
+	return removed, nil
+}
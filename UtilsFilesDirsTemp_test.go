@@ -0,0 +1,90 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package Utils
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestTempPerms(t *testing.T) {
+	var tests = []struct {
+		name  string
+		perms PermsPolicy
+	}{
+		{"open", PermsOpenFILESDIRS()},
+		{"private", PermsPrivateFILESDIRS()},
+		{"programs", PermsProgramsFILESDIRS()},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var dir GPath = PathFILESDIRS(true, "", "/temp").WithFS(NewMemFileSystemFILESDIRS()).WithPerms(test.perms)
+
+			temp_dir, err := dir.MkdirTemp("dir_*")
+			if nil != err {
+				t.Fatal(err)
+			}
+			if gPathInfo, err := temp_dir.Stat(); nil != err || test.perms.Dirs != gPathInfo.Perms {
+				t.Errorf("directory perms = %v, %v, want %v", gPathInfo.Perms, err, test.perms.Dirs)
+			}
+
+			temp_file, file, err := dir.CreateTemp("file_*.txt")
+			if nil != err {
+				t.Fatal(err)
+			}
+			_ = file.Close()
+			if gPathInfo, err := temp_file.Stat(); nil != err || test.perms.Files != gPathInfo.Perms {
+				t.Errorf("file perms = %v, %v, want %v", gPathInfo.Perms, err, test.perms.Files)
+			}
+		})
+	}
+}
+
+func TestMkdirTempConcurrent(t *testing.T) {
+	var dir GPath = PathFILESDIRS(true, "", t.TempDir())
+	const goroutines_num int = 16
+	const dirs_num int = 50
+
+	var wait_group sync.WaitGroup
+	var errs chan error = make(chan error, goroutines_num * dirs_num)
+	for i := 0; i < goroutines_num; i++ {
+		wait_group.Add(1)
+		go func() {
+			defer wait_group.Done()
+			for j := 0; j < dirs_num; j++ {
+				_, err := dir.MkdirTemp("dir_*")
+				errs <- err
+			}
+		}()
+	}
+	wait_group.Wait()
+	close(errs)
+
+	for err := range errs {
+		if nil != err {
+			t.Fatal(err)
+		}
+	}
+	if paths, err := dir.List(nil); nil != err || goroutines_num * dirs_num != len(paths) {
+		t.Errorf("%d directories created (error %v), want %d", len(paths), err, goroutines_num * dirs_num)
+	}
+}
@@ -0,0 +1,529 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package Utils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"syscall"
+)
+
+const (
+	// SYMLINKS_KEEP is the TreeOptions.Symlinks mode that recreates the symbolic links themselves.
+	SYMLINKS_KEEP int = iota
+	// SYMLINKS_FOLLOW is the TreeOptions.Symlinks mode that copies what the symbolic links point to.
+	SYMLINKS_FOLLOW
+	// SYMLINKS_SKIP is the TreeOptions.Symlinks mode that ignores the symbolic links.
+	SYMLINKS_SKIP
+)
+
+// _ERROR_NOT_SAME_DEVICE is the Windows error returned when renaming to another drive.
+const _ERROR_NOT_SAME_DEVICE syscall.Errno = 17
+
+/*
+ProgressFunc is the type of the function called by the tree operations for each path processed.
+
+-----------------------------------------------------------
+
+– Params:
+  - gPath – the path just processed (the source one for copies and moves)
+  - bytes_done – the number of bytes of files processed so far
+  - bytes_total – the total number of bytes of files to process
+*/
+type ProgressFunc func(gPath GPath, bytes_done int64, bytes_total int64)
+
+// TreeOptions is the options for GPath.CopyTo(), GPath.MoveTo() and GPath.RemoveAll().
+type TreeOptions struct {
+	// Symlinks is what to do with symbolic links - one of the SYMLINKS_ constants. Ignored by RemoveAll(), which
+	// never follows them.
+	Symlinks int
+	// Progress is the function to call for each path processed or nil to ignore.
+	Progress ProgressFunc
+	// Dry_run is true to not change anything and only return the list of paths that would be processed.
+	Dry_run bool
+}
+
+// _TreeEntry is a path found inside a tree to be processed by the tree operations.
+type _TreeEntry struct {
+	// gPath is the path of the entry.
+	gPath GPath
+	// rel_path is the path relative to the root of the tree with "/" as the separator, or "" for the root itself.
+	rel_path string
+	// file_info is the information about the entry (about the target if the symbolic links are followed).
+	file_info os.FileInfo
+}
+
+/*
+CopyTo copies the path (and all its contents if it's a directory) to another one, keeping the permissions and
+modification times.
+
+The destination is the path of the copy itself, not where to copy it into. Existing files are replaced and existing
+directories are merged.
+
+-----------------------------------------------------------
+
+– Params:
+  - dst – the path of the copy
+  - options – the options for the copy or nil for the default ones
+
+– Returns:
+  - the paths copied (or that would be copied on a dry run), parents before children
+  - nil if everything was copied successfully, an error otherwise (including if both paths are the same)
+*/
+func (gPath GPath) CopyTo(dst GPath, options *TreeOptions) ([]GPath, error) {
+	options = getTreeOptionsFILESDIRS(options)
+
+	// Copying to itself would truncate the files before reading them.
+	if isSamePathFILESDIRS(gPath, dst) {
+		return nil, errors.New("the source and the destination are the same")
+	}
+
+	tree_entries, err := gPath.collectTree(options.Symlinks)
+	if nil != err {
+		return nil, err
+	}
+	if !options.Dry_run {
+		if err = dst.Dir().Create(false); nil != err {
+			return nil, err
+		}
+	}
+
+	var src_fs FileSystem = gPath.getFS()
+	var dst_fs FileSystem = dst.getFS()
+	var bytes_total int64 = getTreeSizeFILESDIRS(tree_entries)
+	var bytes_done int64 = 0
+	var gPaths []GPath = nil
+	var dirs_copied []_TreeEntry = nil
+	for _, tree_entry := range tree_entries {
+		var file_info os.FileInfo = tree_entry.file_info
+		var dst_path string = dst.p
+		if "" != tree_entry.rel_path {
+			dst_path = filepath.Join(dst.p, filepath.FromSlash(tree_entry.rel_path))
+		}
+
+		if !options.Dry_run {
+			if file_info.IsDir() {
+				err = mkdirAllFS(dst_fs, dst_path, dst.getPerms().Dirs)
+				if nil == err {
+					// The permissions and modification time are set at the end, since adding the contents would change
+					// the time and the permissions could forbid adding them.
+					dirs_copied = append(dirs_copied, _TreeEntry{
+						gPath:     PathFILESDIRS(true, dst.s, dst_path),
+						rel_path:  tree_entry.rel_path,
+						file_info: file_info,
+					})
+				}
+			} else if 0 != file_info.Mode() & os.ModeSymlink {
+				var link_target string
+				link_target, err = src_fs.Readlink(tree_entry.gPath.p)
+				if nil == err {
+					_ = dst_fs.Remove(dst_path)
+					err = dst_fs.Symlink(link_target, dst_path)
+				}
+			} else {
+				err = copyFileFILESDIRS(src_fs, tree_entry.gPath.p, dst_fs, dst_path, file_info.Mode().Perm(), false)
+				if nil == err {
+					err = dst_fs.Chtimes(dst_path, file_info.ModTime(), file_info.ModTime())
+				}
+			}
+			if nil != err {
+				return gPaths, err
+			}
+		}
+
+		if !file_info.IsDir() {
+			bytes_done += file_info.Size()
+		}
+		gPaths = append(gPaths, tree_entry.gPath)
+		if nil != options.Progress {
+			options.Progress(tree_entry.gPath, bytes_done, bytes_total)
+		}
+	}
+
+	// Children before parents, so that the parents' times are not changed afterwards.
+	for i := len(dirs_copied) - 1; i >= 0; i-- {
+		var file_info os.FileInfo = dirs_copied[i].file_info
+		_ = dst_fs.Chmod(dirs_copied[i].gPath.p, file_info.Mode().Perm())
+		_ = dst_fs.Chtimes(dirs_copied[i].gPath.p, file_info.ModTime(), file_info.ModTime())
+	}
+
+	return gPaths, nil
+}
+
+/*
+MoveTo moves the path (and all its contents if it's a directory) to another one.
+
+The path is renamed if possible. If it's on another device or file system (where renames don't work), it's copied with
+CopyTo() and then removed with RemoveAll().
+
+-----------------------------------------------------------
+
+– Params:
+  - dst – the new path
+  - options – the options for the move or nil for the default ones (only used if the path must be copied)
+
+– Returns:
+  - the paths moved (or that would be moved on a dry run)
+  - nil if everything was moved successfully, an error otherwise (including if both paths are the same)
+*/
+func (gPath GPath) MoveTo(dst GPath, options *TreeOptions) ([]GPath, error) {
+	options = getTreeOptionsFILESDIRS(options)
+
+	if isSamePathFILESDIRS(gPath, dst) {
+		return nil, errors.New("the source and the destination are the same")
+	}
+
+	if options.Dry_run {
+		return gPath.CopyTo(dst, options)
+	}
+
+	if err := dst.Dir().Create(false); nil != err {
+		return nil, err
+	}
+
+	if isSameFSFILESDIRS(gPath.getBaseFS(), dst.getBaseFS()) {
+		// Each path is checked against its own sandbox.
+		if !gPath.isInSandbox() || !dst.isInSandbox() {
+			return nil, &os.LinkError{Op: "rename", Old: gPath.p, New: dst.p, Err: ErrOutOfSandbox}
+		}
+
+		var err error = gPath.getBaseFS().Rename(gPath.p, dst.p)
+		if nil == err {
+			if nil != options.Progress {
+				options.Progress(gPath, 0, 0)
+			}
+
+			return []GPath{gPath}, nil
+		}
+		if !isCrossDeviceErrFILESDIRS(err) {
+			return nil, err
+		}
+	}
+
+	gPaths, err := gPath.CopyTo(dst, options)
+	if nil != err {
+		return gPaths, err
+	}
+	_, err = gPath.RemoveAll(&TreeOptions{})
+
+	return gPaths, err
+}
+
+/*
+RemoveAll removes the path and all its contents if it's a directory. Symbolic links are removed, never followed.
+
+-----------------------------------------------------------
+
+– Params:
+  - options – the options for the removal or nil for the default ones
+
+– Returns:
+  - the paths removed (or that would be removed on a dry run), children before parents
+  - nil if everything was removed successfully (or if the path didn't exist), an error otherwise
+*/
+func (gPath GPath) RemoveAll(options *TreeOptions) ([]GPath, error) {
+	options = getTreeOptionsFILESDIRS(options)
+
+	tree_entries, err := gPath.collectTree(SYMLINKS_KEEP)
+	if nil != err {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	var bytes_total int64 = getTreeSizeFILESDIRS(tree_entries)
+	var bytes_done int64 = 0
+	var gPaths []GPath = nil
+	for i := len(tree_entries) - 1; i >= 0; i-- {
+		var tree_entry _TreeEntry = tree_entries[i]
+		if !options.Dry_run {
+			err = gPath.getFS().Remove(tree_entry.gPath.p)
+			if nil != err && !errors.Is(err, os.ErrNotExist) {
+				return gPaths, err
+			}
+		}
+
+		if !tree_entry.file_info.IsDir() {
+			bytes_done += tree_entry.file_info.Size()
+		}
+		gPaths = append(gPaths, tree_entry.gPath)
+		if nil != options.Progress {
+			options.Progress(tree_entry.gPath, bytes_done, bytes_total)
+		}
+	}
+
+	return gPaths, nil
+}
+
+/*
+collectTree gets all the entries of a tree, with each directory before its contents.
+
+-----------------------------------------------------------
+
+– Params:
+  - symlinks – what to do with the symbolic links - one of the SYMLINKS_ constants
+
+– Returns:
+  - the entries of the tree, starting by the path itself
+  - nil if the tree was read successfully, an error otherwise
+*/
+func (gPath GPath) collectTree(symlinks int) ([]_TreeEntry, error) {
+	if err := gPath.IsSupported(); nil != err {
+		return nil, err
+	}
+
+	var fileSystem FileSystem = gPath.getFS()
+	file_info, err := fileSystem.Lstat(gPath.p)
+	if nil != err {
+		return nil, err
+	}
+	if 0 != file_info.Mode() & os.ModeSymlink && SYMLINKS_FOLLOW == symlinks {
+		if file_info, err = fileSystem.Stat(gPath.p); nil != err {
+			return nil, err
+		}
+	}
+
+	var tree_entries []_TreeEntry = []_TreeEntry{{
+		gPath:     gPath,
+		rel_path:  "",
+		file_info: file_info,
+	}}
+	if !file_info.IsDir() {
+		return tree_entries, nil
+	}
+
+	var parent_dirs map[string]bool = map[string]bool{}
+	if real_path, err := fileSystem.EvalSymlinks(gPath.p); nil == err {
+		parent_dirs[real_path] = true
+	}
+
+	return collectTreeDirFILESDIRS(PathFILESDIRS(true, gPath.s, gPath), "", symlinks, parent_dirs, tree_entries)
+}
+
+/*
+collectTreeDirFILESDIRS is the recursive part of GPath.collectTree().
+
+-----------------------------------------------------------
+
+– Params:
+  - dir – the directory to collect the entries from
+  - rel_dir – the relative path of the directory to the root of the tree
+  - symlinks – same as in GPath.collectTree()
+  - parent_dirs – the real paths of the directory and of its parents, to not loop through symbolic links
+  - tree_entries – the entries collected so far
+
+– Returns:
+  - the entries collected so far plus the ones of the directory
+  - nil if the directory was read successfully, an error otherwise
+*/
+func collectTreeDirFILESDIRS(dir GPath, rel_dir string, symlinks int, parent_dirs map[string]bool,
+							 tree_entries []_TreeEntry) ([]_TreeEntry, error) {
+	var fileSystem FileSystem = dir.getFS()
+	entries, err := fileSystem.ReadDir(dir.p)
+	if nil != err {
+		return tree_entries, err
+	}
+
+	for _, entry := range entries {
+		var rel_path string = entry.Name()
+		if "" != rel_dir {
+			rel_path = rel_dir + "/" + entry.Name()
+		}
+
+		file_info, err := entry.Info()
+		if nil != err {
+			return tree_entries, err
+		}
+		var is_link bool = 0 != file_info.Mode() & os.ModeSymlink
+		if is_link {
+			if SYMLINKS_SKIP == symlinks {
+				continue
+			}
+			if SYMLINKS_FOLLOW == symlinks {
+				if file_info, err = fileSystem.Stat(filepath.Join(dir.p, entry.Name())); nil != err {
+					// Broken link - nothing to follow.
+					continue
+				}
+			}
+		}
+
+		var tree_entry _TreeEntry = _TreeEntry{
+			gPath:     dir.Add2(file_info.IsDir(), entry.Name()),
+			rel_path:  rel_path,
+			file_info: file_info,
+		}
+		if is_link && !file_info.IsDir() {
+			// Add2() follows the link to check if it's a directory, but here it's the link itself.
+			tree_entry.gPath.p = strings.TrimSuffix(tree_entry.gPath.p, tree_entry.gPath.s)
+			tree_entry.gPath.dir = false
+		}
+
+		var real_path string = ""
+		if file_info.IsDir() {
+			real_path, err = fileSystem.EvalSymlinks(tree_entry.gPath.p)
+			if nil != err || parent_dirs[real_path] {
+				// Loop (or broken path) - don't go through it again.
+				continue
+			}
+		}
+
+		tree_entries = append(tree_entries, tree_entry)
+
+		if file_info.IsDir() {
+			parent_dirs[real_path] = true
+			tree_entries, err = collectTreeDirFILESDIRS(tree_entry.gPath, rel_path, symlinks, parent_dirs, tree_entries)
+			delete(parent_dirs, real_path)
+			if nil != err {
+				return tree_entries, err
+			}
+		}
+	}
+
+	return tree_entries, nil
+}
+
+/*
+getTreeOptionsFILESDIRS gets the options to use in the tree operations.
+
+-----------------------------------------------------------
+
+– Params:
+  - options – the options given to the operation
+
+– Returns:
+  - the given options or the default ones if they are nil
+*/
+func getTreeOptionsFILESDIRS(options *TreeOptions) *TreeOptions {
+	if nil == options {
+		return &TreeOptions{}
+	}
+
+	return options
+}
+
+/*
+getTreeSizeFILESDIRS gets the sum of the sizes of the files of a tree.
+
+-----------------------------------------------------------
+
+– Params:
+  - tree_entries – the entries of the tree
+
+– Returns:
+  - the size of all the files in bytes
+*/
+func getTreeSizeFILESDIRS(tree_entries []_TreeEntry) int64 {
+	var size int64 = 0
+	for _, tree_entry := range tree_entries {
+		if !tree_entry.file_info.IsDir() {
+			size += tree_entry.file_info.Size()
+		}
+	}
+
+	return size
+}
+
+/*
+isSamePathFILESDIRS checks if two paths are the same file or directory.
+
+-----------------------------------------------------------
+
+– Params:
+  - gPath1 – one of the paths
+  - gPath2 – the other path
+
+– Returns:
+  - true if both are on the same file system and are the same path (or the same file through links on the OS file
+	system), false otherwise
+*/
+func isSamePathFILESDIRS(gPath1 GPath, gPath2 GPath) bool {
+	if !isSameFSFILESDIRS(gPath1.getBaseFS(), gPath2.getBaseFS()) {
+		return false
+	}
+
+	var path1 string = filepath.Clean(gPath1.p)
+	var path2 string = filepath.Clean(gPath2.p)
+	if path1 == path2 || ("windows" == runtime.GOOS && strings.EqualFold(path1, path2)) {
+		return true
+	}
+
+	if gPath1.isOsFS() {
+		file_info1, err1 := os.Stat(path1)
+		file_info2, err2 := os.Stat(path2)
+
+		return nil == err1 && nil == err2 && os.SameFile(file_info1, file_info2)
+	}
+
+	return false
+}
+
+/*
+isSameFSFILESDIRS checks if two file systems are the same backend.
+
+-----------------------------------------------------------
+
+– Params:
+  - fileSystem1 – one of the file systems
+  - fileSystem2 – the other file system
+
+– Returns:
+  - true if they're the same, false if they're not or if it can't be known (types that can't be compared)
+*/
+func isSameFSFILESDIRS(fileSystem1 FileSystem, fileSystem2 FileSystem) bool {
+	var type1 reflect.Type = reflect.TypeOf(fileSystem1)
+	if type1 != reflect.TypeOf(fileSystem2) || nil == type1 || !type1.Comparable() {
+		return false
+	}
+
+	// Only compared now that it's known to not panic.
+	return fileSystem1 == fileSystem2
+}
+
+/*
+isCrossDeviceErrFILESDIRS checks if an error was caused by trying to rename a path to another device.
+
+-----------------------------------------------------------
+
+– Params:
+  - err – the error to check
+
+– Returns:
+  - true if the error is a cross-device one, false otherwise
+*/
+func isCrossDeviceErrFILESDIRS(err error) bool {
+	var errno syscall.Errno
+	if !errors.As(err, &errno) {
+		return false
+	}
+
+	if "windows" == runtime.GOOS {
+		return _ERROR_NOT_SAME_DEVICE == errno
+	}
+
+	return syscall.EXDEV == errno
+}
@@ -0,0 +1,99 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package Utils
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestCopyToSamePath(t *testing.T) {
+	var dir string = t.TempDir()
+	var file GPath = PathFILESDIRS(false, "", filepath.Join(dir, "file.txt"))
+	if err := file.WriteTextFile("contents"); nil != err {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		name string
+		dst  GPath
+	}{
+		{"same path", file},
+		{"same path uncleaned", PathFILESDIRS(false, "", dir + "/./file.txt")},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := file.CopyTo(test.dst, nil); nil == err {
+				t.Error("CopyTo() to the same path didn't fail")
+			}
+			if _, err := file.MoveTo(test.dst, nil); nil == err {
+				t.Error("MoveTo() to the same path didn't fail")
+			}
+			if p_contents := file.ReadTextFile(); nil == p_contents || "contents" != *p_contents {
+				t.Error("the source was changed")
+			}
+		})
+	}
+}
+
+func TestCopyToOtherFS(t *testing.T) {
+	var memFS *MemFileSystem = NewMemFileSystemFILESDIRS()
+	var src GPath = PathFILESDIRS(false, "", "/src/file.txt").WithFS(memFS)
+	if err := src.WriteTextFile("contents"); nil != err {
+		t.Fatal(err)
+	}
+
+	var dst GPath = PathFILESDIRS(false, "", filepath.Join(t.TempDir(), "file.txt"))
+	if _, err := src.MoveTo(dst, nil); nil != err {
+		t.Fatal(err)
+	}
+	if p_contents := dst.ReadTextFile(); nil == p_contents || "contents" != *p_contents {
+		t.Error("the file was not moved to the other file system")
+	}
+	if src.Exists() {
+		t.Error("the source still exists")
+	}
+}
+
+func TestIsSameFS(t *testing.T) {
+	var memFS1 *MemFileSystem = NewMemFileSystemFILESDIRS()
+	var memFS2 *MemFileSystem = NewMemFileSystemFILESDIRS()
+
+	var tests = []struct {
+		name        string
+		fileSystem1 FileSystem
+		fileSystem2 FileSystem
+		want        bool
+	}{
+		{"same memory", memFS1, memFS1, true},
+		{"other memory", memFS1, memFS2, false},
+		{"OS and memory", OsFileSystem{}, memFS1, false},
+		{"OS", OsFileSystem{}, OsFileSystem{}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := isSameFSFILESDIRS(test.fileSystem1, test.fileSystem2); got != test.want {
+				t.Errorf("isSameFSFILESDIRS() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package Utils
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// DiskUsage is the space used by a directory.
+type DiskUsage struct {
+	// Size is the sum of the sizes of the files in bytes.
+	Size int64
+	// Files is the number of files.
+	Files int
+	// Dirs is the number of directories (not counting the directory itself).
+	Dirs int
+}
+
+// RetentionPolicy is the policy of which files to keep in a directory, for GPath.ApplyRetention().
+type RetentionPolicy struct {
+	// Max_size is the maximum size of all the files in the directory in bytes, or 0 for no limit.
+	Max_size int64
+	// Max_age is the maximum time since the last modification of the files, or 0 for no limit.
+	Max_age time.Duration
+	// Max_count is the maximum number of files to leave in the directory, not counting the ones kept by Keep (the oldest
+	// of the others are removed until at most this many are left), or 0 for no limit.
+	Max_count int
+	// Keep is the patterns (as in GPath.Glob(), relative to the directory) of the files that are never removed. A
+	// directory that matches keeps all its contents. They still count for Max_size.
+	Keep []string
+	// Dry_run is true to not remove anything and only report what would be removed.
+	Dry_run bool
+}
+
+// RetentionReport is the result of GPath.ApplyRetention().
+type RetentionReport struct {
+	// Removed is the files removed, oldest first.
+	Removed []GPath
+	// Bytes_freed is the sum of the sizes of the files removed.
+	Bytes_freed int64
+	// Usage is the space used by the directory after the removals.
+	Usage DiskUsage
+}
+
+// _RetentionFile is a file that can be removed by a retention policy.
+type _RetentionFile struct {
+	// gPath is the path of the file.
+	gPath GPath
+	// size is the size of the file in bytes.
+	size int64
+	// mod_time is the modification time of the file.
+	mod_time time.Time
+}
+
+/*
+GetUsage gets the space used by the directory, with all its contents.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the space used (all zeros if the directory doesn't exist)
+  - nil if the space was got successfully, an error otherwise
+*/
+func (gPath GPath) GetUsage() (DiskUsage, error) {
+	var diskUsage DiskUsage = DiskUsage{}
+	if !gPath.Exists() {
+		return diskUsage, nil
+	}
+
+	var err_stat error = nil
+	var err error = gPath.Walk(nil, func(found GPath, rel_path string) int {
+		if found.dir {
+			diskUsage.Dirs++
+
+			return WALK_CONTINUE
+		}
+
+		var gPathInfo GPathInfo
+		if gPathInfo, err_stat = found.Stat(); nil != err_stat {
+			return WALK_STOP
+		}
+		diskUsage.Size += gPathInfo.Size
+		diskUsage.Files++
+
+		return WALK_CONTINUE
+	})
+	if nil != err_stat {
+		return diskUsage, err_stat
+	}
+
+	return diskUsage, err
+}
+
+/*
+ApplyRetention removes the files of the directory (recursively) not allowed by a retention policy, oldest first: first
+the ones older than Max_age, then the oldest ones until both Max_count and Max_size are respected (if possible with the
+files not kept by Keep). Directories left empty are removed too.
+
+-----------------------------------------------------------
+
+– Params:
+  - policy – the retention policy
+
+– Returns:
+  - the report of what was removed (or would be, with Dry_run)
+  - nil if the policy was applied successfully, an error otherwise (the report has what was removed until then)
+*/
+func (gPath GPath) ApplyRetention(policy RetentionPolicy) (RetentionReport, error) {
+	var retentionReport RetentionReport = RetentionReport{}
+	if !gPath.dir {
+		return retentionReport, errors.New("the path does not describe a directory")
+	}
+
+	var retention_files []_RetentionFile = nil
+	var err_stat error = nil
+	var err error = gPath.Walk(nil, func(found GPath, rel_path string) int {
+		if found.dir {
+			retentionReport.Usage.Dirs++
+
+			return WALK_CONTINUE
+		}
+
+		var gPathInfo GPathInfo
+		if gPathInfo, err_stat = found.Stat(); nil != err_stat {
+			return WALK_STOP
+		}
+		retentionReport.Usage.Size += gPathInfo.Size
+		retentionReport.Usage.Files++
+
+		// The files to keep can't be removed, but count for the size.
+		if isPathWantedFILESDIRS(rel_path, nil, policy.Keep) {
+			retention_files = append(retention_files, _RetentionFile{
+				gPath:    found,
+				size:     gPathInfo.Size,
+				mod_time: gPathInfo.Mod_time,
+			})
+		}
+
+		return WALK_CONTINUE
+	})
+	if nil != err_stat {
+		return retentionReport, err_stat
+	}
+	if nil != err {
+		return retentionReport, err
+	}
+
+	// Oldest first.
+	sort.SliceStable(retention_files, func(i int, j int) bool {
+		return retention_files[i].mod_time.Before(retention_files[j].mod_time)
+	})
+
+	var min_mod_time time.Time
+	if policy.Max_age > 0 {
+		min_mod_time = time.Now().Add(-policy.Max_age)
+	}
+	var count int = len(retention_files)
+	for _, retention_file := range retention_files {
+		var too_old bool = policy.Max_age > 0 && retention_file.mod_time.Before(min_mod_time)
+		var too_many bool = policy.Max_count > 0 && count > policy.Max_count
+		var too_big bool = policy.Max_size > 0 && retentionReport.Usage.Size > policy.Max_size
+		if !too_old && !too_many && !too_big {
+			// The files are sorted, so the next ones are even newer.
+			break
+		}
+
+		if !policy.Dry_run {
+			if err = retention_file.gPath.Remove(); nil != err {
+				return retentionReport, err
+			}
+			retentionReport.Usage.Dirs -= gPath.removeEmptyParents(retention_file.gPath)
+		}
+		count--
+		retentionReport.Usage.Size -= retention_file.size
+		retentionReport.Usage.Files--
+		retentionReport.Bytes_freed += retention_file.size
+		retentionReport.Removed = append(retentionReport.Removed, retention_file.gPath)
+	}
+
+	return retentionReport, nil
+}
+
+/*
+removeEmptyParents removes the parent directories of a removed path that were left empty, up to the directory (not
+included).
+
+-----------------------------------------------------------
+
+– Params:
+  - removed – the path removed
+
+– Returns:
+  - the number of directories removed
+*/
+func (gPath GPath) removeEmptyParents(removed GPath) int {
+	var num_removed int = 0
+	var parent GPath = removed.Dir()
+	for len(parent.p) > len(gPath.p) {
+		entries, err := parent.getFS().ReadDir(parent.p)
+		if nil != err || 0 != len(entries) || nil != parent.Remove() {
+			break
+		}
+		num_removed++
+		parent = parent.Dir()
+	}
+
+	return num_removed
+}
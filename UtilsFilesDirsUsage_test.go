@@ -0,0 +1,103 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package Utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestApplyRetention(t *testing.T) {
+	// The files by age in days (and size in bytes, the same as the age).
+	var files_ages map[string]int = map[string]int{
+		"keep.json": 10,
+		"a.log":     9,
+		"old/b.log": 8,
+		"c.log":     3,
+		"d.log":     1,
+	}
+
+	var tests = []struct {
+		name        string
+		policy      RetentionPolicy
+		wantRemoved []string
+		wantSize    int64
+		wantDirs    int
+	}{
+		{"no limits", RetentionPolicy{}, nil, 31, 1},
+		{"max age", RetentionPolicy{Max_age: 5 * 24 * time.Hour, Keep: []string{"*.json"}},
+			[]string{"a.log", "old/b.log"}, 14, 0},
+		{"max count", RetentionPolicy{Max_count: 1, Keep: []string{"*.json"}},
+			[]string{"a.log", "old/b.log", "c.log"}, 11, 0},
+		{"max count counts the kept files out", RetentionPolicy{Max_count: 4, Keep: []string{"*.json"}},
+			nil, 31, 1},
+		{"max size", RetentionPolicy{Max_size: 20, Keep: []string{"*.json"}}, []string{"a.log", "old/b.log"}, 14, 0},
+		{"max size not reachable without the kept files", RetentionPolicy{Max_size: 5, Keep: []string{"*.json"}},
+			[]string{"a.log", "old/b.log", "c.log", "d.log"}, 10, 0},
+		{"nothing kept", RetentionPolicy{Max_size: 5}, []string{"keep.json", "a.log", "old/b.log"}, 4, 0},
+		{"dry run", RetentionPolicy{Max_count: 1, Keep: []string{"*.json"}, Dry_run: true},
+			[]string{"a.log", "old/b.log", "c.log"}, 11, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var memFS *MemFileSystem = NewMemFileSystemFILESDIRS()
+			var dir GPath = PathFILESDIRS(true, "", "/dir").WithFS(memFS)
+			for name, age := range files_ages {
+				var file GPath = dir.Add2(false, name)
+				if err := file.WriteTextFile(strings.Repeat("x", age)); nil != err {
+					t.Fatal(err)
+				}
+				var mod_time time.Time = time.Now().Add(-time.Duration(age) * 24 * time.Hour)
+				if err := memFS.Chtimes(file.p, mod_time, mod_time); nil != err {
+					t.Fatal(err)
+				}
+			}
+
+			retentionReport, err := dir.ApplyRetention(test.policy)
+			if nil != err {
+				t.Fatal(err)
+			}
+
+			var removed []string = nil
+			for _, gPath := range retentionReport.Removed {
+				removed = append(removed, strings.TrimPrefix(strings.ReplaceAll(gPath.p, dir.s, "/"), "/dir/"))
+			}
+			if !reflect.DeepEqual(removed, test.wantRemoved) {
+				t.Errorf("removed %v, want %v", removed, test.wantRemoved)
+			}
+			if test.wantSize != retentionReport.Usage.Size || 31 - test.wantSize != retentionReport.Bytes_freed {
+				t.Errorf("size %d, freed %d, want size %d", retentionReport.Usage.Size, retentionReport.Bytes_freed,
+					test.wantSize)
+			}
+			if test.wantDirs != retentionReport.Usage.Dirs {
+				t.Errorf("directories %d, want %d", retentionReport.Usage.Dirs, test.wantDirs)
+			}
+			for _, gPath := range retentionReport.Removed {
+				if gPath.Exists() != test.policy.Dry_run {
+					t.Errorf("%s exists = %v", gPath.p, gPath.Exists())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,395 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package Utils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// FILE_TYPE_ANY is the FileFilter.Type that accepts both files and directories.
+	FILE_TYPE_ANY int = iota
+	// FILE_TYPE_FILE is the FileFilter.Type that accepts only files.
+	FILE_TYPE_FILE
+	// FILE_TYPE_DIR is the FileFilter.Type that accepts only directories.
+	FILE_TYPE_DIR
+)
+
+const (
+	// WALK_CONTINUE is the return of a WalkFunc to continue the walk normally.
+	WALK_CONTINUE int = iota
+	// WALK_SKIP_DIR is the return of a WalkFunc to not go inside the directory just given to it (same as
+	// WALK_CONTINUE for files).
+	WALK_SKIP_DIR
+	// WALK_STOP is the return of a WalkFunc to stop the walk right away.
+	WALK_STOP
+)
+
+/*
+FileFilter is a filter for the paths returned by GPath.List(), GPath.Walk() and GPath.Glob().
+
+The zero value accepts everything. Use nil on the functions for the same effect.
+*/
+type FileFilter struct {
+	// Name_pattern is the filepath.Match() pattern the name of the file or directory must match, or "" for any name.
+	Name_pattern string
+	// Type is the type of the paths to accept - one of the FILE_TYPE_ constants.
+	Type int
+	// Min_size is the minimum size of the files in bytes, or 0 to ignore. Directories ignore the size filters.
+	Min_size int64
+	// Max_size is the maximum size of the files in bytes, or 0 to ignore.
+	Max_size int64
+	// Modified_after only accepts paths modified after this time, or the zero time to ignore.
+	Modified_after time.Time
+	// Modified_before only accepts paths modified before this time, or the zero time to ignore.
+	Modified_before time.Time
+	// Max_results is the maximum number of paths returned by List() and Glob(), or 0 for no limit.
+	Max_results int
+}
+
+/*
+WalkFunc is the type of the function called by GPath.Walk() for each accepted path.
+
+-----------------------------------------------------------
+
+– Params:
+  - gPath – the path found, describing a directory or a file as it is on the disk
+  - rel_path – the path relative to the walked directory, with "/" as the separator
+
+– Returns:
+  - one of the WALK_ constants
+*/
+type WalkFunc func(gPath GPath, rel_path string) int
+
+// errWalkStop is used internally to stop the walk when the WalkFunc returns WALK_STOP.
+var errWalkStop error = errors.New("walk stopped")
+
+/*
+List lists the contents of a directory (not recursively), sorted by name.
+
+-----------------------------------------------------------
+
+– Params:
+  - filter – the filter for the paths to return or nil to return all
+
+– Returns:
+  - the paths in the directory, each describing a directory or a file as it is on the disk
+  - nil if the directory was listed successfully, an error otherwise (including if the path describes a file)
+*/
+func (gPath GPath) List(filter *FileFilter) ([]GPath, error) {
+	if err := gPath.IsSupported(); nil != err {
+		return nil, err
+	}
+	if !gPath.dir {
+		return nil, errors.New("the path does not describe a directory")
+	}
+
+	entries, err := gPath.getFS().ReadDir(gPath.p)
+	if nil != err {
+		return nil, err
+	}
+
+	var gPaths []GPath = nil
+	for _, entry := range entries {
+		if nil != filter && filter.Max_results > 0 && len(gPaths) >= filter.Max_results {
+			break
+		}
+
+		accepted, err := filter.accepts(entry)
+		if nil != err {
+			// The entry may have been removed in the meantime.
+			continue
+		}
+		if accepted {
+			gPaths = append(gPaths, gPath.Add2(entry.IsDir(), entry.Name()))
+		}
+	}
+
+	return gPaths, nil
+}
+
+/*
+Walk goes through all the contents of a directory recursively, calling walkFunc for each accepted path, in lexical order
+and with each directory before its contents.
+
+Symbolic links are reported but not followed. The filter only decides what's given to walkFunc - directories not
+accepted are still walked into.
+
+-----------------------------------------------------------
+
+– Params:
+  - filter – the filter for the paths to give to walkFunc or nil to give all
+  - walkFunc – the function to call for each accepted path
+
+– Returns:
+  - nil if the walk finished or was stopped by walkFunc, an error otherwise (including if the path describes a file)
+*/
+func (gPath GPath) Walk(filter *FileFilter, walkFunc WalkFunc) error {
+	if err := gPath.IsSupported(); nil != err {
+		return err
+	}
+	if !gPath.dir {
+		return errors.New("the path does not describe a directory")
+	}
+
+	var err error = walkDirFILESDIRS(gPath, "", filter, walkFunc)
+	if errWalkStop == err {
+		return nil
+	}
+
+	return err
+}
+
+/*
+Glob finds all the paths inside a directory whose relative path matches the given pattern, in lexical order.
+
+The pattern uses "/" as the separator and each of its components follows the filepath.Match() syntax, with the
+addition of "**", which matches any number of directories (including none). For example, "**" followed by "/*.json"
+matches all JSON files at any depth and "data/UserData/MOD_?" followed by "/PID=*" matches the PID files of all modules.
+
+-----------------------------------------------------------
+
+– Params:
+  - pattern – the pattern to match the relative paths against
+  - filter – an additional filter for the paths to return or nil to ignore
+
+– Returns:
+  - the matching paths, each describing a directory or a file as it is on the disk
+  - nil if the search finished successfully, an error otherwise (including a malformed pattern)
+*/
+func (gPath GPath) Glob(pattern string, filter *FileFilter) ([]GPath, error) {
+	pattern = strings.ReplaceAll(pattern, "\\", "/")
+	var pattern_parts []string = strings.Split(strings.Trim(pattern, "/"), "/")
+	for _, pattern_part := range pattern_parts {
+		if _, err := filepath.Match(pattern_part, ""); nil != err {
+			return nil, err
+		}
+	}
+
+	var gPaths []GPath = nil
+	var err error = gPath.Walk(filter, func(found GPath, rel_path string) int {
+		if matchGlobFILESDIRS(pattern_parts, strings.Split(rel_path, "/")) {
+			gPaths = append(gPaths, found)
+			if nil != filter && filter.Max_results > 0 && len(gPaths) >= filter.Max_results {
+				return WALK_STOP
+			}
+		}
+
+		return WALK_CONTINUE
+	})
+
+	return gPaths, err
+}
+
+/*
+walkDirFILESDIRS is the recursive part of GPath.Walk().
+
+-----------------------------------------------------------
+
+– Params:
+  - dir – the directory to walk
+  - rel_dir – the relative path of the directory to the walked one, or "" for the walked one itself
+  - filter – same as in GPath.Walk()
+  - walkFunc – same as in GPath.Walk()
+
+– Returns:
+  - nil if the walk finished, errWalkStop if walkFunc stopped it, another error otherwise
+*/
+func walkDirFILESDIRS(dir GPath, rel_dir string, filter *FileFilter, walkFunc WalkFunc) error {
+	entries, err := dir.getFS().ReadDir(dir.p)
+	if nil != err {
+		return err
+	}
+
+	for _, entry := range entries {
+		var rel_path string = entry.Name()
+		if "" != rel_dir {
+			rel_path = rel_dir + "/" + entry.Name()
+		}
+		var found GPath = dir.Add2(entry.IsDir(), entry.Name())
+
+		var ret int = WALK_CONTINUE
+		if accepted, err := filter.accepts(entry); nil == err && accepted {
+			ret = walkFunc(found, rel_path)
+		}
+		switch ret {
+			case WALK_STOP:
+				return errWalkStop
+			case WALK_SKIP_DIR:
+				continue
+		}
+
+		if entry.IsDir() {
+			if err = walkDirFILESDIRS(found, rel_path, filter, walkFunc); nil != err {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+/*
+matchGlobFILESDIRS checks if a relative path matches a pattern, both already split by "/".
+
+-----------------------------------------------------------
+
+– Params:
+  - pattern_parts – the components of the pattern
+  - path_parts – the components of the path
+
+– Returns:
+  - true if the path matches the pattern, false otherwise
+*/
+func matchGlobFILESDIRS(pattern_parts []string, path_parts []string) bool {
+	if 0 == len(pattern_parts) {
+		return 0 == len(path_parts)
+	}
+
+	if "**" == pattern_parts[0] {
+		// Try to match the rest of the pattern with every suffix of the path (including the path itself).
+		for i := 0; i <= len(path_parts); i++ {
+			if matchGlobFILESDIRS(pattern_parts[1:], path_parts[i:]) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if 0 == len(path_parts) {
+		return false
+	}
+	if matched, _ := filepath.Match(pattern_parts[0], path_parts[0]); !matched {
+		return false
+	}
+
+	return matchGlobFILESDIRS(pattern_parts[1:], path_parts[1:])
+}
+
+/*
+accepts checks if a directory entry passes the filter.
+
+-----------------------------------------------------------
+
+– Params:
+  - entry – the directory entry to check
+
+– Returns:
+  - true if the entry passes the filter (always if the filter is nil), false otherwise
+  - nil if the entry information could be read, an error otherwise
+*/
+func (filter *FileFilter) accepts(entry os.DirEntry) (bool, error) {
+	if nil == filter {
+		return true, nil
+	}
+
+	switch filter.Type {
+		case FILE_TYPE_FILE:
+			if entry.IsDir() {
+				return false, nil
+			}
+		case FILE_TYPE_DIR:
+			if !entry.IsDir() {
+				return false, nil
+			}
+	}
+
+	if "" != filter.Name_pattern {
+		matched, err := filepath.Match(filter.Name_pattern, entry.Name())
+		if nil != err {
+			return false, err
+		}
+		if !matched {
+			return false, nil
+		}
+	}
+
+	if 0 == filter.Min_size && 0 == filter.Max_size && filter.Modified_after.IsZero() &&
+				filter.Modified_before.IsZero() {
+		// No need to get the file information.
+		return true, nil
+	}
+
+	file_info, err := entry.Info()
+	if nil != err {
+		return false, err
+	}
+
+	if !entry.IsDir() {
+		if filter.Min_size > 0 && file_info.Size() < filter.Min_size {
+			return false, nil
+		}
+		if filter.Max_size > 0 && file_info.Size() > filter.Max_size {
+			return false, nil
+		}
+	}
+	if !filter.Modified_after.IsZero() && !file_info.ModTime().After(filter.Modified_after) {
+		return false, nil
+	}
+	if !filter.Modified_before.IsZero() && !file_info.ModTime().Before(filter.Modified_before) {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+/*
+isPathWantedFILESDIRS checks if a relative path passes include and exclude patterns (as in GPath.Glob()). A pattern that
+matches a directory matches all its contents too.
+
+-----------------------------------------------------------
+
+– Params:
+  - rel_path – the relative path with "/" as the separator
+  - include – the patterns of the paths to accept, or nil to accept all
+  - exclude – the patterns of the paths to reject, which take precedence over include
+
+– Returns:
+  - true if the path passes the patterns, false otherwise
+*/
+func isPathWantedFILESDIRS(rel_path string, include []string, exclude []string) bool {
+	var path_parts []string = strings.Split(rel_path, "/")
+	var matches = func(patterns []string) bool {
+		for _, pattern := range patterns {
+			var pattern_parts []string = strings.Split(strings.Trim(strings.ReplaceAll(pattern, "\\", "/"), "/"), "/")
+			// The path or any of its parent directories.
+			for i := 1; i <= len(path_parts); i++ {
+				if matchGlobFILESDIRS(pattern_parts, path_parts[:i]) {
+					return true
+				}
+			}
+		}
+
+		return false
+	}
+
+	if 0 != len(include) && !matches(include) {
+		return false
+	}
+
+	return !matches(exclude)
+}
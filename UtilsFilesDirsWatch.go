@@ -0,0 +1,432 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package Utils
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// WATCH_EVENT_CREATE is the WatchEvent.Type of a path that was created (or moved into the watched path).
+	WATCH_EVENT_CREATE int = iota
+	// WATCH_EVENT_MODIFY is the WatchEvent.Type of a path whose contents changed.
+	WATCH_EVENT_MODIFY
+	// WATCH_EVENT_DELETE is the WatchEvent.Type of a path that was removed.
+	WATCH_EVENT_DELETE
+	// WATCH_EVENT_RENAME is the WatchEvent.Type of a path that was renamed or moved away (its new path, if still
+	// watched, comes in a WATCH_EVENT_CREATE event). Never given when polling, which gives WATCH_EVENT_DELETE instead.
+	WATCH_EVENT_RENAME
+)
+
+const (
+	// _WATCH_DEF_DEBOUNCE is the default WatchOptions.Debounce.
+	_WATCH_DEF_DEBOUNCE time.Duration = 100 * time.Millisecond
+	// _WATCH_DEF_POLL_INTERVAL is the default WatchOptions.Poll_interval.
+	_WATCH_DEF_POLL_INTERVAL time.Duration = 1 * time.Second
+	// _WATCH_MAX_DEBOUNCES is how many times the debounce time events can be held at most while more keep coming.
+	_WATCH_MAX_DEBOUNCES int64 = 10
+)
+
+// errWatchNotSupported is returned by the native watching functions on the OSes without native watching.
+var errWatchNotSupported error = errors.New("native watching is not supported on this OS")
+
+// WatchEvent is a change to a watched path, given by Watcher.Events().
+type WatchEvent struct {
+	// Path is the path that changed.
+	Path GPath
+	// Type is the type of the change - one of the WATCH_EVENT_ constants.
+	Type int
+}
+
+// WatchOptions is the options for GPath.Watch().
+type WatchOptions struct {
+	// Recursive is true to watch the contents of the subdirectories too, false to watch only the direct contents of
+	// the directory. Ignored when watching a file.
+	Recursive bool
+	// Debounce is how long to wait without new changes before giving the events (coalesced), or 0 for the default
+	// (100 ms).
+	Debounce time.Duration
+	// Poll_interval is the time between checks when polling, or 0 for the default (1 second).
+	Poll_interval time.Duration
+	// Force_polling is true to poll even if the OS supports native watching (inotify on Linux). Paths on file systems
+	// other than the OS one are always polled.
+	Force_polling bool
+}
+
+/*
+Watcher watches a path for changes, got through GPath.Watch().
+
+The events of the same path that happen within the debounce time are coalesced into one (or none, like for a file
+created and removed right away), and given in the order they first happened.
+*/
+type Watcher struct {
+	// events is the channel of the coalesced events given to the user.
+	events chan WatchEvent
+	// stop is closed to stop the native watching or the polling.
+	stop chan struct{}
+	// stop_once makes sure stop is closed only once.
+	stop_once sync.Once
+	// done is closed when all the goroutines of the watcher have finished.
+	done chan struct{}
+}
+
+// _PollState is the state of a path as seen when polling.
+type _PollState struct {
+	// gPath is the path.
+	gPath GPath
+	// size is the size of the path in bytes.
+	size int64
+	// mod_time is the modification time of the path.
+	mod_time time.Time
+}
+
+/*
+Watch starts watching a file or a directory for changes.
+
+When watching a file, it's its directory that is actually watched, so that a file replaced by another (like through
+GPath.WriteFileAtomic()) is still watched.
+
+-----------------------------------------------------------
+
+– Params:
+  - options – the options for the watching or nil for the default ones
+
+– Returns:
+  - the watcher, which must be closed with Watcher.Close() when no longer needed, or nil if an error occurred
+  - nil if the path is being watched, an error otherwise (including if the directory to watch doesn't exist)
+*/
+func (gPath GPath) Watch(options *WatchOptions) (*Watcher, error) {
+	if err := gPath.IsSupported(); nil != err {
+		return nil, err
+	}
+
+	var watchOptions WatchOptions = WatchOptions{}
+	if nil != options {
+		watchOptions = *options
+	}
+	if 0 == watchOptions.Debounce {
+		watchOptions.Debounce = _WATCH_DEF_DEBOUNCE
+	}
+	if 0 == watchOptions.Poll_interval {
+		watchOptions.Poll_interval = _WATCH_DEF_POLL_INTERVAL
+	}
+
+	var watcher *Watcher = &Watcher{
+		events: make(chan WatchEvent, 64),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	var raw_events chan WatchEvent = make(chan WatchEvent, 256)
+
+	var err error = errWatchNotSupported
+	if !watchOptions.Force_polling && gPath.isOsFS() {
+		err = watchNativeFILESDIRS(gPath, watchOptions.Recursive, raw_events, watcher.stop)
+	}
+	if nil != err {
+		if !errors.Is(err, errWatchNotSupported) {
+			return nil, err
+		}
+
+		var dir GPath = gPath
+		if !gPath.dir {
+			dir = gPath.Dir()
+		}
+		if !dir.Exists() {
+			return nil, errors.New("the directory to watch does not exist")
+		}
+
+		// The first state is got right away so that any changes after this function returns are detected.
+		var poll_states map[string]_PollState = getPollStatesFILESDIRS(gPath, watchOptions.Recursive)
+		go watchPollFILESDIRS(gPath, watchOptions.Recursive, watchOptions.Poll_interval, poll_states, raw_events,
+			watcher.stop)
+	}
+
+	go watcher.coalesce(raw_events, watchOptions.Debounce)
+
+	return watcher, nil
+}
+
+/*
+Events gets the channel of the events of the watcher, which is closed after Watcher.Close() is called.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the channel of the events
+*/
+func (watcher *Watcher) Events() <-chan WatchEvent {
+	return watcher.events
+}
+
+/*
+Close stops the watcher and waits for it to finish. Calling it more than once does nothing.
+
+Pending events are still given if there's anyone reading them - else they're discarded.
+
+-----------------------------------------------------------
+
+– Returns:
+  - always nil (the return is for compatibility with io.Closer)
+*/
+func (watcher *Watcher) Close() error {
+	watcher.stop_once.Do(func() {
+		close(watcher.stop)
+	})
+
+	// Discard events nobody reads so that the watcher can finish.
+	for {
+		select {
+			case <-watcher.done:
+				return nil
+			case <-watcher.events:
+		}
+	}
+}
+
+/*
+coalesce reads the raw events, coalesces them and gives them to the user after the debounce time.
+
+It returns (closing the events channel) once the raw events channel is closed.
+
+-----------------------------------------------------------
+
+– Params:
+  - raw_events – the channel of the raw events
+  - debounce – the debounce time
+*/
+func (watcher *Watcher) coalesce(raw_events <-chan WatchEvent, debounce time.Duration) {
+	defer close(watcher.done)
+	defer close(watcher.events)
+
+	var pending []WatchEvent = nil
+	var pending_idxs map[string]int = map[string]int{}
+	var first_pending time.Time
+
+	var timer *time.Timer = time.NewTimer(debounce)
+	timer.Stop()
+
+	var flush = func() {
+		for _, watchEvent := range pending {
+			if -1 != watchEvent.Type {
+				watcher.events <- watchEvent
+			}
+		}
+		pending = nil
+		pending_idxs = map[string]int{}
+	}
+
+	for {
+		select {
+			case watchEvent, ok := <-raw_events:
+				if !ok {
+					timer.Stop()
+					flush()
+
+					return
+				}
+
+				if 0 == len(pending) {
+					first_pending = time.Now()
+				}
+				if idx, ok := pending_idxs[watchEvent.Path.p]; ok {
+					pending[idx].Type = coalesceWatchEventsFILESDIRS(pending[idx].Type, watchEvent.Type)
+				} else {
+					pending_idxs[watchEvent.Path.p] = len(pending)
+					pending = append(pending, watchEvent)
+				}
+
+				// Wait for the changes to settle, but not forever if they never do.
+				if time.Since(first_pending) < debounce * time.Duration(_WATCH_MAX_DEBOUNCES) {
+					if !timer.Stop() {
+						// Fired but not received yet - drained, or it would flush right away.
+						select {
+							case <-timer.C:
+							default:
+						}
+					}
+					timer.Reset(debounce)
+				}
+			case <-timer.C:
+				flush()
+		}
+	}
+}
+
+/*
+coalesceWatchEventsFILESDIRS coalesces 2 consecutive events of the same path into one.
+
+-----------------------------------------------------------
+
+– Params:
+  - old_type – the type of the first event, or -1 if the events coalesced so far cancelled each other
+  - new_type – the type of the second event
+
+– Returns:
+  - the type of the coalesced event, or -1 if the events cancel each other
+*/
+func coalesceWatchEventsFILESDIRS(old_type int, new_type int) int {
+	switch old_type {
+		case WATCH_EVENT_CREATE:
+			switch new_type {
+				case WATCH_EVENT_DELETE, WATCH_EVENT_RENAME:
+					// Created and gone - nothing happened.
+					return -1
+				default:
+					return WATCH_EVENT_CREATE
+			}
+		case WATCH_EVENT_DELETE, WATCH_EVENT_RENAME:
+			if WATCH_EVENT_CREATE == new_type {
+				// Replaced by another one.
+				return WATCH_EVENT_MODIFY
+			}
+	}
+
+	return new_type
+}
+
+/*
+watchPollFILESDIRS watches a path by checking it for changes periodically, until stop is closed (which closes
+raw_events).
+
+-----------------------------------------------------------
+
+– Params:
+  - gPath – the path to watch
+  - recursive – same as in WatchOptions.Recursive
+  - interval – the time between checks
+  - poll_states – the state of the watched paths when the watching started
+  - raw_events – the channel where to send the changes found
+  - stop – the channel that stops the polling when closed
+*/
+func watchPollFILESDIRS(gPath GPath, recursive bool, interval time.Duration, poll_states map[string]_PollState,
+						raw_events chan<- WatchEvent, stop <-chan struct{}) {
+	defer close(raw_events)
+
+	var ticker *time.Ticker = time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var old_states map[string]_PollState = poll_states
+	for {
+		select {
+			case <-stop:
+				return
+			case <-ticker.C:
+		}
+
+		var new_states map[string]_PollState = getPollStatesFILESDIRS(gPath, recursive)
+
+		// Sorted so that parents come before their children (and the other way around for removals).
+		var new_paths []string = getSortedKeysFILESDIRS(new_states)
+		for _, path := range new_paths {
+			var new_state _PollState = new_states[path]
+			old_state, ok := old_states[path]
+			if !ok {
+				raw_events <- WatchEvent{Path: new_state.gPath, Type: WATCH_EVENT_CREATE}
+			} else if !new_state.gPath.dir && (old_state.size != new_state.size ||
+						!old_state.mod_time.Equal(new_state.mod_time)) {
+				raw_events <- WatchEvent{Path: new_state.gPath, Type: WATCH_EVENT_MODIFY}
+			}
+		}
+		var old_paths []string = getSortedKeysFILESDIRS(old_states)
+		for i := len(old_paths) - 1; i >= 0; i-- {
+			if _, ok := new_states[old_paths[i]]; !ok {
+				raw_events <- WatchEvent{Path: old_states[old_paths[i]].gPath, Type: WATCH_EVENT_DELETE}
+			}
+		}
+
+		old_states = new_states
+	}
+}
+
+/*
+getPollStatesFILESDIRS gets the current state of the watched paths for watchPollFILESDIRS().
+
+-----------------------------------------------------------
+
+– Params:
+  - gPath – the path being watched
+  - recursive – same as in WatchOptions.Recursive
+
+– Returns:
+  - the states of the watched paths (not including the watched directory itself), by their path strings
+*/
+func getPollStatesFILESDIRS(gPath GPath, recursive bool) map[string]_PollState {
+	var poll_states map[string]_PollState = map[string]_PollState{}
+
+	if !gPath.dir {
+		if file_info, err := gPath.getFS().Stat(gPath.p); nil == err {
+			poll_states[gPath.p] = _PollState{
+				gPath:    gPath,
+				size:     file_info.Size(),
+				mod_time: file_info.ModTime(),
+			}
+		}
+
+		return poll_states
+	}
+
+	var add_state = func(found GPath, rel_path string) int {
+		if file_info, err := found.getFS().Lstat(found.p); nil == err {
+			poll_states[found.p] = _PollState{
+				gPath:    found,
+				size:     file_info.Size(),
+				mod_time: file_info.ModTime(),
+			}
+		}
+
+		return WALK_CONTINUE
+	}
+	if recursive {
+		_ = gPath.Walk(nil, add_state)
+	} else {
+		gPaths, _ := gPath.List(nil)
+		for _, found := range gPaths {
+			add_state(found, "")
+		}
+	}
+
+	return poll_states
+}
+
+/*
+getSortedKeysFILESDIRS gets the keys of a map of poll states, sorted.
+
+-----------------------------------------------------------
+
+– Params:
+  - poll_states – the map of poll states
+
+– Returns:
+  - the sorted keys
+*/
+func getSortedKeysFILESDIRS(poll_states map[string]_PollState) []string {
+	var keys []string = make([]string, 0, len(poll_states))
+	for key := range poll_states {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,222 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+//go:build linux
+
+package Utils
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// _INOTIFY_MASK is the mask of the inotify events watched.
+const _INOTIFY_MASK uint32 = syscall.IN_CREATE | syscall.IN_MODIFY | syscall.IN_DELETE | syscall.IN_MOVED_FROM |
+			syscall.IN_MOVED_TO | syscall.IN_DELETE_SELF | syscall.IN_MOVE_SELF
+
+/*
+watchNativeFILESDIRS watches a path with inotify, until stop is closed (which closes raw_events).
+
+-----------------------------------------------------------
+
+– Params:
+  - gPath – the path to watch
+  - recursive – same as in WatchOptions.Recursive
+  - raw_events – the channel where to send the changes found
+  - stop – the channel that stops the watching when closed
+
+– Returns:
+  - nil if the path is being watched, an error otherwise
+*/
+func watchNativeFILESDIRS(gPath GPath, recursive bool, raw_events chan<- WatchEvent, stop <-chan struct{}) error {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if nil != err {
+		return err
+	}
+	// Being non-blocking, the file uses the Go poller, so closing it unblocks the reads.
+	var inotify_file *os.File = os.NewFile(uintptr(fd), "inotify")
+
+	var dir GPath = gPath
+	var file_name string = ""
+	if !gPath.dir {
+		// Watch the directory of the file, so that the file can be replaced and still be watched.
+		dir = gPath.Dir()
+		file_name = gPath.Name()
+		recursive = false
+	}
+
+	var wds map[int32]GPath = map[int32]GPath{}
+	if err = addInotifyWatchesFILESDIRS(fd, dir, recursive, wds, nil); nil != err {
+		_ = inotify_file.Close()
+
+		return err
+	}
+
+	go func() {
+		<-stop
+		_ = inotify_file.Close()
+	}()
+
+	go func() {
+		defer close(raw_events)
+
+		var buffer []byte = make([]byte, 64 * (syscall.SizeofInotifyEvent + syscall.NAME_MAX + 1))
+		for {
+			n, err := inotify_file.Read(buffer)
+			if nil != err {
+				// Closed (stopped) or broken - either way, nothing else to do.
+				return
+			}
+
+			for offset := 0; offset + syscall.SizeofInotifyEvent <= n; {
+				var event *syscall.InotifyEvent = (*syscall.InotifyEvent)(unsafe.Pointer(&buffer[offset]))
+				var name_bytes []byte = buffer[offset+syscall.SizeofInotifyEvent : offset+syscall.SizeofInotifyEvent+
+							int(event.Len)]
+				offset += syscall.SizeofInotifyEvent + int(event.Len)
+
+				var name string = string(name_bytes)
+				for i, c := range name_bytes {
+					if 0 == c {
+						name = string(name_bytes[:i])
+
+						break
+					}
+				}
+
+				handleInotifyEventFILESDIRS(fd, event, name, file_name, recursive, wds, raw_events)
+			}
+		}
+	}()
+
+	return nil
+}
+
+/*
+handleInotifyEventFILESDIRS converts an inotify event into a WatchEvent, adding watches to new directories if needed.
+
+-----------------------------------------------------------
+
+– Params:
+  - fd – the inotify file descriptor
+  - event – the inotify event
+  - name – the name of the path inside the watched directory, or "" if the event is about the directory itself
+  - file_name – the name of the watched file, or "" if watching a directory
+  - recursive – same as in WatchOptions.Recursive
+  - wds – the map of the watch descriptors to their directories
+  - raw_events – the channel where to send the changes found
+*/
+func handleInotifyEventFILESDIRS(fd int, event *syscall.InotifyEvent, name string, file_name string, recursive bool,
+								 wds map[int32]GPath, raw_events chan<- WatchEvent) {
+	dir, ok := wds[event.Wd]
+	if !ok {
+		return
+	}
+
+	if 0 != event.Mask & syscall.IN_IGNORED {
+		delete(wds, event.Wd)
+
+		return
+	}
+	if "" == name {
+		// Events about the watched directories themselves are given by their parents (except for the main one, which
+		// is the user's job to check).
+		return
+	}
+	if "" != file_name && name != file_name {
+		return
+	}
+
+	var is_dir bool = 0 != event.Mask & syscall.IN_ISDIR
+	var gPath GPath = PathFILESDIRS(is_dir, dir.s, dir, name)
+
+	var event_type int
+	switch {
+		case 0 != event.Mask & (syscall.IN_CREATE | syscall.IN_MOVED_TO):
+			raw_events <- WatchEvent{Path: gPath, Type: WATCH_EVENT_CREATE}
+			if is_dir && recursive {
+				// Watch the new directory and report what was created in it before the watch was added.
+				_ = addInotifyWatchesFILESDIRS(fd, gPath, true, wds, raw_events)
+			}
+
+			return
+		case 0 != event.Mask & syscall.IN_MODIFY:
+			event_type = WATCH_EVENT_MODIFY
+		case 0 != event.Mask & syscall.IN_DELETE:
+			event_type = WATCH_EVENT_DELETE
+		case 0 != event.Mask & syscall.IN_MOVED_FROM:
+			event_type = WATCH_EVENT_RENAME
+		default:
+			return
+	}
+
+	raw_events <- WatchEvent{Path: gPath, Type: event_type}
+}
+
+/*
+addInotifyWatchesFILESDIRS adds inotify watches to a directory and, if recursive, to all its subdirectories.
+
+-----------------------------------------------------------
+
+– Params:
+  - fd – the inotify file descriptor
+  - dir – the directory to watch
+  - recursive – true to watch the subdirectories too
+  - wds – the map of the watch descriptors to their directories, where the new ones are added
+  - raw_events – the channel where to send creation events for the contents found, or nil to not send them
+
+– Returns:
+  - nil if the watches were added successfully, an error otherwise
+*/
+func addInotifyWatchesFILESDIRS(fd int, dir GPath, recursive bool, wds map[int32]GPath,
+								raw_events chan<- WatchEvent) error {
+	wd, err := syscall.InotifyAddWatch(fd, dir.p, _INOTIFY_MASK)
+	if nil != err {
+		return err
+	}
+	if _, ok := wds[int32(wd)]; ok {
+		// Already watched (a symbolic link to a directory already watched, for example).
+		return nil
+	}
+	wds[int32(wd)] = dir
+
+	if !recursive && nil == raw_events {
+		return nil
+	}
+
+	var gPaths []GPath = nil
+	gPaths, err = dir.List(nil)
+	if nil != err {
+		return err
+	}
+	for _, found := range gPaths {
+		if nil != raw_events {
+			raw_events <- WatchEvent{Path: found, Type: WATCH_EVENT_CREATE}
+		}
+		if found.dir && recursive {
+			if err = addInotifyWatchesFILESDIRS(fd, found, true, wds, raw_events); nil != err {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
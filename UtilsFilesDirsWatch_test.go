@@ -0,0 +1,161 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package Utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCoalesceWatchEvents(t *testing.T) {
+	var tests = []struct {
+		name     string
+		old_type int
+		new_type int
+		want     int
+	}{
+		{"created and modified", WATCH_EVENT_CREATE, WATCH_EVENT_MODIFY, WATCH_EVENT_CREATE},
+		{"created and deleted", WATCH_EVENT_CREATE, WATCH_EVENT_DELETE, -1},
+		{"created and renamed", WATCH_EVENT_CREATE, WATCH_EVENT_RENAME, -1},
+		{"deleted and created", WATCH_EVENT_DELETE, WATCH_EVENT_CREATE, WATCH_EVENT_MODIFY},
+		{"renamed and created", WATCH_EVENT_RENAME, WATCH_EVENT_CREATE, WATCH_EVENT_MODIFY},
+		{"modified and deleted", WATCH_EVENT_MODIFY, WATCH_EVENT_DELETE, WATCH_EVENT_DELETE},
+		{"cancelled and created", -1, WATCH_EVENT_CREATE, WATCH_EVENT_CREATE},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := coalesceWatchEventsFILESDIRS(test.old_type, test.new_type); test.want != got {
+				t.Errorf("got %d, want %d", got, test.want)
+			}
+		})
+	}
+}
+
+func TestWatch(t *testing.T) {
+	var tests = []struct {
+		name    string
+		dir     GPath
+		options *WatchOptions
+	}{
+		{"native", PathFILESDIRS(true, "", t.TempDir()), &WatchOptions{Debounce: 50 * time.Millisecond}},
+		{"polling on the OS", PathFILESDIRS(true, "", t.TempDir()),
+			&WatchOptions{Debounce: 50 * time.Millisecond, Poll_interval: 20 * time.Millisecond, Force_polling: true}},
+		{"polling on another file system", PathFILESDIRS(true, "", "/dir").WithFS(NewMemFileSystemFILESDIRS()),
+			&WatchOptions{Debounce: 50 * time.Millisecond, Poll_interval: 20 * time.Millisecond}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.dir.Create(true); nil != err {
+				t.Fatal(err)
+			}
+			watcher, err := test.dir.Watch(test.options)
+			if nil != err {
+				t.Fatal(err)
+			}
+			defer watcher.Close()
+
+			var file GPath = test.dir.Add2(false, "file.txt")
+			var steps = []struct {
+				name     string
+				change   func() error
+				wantType int
+			}{
+				{"create", func() error {
+					return file.WriteTextFile("a")
+				}, WATCH_EVENT_CREATE},
+				{"modify", func() error {
+					return file.WriteTextFile("bb")
+				}, WATCH_EVENT_MODIFY},
+				{"delete", func() error {
+					return file.Remove()
+				}, WATCH_EVENT_DELETE},
+			}
+			for _, step := range steps {
+				if err = step.change(); nil != err {
+					t.Fatal(err)
+				}
+
+				select {
+					case watchEvent := <-watcher.Events():
+						if file.p != watchEvent.Path.p || step.wantType != watchEvent.Type {
+							t.Fatalf("%s: got event %d on %q, want %d on %q", step.name, watchEvent.Type,
+								watchEvent.Path.p, step.wantType, file.p)
+						}
+					case <-time.After(5 * time.Second):
+						t.Fatalf("%s: no event", step.name)
+				}
+			}
+
+			_ = watcher.Close()
+			if _, ok := <-watcher.Events(); ok {
+				t.Error("events channel not closed after Close()")
+			}
+		})
+	}
+}
+
+func TestWatchCoalesce(t *testing.T) {
+	var dir GPath = PathFILESDIRS(true, "", "/dir").WithFS(NewMemFileSystemFILESDIRS())
+	if err := dir.Create(true); nil != err {
+		t.Fatal(err)
+	}
+	var kept GPath = dir.Add2(false, "kept.txt")
+	if err := kept.WriteTextFile("a"); nil != err {
+		t.Fatal(err)
+	}
+
+	// Polled right away, so the changes below are all within the same check.
+	watcher, err := dir.Watch(&WatchOptions{Debounce: 200 * time.Millisecond, Poll_interval: 100 * time.Millisecond})
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer watcher.Close()
+
+	var created GPath = dir.Add2(false, "created.txt")
+	if err = created.WriteTextFile("a"); nil != err {
+		t.Fatal(err)
+	}
+	if err = kept.Remove(); nil != err {
+		t.Fatal(err)
+	}
+
+	var got []WatchEvent = nil
+	var timeout <-chan time.Time = time.After(2 * time.Second)
+	for len(got) < 2 {
+		select {
+			case watchEvent := <-watcher.Events():
+				got = append(got, watchEvent)
+			case <-timeout:
+				t.Fatalf("got only %v", got)
+		}
+	}
+	var want []WatchEvent = []WatchEvent{
+		{Path: created, Type: WATCH_EVENT_CREATE},
+		{Path: kept, Type: WATCH_EVENT_DELETE},
+	}
+	for i := range want {
+		if want[i].Path.p != got[i].Path.p || want[i].Type != got[i].Type {
+			t.Errorf("event %d: got %d on %q, want %d on %q", i, got[i].Type, got[i].Path.p, want[i].Type,
+				want[i].Path.p)
+		}
+	}
+}
@@ -28,6 +28,7 @@ import (
 	"fmt"
 	"math/rand"
 	"strings"
+	"sync"
 	"time"
 	"unsafe"
 
@@ -44,6 +45,9 @@ const (
 	letterIdxMax  = 63 / letterIdxBits   // # of letter indices fitting in 63 bits
 )
 var src = rand.NewSource(time.Now().UnixNano())
+// src_mutex_GL protects src, which is not safe for concurrent use (temporary names, MIME boundaries and queue names are
+// generated from many goroutines).
+var src_mutex_GL sync.Mutex
 
 /*
 RandStringGENERAL generates a random string with uppercase and lowercase letters of the given length.
@@ -58,6 +62,9 @@ RandStringGENERAL generates a random string with uppercase and lowercase letters
 */
 func RandStringGENERAL(letters_num int) string {
 	// Original function name: RandStringBytesMaskImprSrcUnsafe
+	src_mutex_GL.Lock()
+	defer src_mutex_GL.Unlock()
+
 	b := make([]byte, letters_num)
 	// A src.Int63() generates 63 random bits, enough for letterIdxMax characters!
 	for i, cache, remain := letters_num-1, src.Int63(), letterIdxMax; i >= 0; {
@@ -62,13 +62,8 @@ isMOD5Supported checks if the module MOD_5 is supported on the current machine.
   - true if the module is supported, false otherwise.
 */
 func isMOD5Supported() bool {
-	// Check if the command "smartctl" is available
-	output, err := ExecCmdSHELL([]string{"curl{{EXE}}"})
-	if err != nil {
-		return false
-	}
-
-	return output.Exit_code == 0
+	// The emails are sent with the internal SMTP client, so nothing external is needed.
+	return true
 }
 
 /*
@@ -27,6 +27,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"Utils/Tcef"
@@ -43,6 +44,8 @@ const (
 	_USER_DATA_REL_DIR string = _DATA_REL_DIR + "UserData"
 	// _PROGRAM_DATA_REL_DIR is the relative path to the program data directory from PersonalConsts._VISOR_DIR.
 	_PROGRAM_DATA_REL_DIR string = _DATA_REL_DIR + "ProgramData"
+	// _MANIFESTS_REL_DIR is the relative path to the directory of the integrity manifests from PersonalConsts._VISOR_DIR.
+	_MANIFESTS_REL_DIR string = _DATA_REL_DIR + "Manifests"
 	// _WEBSCRAPE_WEBSITE_FILES_REL_DIR is the relative path to the website files directory from PersonalConsts._VISOR_DIR.
 	_WEBSITE_FILES_REL_DIR string = _DATA_REL_DIR + "Website/files_EOG"
 )
@@ -56,8 +59,6 @@ const _MOD_GEN_ERROR_CODE int = 3234
 const (
 	// _MOD_GEN_INFO_JSON is the name of the file containing the module-generated information
 	_MOD_GEN_INFO_JSON string = "mod_gen_info.json"
-	// _MOD_GEN_INFO_JSON_TMP is the name of the temporary file containing the module-generated information
-	_MOD_GEN_INFO_JSON_TMP string = "mod_gen_info.json_tmp"
 	// _MOD_USER_INFO_JSON is the name of the file containing the user-given module information (read-only by the
 	// module)
 	_MOD_USER_INFO_JSON string = "mod_user_info.json"
@@ -82,6 +83,15 @@ const (
 	NUM_MODULES             int = 5
 )
 
+// _MOD_FILES_LOCK_TIMEOUT is the maximum time to wait for the lock of a file shared between modules.
+const _MOD_FILES_LOCK_TIMEOUT time.Duration = 5 * time.Second
+
+// _MOD_TEMP_MAX_AGE is the age of the leftovers in the Temp directory of a module purged when the module starts.
+const _MOD_TEMP_MAX_AGE time.Duration = 24 * time.Hour
+
+// _MOD_RETENTION_KEEP is the patterns of the files of the modules' data never removed by the retention policies.
+var _MOD_RETENTION_KEEP []string = []string{_MOD_GEN_INFO_JSON, _MOD_USER_INFO_JSON, "PID=*", "STOP", "**/*" + LOCK_FILE_EXT}
+
 // MAX_WAIT_NEXT_TIMESTAMP_S is the maximum number of seconds to wait for the next timestamp to be registered by a module.
 const MAX_WAIT_NEXT_TIMESTAMP_S int64 = 5
 
@@ -102,6 +112,24 @@ type _ModDirsInfo struct {
 	Temp GPath
 }
 
+// ModDiskUsage is the space used by the directories of a module.
+type ModDiskUsage struct {
+	// ProgramData is the space used by the program data directory.
+	ProgramData DiskUsage
+	// UserData is the space used by the user data directory.
+	UserData DiskUsage
+	// Temp is the space used by the temporary directory.
+	Temp DiskUsage
+}
+
+// ModRetention is the retention policies of the directories of a module, for ApplyModRetentionMODULES().
+type ModRetention struct {
+	// UserData is the policy of the user data directory, or nil to keep everything.
+	UserData *RetentionPolicy
+	// Temp is the policy of the temporary directory, or nil to keep everything.
+	Temp *RetentionPolicy
+}
+
 type _ModGenInfo[T any] struct {
 	// Mod_num is the number of the module.
 	Mod_num int
@@ -119,6 +147,30 @@ type ModuleInfo[T any] struct {
 	ModGenInfo _ModGenInfo[T]
 	// ModDirsInfo is the information about the directories of the module.
 	ModDirsInfo _ModDirsInfo
+
+	// temps is the temporary files and directories created through the ModuleInfo, shared by all its copies.
+	temps *_ModTemps
+	// stopWatch is the watcher of the STOP file used by LoopSleep(), shared by all the copies of the ModuleInfo.
+	stopWatch *_ModStopWatch
+}
+
+// _ModTemps is the temporary files and directories created by a module, removed when it stops.
+type _ModTemps struct {
+	// mutex protects gPaths.
+	mutex sync.Mutex
+	// gPaths is the paths created.
+	gPaths []GPath
+}
+
+// _ModStopWatch is the watcher of the STOP file of a module, created on the first LoopSleep() and closed when the
+// module stops.
+type _ModStopWatch struct {
+	// mutex protects the other fields.
+	mutex sync.Mutex
+	// watcher is the watcher, or nil if it wasn't created yet or if the watching failed.
+	watcher *Watcher
+	// failed is true if the watching failed, broke or was closed, in which case LoopSleep() sleeps normally.
+	failed bool
 }
 
 /*
@@ -155,6 +207,9 @@ func ModStartup[T any](mod_num int, realMain RealMain) {
 	// Try to run the module, catching any fatal errors and sending an email with them.
 	var mod_name string = "ERROR"
 	var errs bool = false
+	// The temporary files are only removed if the module got to run.
+	var mod_temps *_ModTemps = nil
+	var mod_stop_watch *_ModStopWatch = nil
 	Tcef.Tcef{
 		Try: func() {
 			// Module startup routine //
@@ -187,12 +242,21 @@ func ModStartup[T any](mod_num int, realMain RealMain) {
 					UserData:    getUserDataDirMODULES(mod_num),
 					Temp:        getModTempDirMODULES(mod_num),
 				},
+				temps:       &_ModTemps{},
+				stopWatch:   &_ModStopWatch{},
 			}
+			mod_stop_watch = moduleInfo.stopWatch
 
 			moduleInfo.getGenInfo()
 
 			moduleInfo.updateModRunInfo()
 
+			// Purge the leftovers of previous runs that didn't clean up (like if they crashed)
+			mod_temps = moduleInfo.temps
+			if moduleInfo.ModDirsInfo.Temp.Exists() {
+				_, _ = moduleInfo.ModDirsInfo.Temp.RemoveOlderThan(_MOD_TEMP_MAX_AGE)
+			}
+
 			// Execute realMain()
 			realMain(moduleInfo)
 		},
@@ -211,6 +275,13 @@ func ModStartup[T any](mod_num int, realMain RealMain) {
 
 	// Module shutdown routine //
 
+	// Only what this process created is removed - another instance may be using the Temp directory too (like one
+	// started after this one was considered stopped).
+	if nil != mod_temps {
+		mod_temps.removeAll()
+	}
+	mod_stop_watch.close()
+
 	if errs {
 		printShutdownSequenceMODULES(errs, mod_name, strconv.Itoa(mod_num))
 
@@ -242,6 +313,8 @@ func GetModNameMODULES(mod_num int) string {
 /*
 SendModErrorEmailMODULES directly sends an email to the developer with the error message.
 
+The email goes to the ADMIN recipients of PersonalConsts.EMAIL_ROUTING (or to the default ones if there are none).
+
 This function does *not* use any modules to do anything. Only utility functions. So it can be used from any
 module.
 
@@ -259,15 +332,16 @@ func SendModErrorEmailMODULES(mod_num int, err_str string) error {
 		MODEL_INFO_MSG_BODY_EMAIL : err_str,
 		MODEL_INFO_DATE_TIME_EMAIL: GetDateTimeStrTIMEDATE(-1),
 	}
-	var email_info = GetModelFileEMAIL(MODEL_FILE_INFO, things_replace)
+	var email_info = GetModelFileModEMAIL(mod_num, MODEL_FILE_INFO, things_replace)
+	email_info.To = GetAdminRecipientsEMAIL()
 	email_info.Subject = "Error in module: " + GetModNameMODULES(mod_num)
 
-	message_eml, mail_to, success := prepareEmlEMAIL(email_info)
+	message_eml, recipients, success := prepareEmlEMAIL(email_info)
 	if !success {
 		return errors.New("error preparing email")
 	}
 
-	return SendEmailEMAIL(message_eml, mail_to, true)
+	return SendEmailEMAIL(message_eml, strings.Join(recipients, ","), true)
 }
 
 /*
@@ -275,6 +349,9 @@ LoopSleep sleeps for the given number of seconds (with a caveat) and updates the
 
 If the number of seconds exceeds MAX_WAIT_NEXT_TIMESTAMP_S, the latter is used instead.
 
+The sleep is interrupted as soon as the module is signalled to stop (the STOP file is watched for that, with one watcher
+for the whole module, closed when it stops).
+
 -----------------------------------------------------------
 
 – Params:
@@ -284,6 +361,8 @@ If the number of seconds exceeds MAX_WAIT_NEXT_TIMESTAMP_S, the latter is used i
   - true if the module should stop, false otherwise
 */
 func (moduleInfo *ModuleInfo[T]) LoopSleep(s int64) bool {
+	var stop_events <-chan WatchEvent = moduleInfo.stopWatch.getEvents(moduleInfo.ModDirsInfo.UserData.Add2(false,
+		"STOP"))
 
 	var curr_s int64 = time.Now().Unix()
 	var end_s int64 = curr_s + s
@@ -296,7 +375,16 @@ func (moduleInfo *ModuleInfo[T]) LoopSleep(s int64) bool {
 		if s > MAX_WAIT_NEXT_TIMESTAMP_S {
 			seconds = MAX_WAIT_NEXT_TIMESTAMP_S
 		}
-		time.Sleep(time.Duration(seconds) * time.Second)
+		// If the watching failed, the channel is nil and this is a normal sleep.
+		select {
+			case <-time.After(time.Duration(seconds) * time.Second):
+			case _, ok := <-stop_events:
+				if !ok {
+					// The watcher broke - sleep normally from now on.
+					moduleInfo.stopWatch.close()
+					stop_events = nil
+				}
+		}
 
 		moduleInfo.updateModRunInfo()
 
@@ -306,6 +394,136 @@ func (moduleInfo *ModuleInfo[T]) LoopSleep(s int64) bool {
 	return false
 }
 
+/*
+CreateTemp creates a new temporary file in the Temp directory of the module, which is removed when the module stops.
+
+-----------------------------------------------------------
+
+– Params:
+  - pattern – the name of the file, where the last "*" is replaced by a random string
+
+– Returns:
+  - the path of the file
+  - the file, opened for reading and writing, which must be closed when no longer needed, or nil if an error occurred
+  - nil if the file was created successfully, an error otherwise
+*/
+func (moduleInfo *ModuleInfo[T]) CreateTemp(pattern string) (GPath, FSFile, error) {
+	gPath, file, err := moduleInfo.ModDirsInfo.Temp.CreateTemp(pattern)
+	if nil == err {
+		moduleInfo.temps.add(gPath)
+	}
+
+	return gPath, file, err
+}
+
+/*
+MkdirTemp creates a new temporary directory in the Temp directory of the module, which is removed (with all its
+contents) when the module stops.
+
+-----------------------------------------------------------
+
+– Params:
+  - pattern – the name of the directory, where the last "*" is replaced by a random string
+
+– Returns:
+  - the path of the directory
+  - nil if the directory was created successfully, an error otherwise
+*/
+func (moduleInfo *ModuleInfo[T]) MkdirTemp(pattern string) (GPath, error) {
+	gPath, err := moduleInfo.ModDirsInfo.Temp.MkdirTemp(pattern)
+	if nil == err {
+		moduleInfo.temps.add(gPath)
+	}
+
+	return gPath, err
+}
+
+/*
+add registers a temporary path created by the module, to be removed when it stops.
+
+-----------------------------------------------------------
+
+– Params:
+  - gPath – the path created
+*/
+func (modTemps *_ModTemps) add(gPath GPath) {
+	if nil == modTemps {
+		// ModuleInfo not created by ModStartup() - nothing will remove the paths.
+		return
+	}
+
+	modTemps.mutex.Lock()
+	defer modTemps.mutex.Unlock()
+
+	modTemps.gPaths = append(modTemps.gPaths, gPath)
+}
+
+/*
+removeAll removes all the temporary paths created by the module that still exist.
+*/
+func (modTemps *_ModTemps) removeAll() {
+	modTemps.mutex.Lock()
+	defer modTemps.mutex.Unlock()
+
+	for _, gPath := range modTemps.gPaths {
+		_, _ = gPath.RemoveAll(nil)
+	}
+	modTemps.gPaths = nil
+}
+
+/*
+getEvents gets the events of the watcher of the STOP file, creating the watcher if it wasn't created yet.
+
+-----------------------------------------------------------
+
+– Params:
+  - stop_file_path – the path to the STOP file
+
+– Returns:
+  - the channel of the events, or nil if the watching failed (or if the ModuleInfo was not created by ModStartup())
+*/
+func (modStopWatch *_ModStopWatch) getEvents(stop_file_path GPath) <-chan WatchEvent {
+	if nil == modStopWatch {
+		return nil
+	}
+
+	modStopWatch.mutex.Lock()
+	defer modStopWatch.mutex.Unlock()
+
+	if modStopWatch.failed {
+		return nil
+	}
+	if nil == modStopWatch.watcher {
+		watcher, err := stop_file_path.Watch(nil)
+		if nil != err {
+			modStopWatch.failed = true
+
+			return nil
+		}
+		modStopWatch.watcher = watcher
+	}
+
+	return modStopWatch.watcher.Events()
+}
+
+/*
+close closes the watcher of the STOP file, if it was created. The events are not got anymore after this.
+*/
+func (modStopWatch *_ModStopWatch) close() {
+	if nil == modStopWatch {
+		return
+	}
+
+	modStopWatch.mutex.Lock()
+	defer modStopWatch.mutex.Unlock()
+
+	modStopWatch.failed = true
+	if nil != modStopWatch.watcher {
+		_ = modStopWatch.watcher.Close()
+		modStopWatch.watcher = nil
+	}
+}
+
 /*
 GetModUserInfo gets the information about the module from the user info file.
 
@@ -336,13 +554,15 @@ signalledToStop checks if the module was signalled to stop.
 */
 func (moduleInfo *ModuleInfo[T]) signalledToStop() bool {
 	var stop_file_path GPath = moduleInfo.ModDirsInfo.UserData.Add2(false, "STOP")
-	if stop_file_path.Exists() {
-		_ = stop_file_path.Remove()
-
-		return true
+	if !stop_file_path.Exists() {
+		return false
 	}
 
-	return false
+	_ = stop_file_path.WithLock(LOCK_EXCLUSIVE, _MOD_FILES_LOCK_TIMEOUT, func() error {
+		return stop_file_path.Remove()
+	})
+
+	return true
 }
 
 /*
@@ -359,37 +579,32 @@ Update updates the information about the module in its generated information fil
 func (modGenInfo *_ModGenInfo[T]) Update() error {
 	var json_str string = *ToJsonGENERAL(&modGenInfo)
 
-	var file_path_curr GPath = getUserDataDirMODULES(modGenInfo.Mod_num).Add2(false, _MOD_GEN_INFO_JSON)
-	var file_path_new GPath = getUserDataDirMODULES(modGenInfo.Mod_num).Add2(false, _MOD_GEN_INFO_JSON_TMP)
-
-	var err error = file_path_new.WriteTextFile(json_str)
-	if nil != err {
-		return err
-	}
+	var file_path GPath = getUserDataDirMODULES(modGenInfo.Mod_num).Add2(false, _MOD_GEN_INFO_JSON)
 
-	return os.Rename(file_path_new.GPathToStringConversion(), file_path_curr.GPathToStringConversion())
+	return file_path.WithLock(LOCK_EXCLUSIVE, _MOD_FILES_LOCK_TIMEOUT, func() error {
+		return file_path.WriteTextFileAtomic(json_str, nil)
+	})
 }
 
 /*
 getGenInfo gets the information about the module from its generated information file.
  */
 func (moduleInfo *ModuleInfo[T]) getGenInfo() {
-	// Get information from the existing mod_gen_info.json file
-	// Check first if the temporary file exists
-	var p_info []byte = moduleInfo.ModDirsInfo.UserData.Add2(false, _MOD_GEN_INFO_JSON_TMP).ReadFile()
+	// Get information from the existing mod_gen_info.json file (always complete, since it's written atomically)
+	var file_path GPath = moduleInfo.ModDirsInfo.UserData.Add2(false, _MOD_GEN_INFO_JSON)
+	var p_info []byte = nil
+	_ = file_path.WithLock(LOCK_SHARED, _MOD_FILES_LOCK_TIMEOUT, func() error {
+		p_info = file_path.ReadFile()
+
+		return nil
+	})
 	if nil == p_info {
-		// If not, check if the main file exists
-		p_info = moduleInfo.ModDirsInfo.UserData.Add2(false, _MOD_GEN_INFO_JSON).ReadFile()
-		if nil == p_info {
-			// If not, empty struct (new file)
+		// If it doesn't exist, empty struct (new file)
 
-			goto new_file
-		}
+		return
 	}
 
 	FromJsonGENERAL(p_info, &moduleInfo.ModGenInfo)
-
-	new_file:
 }
 
 /*
@@ -438,10 +653,11 @@ getProgramDataDirMODULES gets the full path to the program data directory of a m
   - mod_num – the number of the module
 
 – Returns:
-  - the full path to the program data directory of the module
+  - the full path to the program data directory of the module, as a sandbox
 */
 func getProgramDataDirMODULES(mod_num int) GPath {
-	return PersonalConsts_GL._VISOR_DIR.Add2(true, _PROGRAM_DATA_REL_DIR, _MOD_FOLDER_PREFFIX + strconv.Itoa(mod_num))
+	return PersonalConsts_GL._VISOR_DIR.Add2(true, _PROGRAM_DATA_REL_DIR, _MOD_FOLDER_PREFFIX + strconv.Itoa(mod_num)).
+		Sandbox()
 }
 
 /*
@@ -453,10 +669,12 @@ getUserDataDirMODULES gets the full path to the private user data directory of a
   - mod_num – the number of the module
 
 – Returns:
-  - the full path to the private data directory of the module
+  - the full path to the private data directory of the module, as a sandbox and with the PermsPrivateFILESDIRS()
+    permissions policy
 */
 func getUserDataDirMODULES(mod_num int) GPath {
-	return PersonalConsts_GL._VISOR_DIR.Add2(true, _USER_DATA_REL_DIR, _MOD_FOLDER_PREFFIX + strconv.Itoa(mod_num))
+	return PersonalConsts_GL._VISOR_DIR.Add2(true, _USER_DATA_REL_DIR, _MOD_FOLDER_PREFFIX + strconv.Itoa(mod_num)).
+		WithPerms(PermsPrivateFILESDIRS()).Sandbox()
 }
 
 /*
@@ -468,10 +686,12 @@ getModTempDirMODULES gets the full path to the private temporary directory of a
   - mod_num – the number of the module
 
 – Returns:
-  - the full path to the private temporary directory of the module
+  - the full path to the private temporary directory of the module, as a sandbox and with the PermsPrivateFILESDIRS()
+    permissions policy
 */
 func getModTempDirMODULES(mod_num int) GPath {
-	return PersonalConsts_GL._VISOR_DIR.Add2(true, _TEMP_FOLDER, _MOD_FOLDER_PREFFIX + strconv.Itoa(mod_num))
+	return PersonalConsts_GL._VISOR_DIR.Add2(true, _TEMP_FOLDER, _MOD_FOLDER_PREFFIX + strconv.Itoa(mod_num)).
+		WithPerms(PermsPrivateFILESDIRS()).Sandbox()
 }
 
 /*
@@ -485,15 +705,12 @@ updateModRunInfo updates the information about the running of a module.
 func (moduleInfo *ModuleInfo[T]) updateModRunInfo() GPath {
 	var mod_num int = moduleInfo.ModGenInfo.Mod_num
 
-	files, _ := os.ReadDir(getUserDataDirMODULES(mod_num).GPathToStringConversion())
+	files, _ := getUserDataDirMODULES(mod_num).List(&FileFilter{Name_pattern: "PID=*", Type: FILE_TYPE_FILE})
 
 	// Remove all the old info files
 	for _, file := range files {
-		if strings.HasPrefix(file.Name(), "PID=") {
-			err := moduleInfo.ModDirsInfo.UserData.Add2(false, file.Name()).Remove()
-			if nil != err {
-				panic(err)
-			}
+		if err := file.Remove(); nil != err {
+			panic(err)
 		}
 	}
 
@@ -525,44 +742,228 @@ IsModRunningMODULES checks if a module is already running.
 func IsModRunningMODULES(mod_num int) bool {
 	var curr_pid int = os.Getpid()
 
-	files, err := os.ReadDir(getUserDataDirMODULES(mod_num).GPathToStringConversion())
+	files, err := getUserDataDirMODULES(mod_num).List(&FileFilter{Name_pattern: "PID=*", Type: FILE_TYPE_FILE})
 	if nil != err {
 		return false
 	}
 
-	for _, file := range files {
-		if strings.HasPrefix(file.Name(), "PID=") {
-			var file_path GPath = getUserDataDirMODULES(mod_num).Add2(false, file.Name())
+	for _, file_path := range files {
+		var info_list []string = strings.Split(file_path.Name(), "_")
+		if 2 != len(info_list) || !strings.HasPrefix(info_list[1], "TS=") {
+			// Old format ("PID=[pid]") or malformed, without the timestamp to know if the file is current.
+			_ = file_path.Remove()
 
-			var info_list []string = strings.Split(file.Name(), "_")
-			var pid_str string = strings.TrimPrefix(info_list[0], "PID=")
-			var ts_str string = strings.TrimPrefix(info_list[1], "TS=")
+			continue
+		}
+		var pid_str string = strings.TrimPrefix(info_list[0], "PID=")
+		var ts_str string = strings.TrimPrefix(info_list[1], "TS=")
 
-			var pid int
-			if pid, err = strconv.Atoi(pid_str); nil != err {
-				_ = file_path.Remove()
+		var pid int
+		if pid, err = strconv.Atoi(pid_str); nil != err {
+			_ = file_path.Remove()
 
-				continue
-			}
-			var ts int64
-			if ts, err = strconv.ParseInt(ts_str, 10, 64); nil != err {
-				_ = file_path.Remove()
+			continue
+		}
+		var ts int64
+		if ts, err = strconv.ParseInt(ts_str, 10, 64); nil != err {
+			_ = file_path.Remove()
 
-				continue
-			}
+			continue
+		}
 
-			if pid != curr_pid && IsPidRunningPROCESSES(pid) &&
-					(time.Now().UnixNano() - ts) < ((MAX_WAIT_NEXT_TIMESTAMP_S + 1) * 1e9) {
-				return true
-			}
+		if pid != curr_pid && IsPidRunningPROCESSES(pid) &&
+				(time.Now().UnixNano() - ts) < ((MAX_WAIT_NEXT_TIMESTAMP_S + 1) * 1e9) {
+			return true
 		}
 	}
 
 	return false
 }
 
+/*
+ModSignalStopMODULES signals a module to stop, which it will do the next time it checks (like in LoopSleep()).
+
+-----------------------------------------------------------
+
+– Params:
+  - mod_num – the number of the module
+
+– Returns:
+  - true if the module was signalled, false otherwise
+*/
 func ModSignalStopMODULES(mod_num int) bool {
-	return nil == getUserDataDirMODULES(mod_num).Add2(false, "STOP").Create(true)
+	var stop_file_path GPath = getUserDataDirMODULES(mod_num).Add2(false, "STOP")
+
+	return nil == stop_file_path.WithLock(LOCK_EXCLUSIVE, _MOD_FILES_LOCK_TIMEOUT, func() error {
+		return stop_file_path.Create(true)
+	})
+}
+
+/*
+GetModDiskUsageMODULES gets the space used by the directories of a module.
+
+-----------------------------------------------------------
+
+– Params:
+  - mod_num – the number of the module
+
+– Returns:
+  - the space used by each directory
+  - nil if the space was got successfully, an error otherwise
+*/
+func GetModDiskUsageMODULES(mod_num int) (ModDiskUsage, error) {
+	var modDiskUsage ModDiskUsage = ModDiskUsage{}
+	var err error
+	if modDiskUsage.ProgramData, err = getProgramDataDirMODULES(mod_num).GetUsage(); nil != err {
+		return modDiskUsage, err
+	}
+	if modDiskUsage.UserData, err = getUserDataDirMODULES(mod_num).GetUsage(); nil != err {
+		return modDiskUsage, err
+	}
+	modDiskUsage.Temp, err = getModTempDirMODULES(mod_num).GetUsage()
+
+	return modDiskUsage, err
+}
+
+/*
+GetDiskUsageReportMODULES gets the space used by the directories of all the modules.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the space used by each module, by the number of the module
+  - nil if the space was got successfully, an error otherwise
+*/
+func GetDiskUsageReportMODULES() (map[int]ModDiskUsage, error) {
+	var report map[int]ModDiskUsage = map[int]ModDiskUsage{}
+	for mod_num := range MOD_NUMS_NAMES {
+		modDiskUsage, err := GetModDiskUsageMODULES(mod_num)
+		if nil != err {
+			return nil, err
+		}
+		report[mod_num] = modDiskUsage
+	}
+
+	return report, nil
+}
+
+/*
+GetTotal gets the space used by all the directories of the module together.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the total space used
+*/
+func (modDiskUsage ModDiskUsage) GetTotal() DiskUsage {
+	return DiskUsage{
+		Size:  modDiskUsage.ProgramData.Size + modDiskUsage.UserData.Size + modDiskUsage.Temp.Size,
+		Files: modDiskUsage.ProgramData.Files + modDiskUsage.UserData.Files + modDiskUsage.Temp.Files,
+		Dirs:  modDiskUsage.ProgramData.Dirs + modDiskUsage.UserData.Dirs + modDiskUsage.Temp.Dirs,
+	}
+}
+
+/*
+ApplyModRetentionMODULES applies retention policies to the directories of a module. The files the modules need to run
+(like the information files) are always kept.
+
+-----------------------------------------------------------
+
+– Params:
+  - mod_num – the number of the module
+  - modRetention – the retention policies
+
+– Returns:
+  - the reports of the directories with a policy, by the name of the directory ("UserData" or "Temp")
+  - nil if the policies were applied successfully, an error otherwise
+*/
+func ApplyModRetentionMODULES(mod_num int, modRetention ModRetention) (map[string]RetentionReport, error) {
+	var reports map[string]RetentionReport = map[string]RetentionReport{}
+	var dirs_policies = []struct {
+		name   string
+		dir    GPath
+		policy *RetentionPolicy
+	}{
+		{"UserData", getUserDataDirMODULES(mod_num), modRetention.UserData},
+		{"Temp", getModTempDirMODULES(mod_num), modRetention.Temp},
+	}
+	for _, dir_policy := range dirs_policies {
+		if nil == dir_policy.policy || !dir_policy.dir.Exists() {
+			continue
+		}
+
+		var policy RetentionPolicy = *dir_policy.policy
+		policy.Keep = append(append([]string(nil), policy.Keep...), _MOD_RETENTION_KEEP...)
+		retentionReport, err := dir_policy.dir.ApplyRetention(policy)
+		reports[dir_policy.name] = retentionReport
+		if nil != err {
+			return reports, err
+		}
+	}
+
+	return reports, nil
+}
+
+/*
+WriteProgramManifestsMODULES writes the integrity manifests of the program files (the binaries and the ProgramData
+directory), to be checked later with VerifyProgramManifestsMODULES(). Call it after installing or updating them.
+
+-----------------------------------------------------------
+
+– Returns:
+  - nil if the manifests were written successfully, an error otherwise
+*/
+func WriteProgramManifestsMODULES() error {
+	for name, dir := range getProgramTreesMODULES() {
+		var manifest_path GPath = PersonalConsts_GL._VISOR_DIR.Add2(false, _MANIFESTS_REL_DIR, name + MANIFEST_FILE_EXT)
+		if err := dir.WriteManifest(manifest_path, nil); nil != err {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/*
+VerifyProgramManifestsMODULES checks the program files (the binaries and the ProgramData directory) against the
+manifests written by WriteProgramManifestsMODULES(), to detect corruption or tampering. Meant to be called by the
+Modules Manager at startup.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the differences found in each directory with any, by the name of the directory ("bin" or "ProgramData")
+  - nil if the verification was done, an error otherwise (wrapping os.ErrNotExist if there are no manifests yet)
+*/
+func VerifyProgramManifestsMODULES() (map[string]ManifestDiff, error) {
+	var manifestDiffs map[string]ManifestDiff = map[string]ManifestDiff{}
+	for name, dir := range getProgramTreesMODULES() {
+		var manifest_path GPath = PersonalConsts_GL._VISOR_DIR.Add2(false, _MANIFESTS_REL_DIR, name + MANIFEST_FILE_EXT)
+		manifestDiff, err := dir.VerifyManifest(manifest_path, nil)
+		if nil != err {
+			return nil, err
+		}
+		if !manifestDiff.IsEmpty() {
+			manifestDiffs[name] = manifestDiff
+		}
+	}
+
+	return manifestDiffs, nil
+}
+
+/*
+getProgramTreesMODULES gets the directories of the program files checked by the integrity manifests.
+
+-----------------------------------------------------------
+
+– Returns:
+  - the directories by their names
+*/
+func getProgramTreesMODULES() map[string]GPath {
+	return map[string]GPath{
+		_BIN_REL_DIR: GetBinDirFILESDIRS(),
+		"ProgramData": PersonalConsts_GL._VISOR_DIR.Add2(true, _PROGRAM_DATA_REL_DIR),
+	}
 }
 
 /*
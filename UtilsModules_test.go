@@ -0,0 +1,103 @@
+/*******************************************************************************
+ * Copyright 2023-2023 Edw590
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ ******************************************************************************/
+
+package Utils
+
+import (
+	"testing"
+)
+
+func TestModTempsRemoveAll(t *testing.T) {
+	var temp_dir GPath = PathFILESDIRS(true, "", "/temp").WithFS(NewMemFileSystemFILESDIRS())
+	var moduleInfo ModuleInfo[any] = ModuleInfo[any]{
+		ModDirsInfo: _ModDirsInfo{Temp: temp_dir},
+		temps:       &_ModTemps{},
+	}
+
+	created_file, file, err := moduleInfo.CreateTemp("file_*")
+	if nil != err {
+		t.Fatal(err)
+	}
+	_ = file.Close()
+	created_dir, err := moduleInfo.MkdirTemp("dir_*")
+	if nil != err {
+		t.Fatal(err)
+	}
+	if err = created_dir.Add2(false, "inner.txt").WriteTextFile("x"); nil != err {
+		t.Fatal(err)
+	}
+	// Not created through the ModuleInfo, like by another instance of the module.
+	var other GPath = temp_dir.Add2(false, "other.txt")
+	if err = other.WriteTextFile("x"); nil != err {
+		t.Fatal(err)
+	}
+
+	moduleInfo.temps.removeAll()
+
+	var tests = []struct {
+		name      string
+		gPath     GPath
+		wantExist bool
+	}{
+		{"created file", created_file, false},
+		{"created directory", created_dir, false},
+		{"other file", other, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := test.gPath.Stat(); (nil == err) != test.wantExist {
+				t.Errorf("exists = %v, want %v", nil == err, test.wantExist)
+			}
+		})
+	}
+}
+
+func TestModStopWatch(t *testing.T) {
+	var user_data GPath = PathFILESDIRS(true, "", "/user_data").WithFS(NewMemFileSystemFILESDIRS())
+	if err := user_data.Create(true); nil != err {
+		t.Fatal(err)
+	}
+	var stop_file_path GPath = user_data.Add2(false, "STOP")
+
+	var modStopWatch *_ModStopWatch = &_ModStopWatch{}
+	var events <-chan WatchEvent = modStopWatch.getEvents(stop_file_path)
+	if nil == events {
+		t.Fatal("no events")
+	}
+	if again := modStopWatch.getEvents(stop_file_path); again != events {
+		t.Error("a new watcher was created on the second call")
+	}
+
+	modStopWatch.close()
+	if _, ok := <-events; ok {
+		t.Error("watcher not closed")
+	}
+	if nil != modStopWatch.getEvents(stop_file_path) {
+		t.Error("events got after being closed")
+	}
+
+	// Not created by ModStartup().
+	var nil_watch *_ModStopWatch = nil
+	if nil != nil_watch.getEvents(stop_file_path) {
+		t.Error("events got without a watcher")
+	}
+	nil_watch.close()
+}
@@ -3,6 +3,7 @@ module Utils
 go 1.20
 
 require (
+	github.com/cention-sany/utf7 v0.0.0-20170124080048-26cad61bd60a
 	github.com/dchest/jsmin v0.0.0-20220218165748-59f39799265f
 	github.com/ztrue/tracerr v0.4.0
 )